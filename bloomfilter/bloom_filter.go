@@ -0,0 +1,318 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package bloomfilter contains a Bloom filter, a probabilistic set membership test
+// with no false negatives and a bounded rate of false positives.
+//
+// Serialized format, all values little-endian:
+//
+//	Byte  0:     preamble longs (4)
+//	Byte  1:     serialization version (1)
+//	Byte  2:     family id (149)
+//	Byte  3:     flags (bit 2: empty)
+//	Bytes 4-5:   number of hash functions
+//	Bytes 6-7:   unused
+//	Bytes 8-15:  hash seed
+//	Bytes 16-19: number of 64 bit words in the bit array
+//	Bytes 20-23: unused
+//	Bytes 24-31: number of bits set
+//	Bytes 32-:   bit array, as 64 bit words (omitted if empty)
+//
+// Items are hashed with the 128 bit x64 MurmurHash3 using the seed for both halves, giving h1 and h2.
+// The i-th of the k bit positions of an item is ((h1 + i * h2) >> 1) mod numBits, with i in [0, k)
+// and unsigned 64 bit overflow (double hashing, Kirsch and Mitzenmacher).
+// Integers are hashed as their 8 bytes little-endian and strings as their UTF-8 bytes.
+//
+// The Java and C++ Bloom filters use the family id 21 and hash with XXHash64. The bit positions of
+// an item differ, so a filter of the other libraries would answer false for items it holds. This
+// filter has its own family id, 149 (21 with the high bit set), and rejects family 21 instead of
+// misreading those filters.
+package bloomfilter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/apache/datasketches-go/internal"
+	"github.com/twmb/murmur3"
+)
+
+const (
+	_PREAMBLE_LONGS_BYTE = 0
+	_SER_VER_BYTE        = 1
+	_FAMILY_BYTE         = 2
+	_FLAGS_BYTE          = 3
+	_NUM_HASHES_SHORT    = 4
+	_SEED_LONG           = 8
+	_NUM_WORDS_INT       = 16
+	_NUM_BITS_SET_LONG   = 24
+	_BIT_ARRAY_START     = 32
+
+	_SER_VER         = 1
+	_PREAMBLE_LONGS  = 4
+	_EMPTY_FLAG_MASK = 4
+
+	_MAX_NUM_HASHES = math.MaxInt16
+	_MAX_NUM_WORDS  = math.MaxInt32
+)
+
+// BloomFilter is a fixed size array of bits in which every item sets k bit positions.
+// An item is reported as possibly present if all its positions are set.
+type BloomFilter struct {
+	numHashes  uint16
+	seed       uint64
+	numBitsSet uint64
+	bitArray   []uint64
+}
+
+// New constructs a Bloom filter sized to keep the false positive probability at or below
+// targetFPP once maxDistinctItems distinct items have been inserted.
+// The number of bits m and of hash functions k are the optimal ones:
+// m = -n ln(p) / ln(2)^2, rounded up to a multiple of 64, and k = round(m / n * ln(2)).
+func New(targetFPP float64, maxDistinctItems uint64) (*BloomFilter, error) {
+	if !(targetFPP > 0 && targetFPP < 1) {
+		return nil, fmt.Errorf("targetFPP must be in (0, 1): %f", targetFPP)
+	}
+	if maxDistinctItems == 0 {
+		return nil, errors.New("maxDistinctItems must be positive")
+	}
+	n := float64(maxDistinctItems)
+	numBits := math.Ceil(-n * math.Log(targetFPP) / (math.Ln2 * math.Ln2))
+	numWords := math.Ceil(numBits / 64)
+	if numWords > _MAX_NUM_WORDS {
+		return nil, fmt.Errorf("filter would need more than %d 64 bit words", _MAX_NUM_WORDS)
+	}
+	numHashes := math.Round(numWords * 64 / n * math.Ln2)
+	numHashes = math.Max(1, math.Min(numHashes, _MAX_NUM_HASHES))
+	return NewWithSize(uint64(numWords)*64, uint16(numHashes), internal.DEFAULT_UPDATE_SEED)
+}
+
+// NewWithSize constructs a Bloom filter with numBits bits, rounded up to a multiple of 64,
+// numHashes hash functions and the given hash seed.
+func NewWithSize(numBits uint64, numHashes uint16, seed uint64) (*BloomFilter, error) {
+	if numBits == 0 || (numBits+63)/64 > _MAX_NUM_WORDS {
+		return nil, fmt.Errorf("numBits must be in [1, %d]: %d", uint64(_MAX_NUM_WORDS)*64, numBits)
+	}
+	if numHashes == 0 || numHashes > _MAX_NUM_HASHES {
+		return nil, fmt.Errorf("numHashes must be in [1, %d]: %d", _MAX_NUM_HASHES, numHashes)
+	}
+	return &BloomFilter{
+		numHashes: numHashes,
+		seed:      seed,
+		bitArray:  make([]uint64, (numBits+63)/64),
+	}, nil
+}
+
+// NewBloomFilterFromSlice constructs a Bloom filter from its serialized form.
+func NewBloomFilterFromSlice(sl []byte) (*BloomFilter, error) {
+	if len(sl) < _BIT_ARRAY_START {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d", len(sl))
+	}
+	preLongs := int(sl[_PREAMBLE_LONGS_BYTE])
+	serVer := int(sl[_SER_VER_BYTE])
+	familyID := int(sl[_FAMILY_BYTE])
+	flags := sl[_FLAGS_BYTE]
+	if serVer != _SER_VER {
+		return nil, fmt.Errorf("possible corruption: ser ver must be %d: %d", _SER_VER, serVer)
+	}
+	if familyID == internal.FamilyEnum.Bloom.Id {
+		return nil, errors.New("unsupported filter: family 21 is the Java and C++ BloomFilter, which hashes with XXHash64")
+	}
+	if familyID != internal.FamilyEnum.GoBloom.Id {
+		return nil, fmt.Errorf("possible corruption: familyID must be %d: %d", internal.FamilyEnum.GoBloom.Id, familyID)
+	}
+	if preLongs != _PREAMBLE_LONGS {
+		return nil, fmt.Errorf("possible corruption: preLongs must be %d: %d", _PREAMBLE_LONGS, preLongs)
+	}
+	empty := (flags & _EMPTY_FLAG_MASK) != 0
+
+	numHashes := binary.LittleEndian.Uint16(sl[_NUM_HASHES_SHORT:])
+	seed := binary.LittleEndian.Uint64(sl[_SEED_LONG:])
+	numWords := int32(binary.LittleEndian.Uint32(sl[_NUM_WORDS_INT:]))
+	if numWords <= 0 {
+		return nil, fmt.Errorf("possible corruption: invalid number of words: %d", numWords)
+	}
+	bf, err := NewWithSize(uint64(numWords)*64, numHashes, seed)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		return bf, nil
+	}
+
+	if len(sl) < _BIT_ARRAY_START+int(numWords)*8 {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d, %d", len(sl), _BIT_ARRAY_START+int(numWords)*8)
+	}
+	numBitsSet := uint64(0)
+	for i := range bf.bitArray {
+		bf.bitArray[i] = binary.LittleEndian.Uint64(sl[_BIT_ARRAY_START+i*8:])
+		numBitsSet += uint64(bits.OnesCount64(bf.bitArray[i]))
+	}
+	if numBitsSet != binary.LittleEndian.Uint64(sl[_NUM_BITS_SET_LONG:]) {
+		return nil, errors.New("possible corruption: number of bits set does not match the bit array")
+	}
+	bf.numBitsSet = numBitsSet
+	return bf, nil
+}
+
+// IsEmpty returns true if no item has been inserted in the filter.
+func (bf *BloomFilter) IsEmpty() bool {
+	return bf.numBitsSet == 0
+}
+
+// GetNumHashes returns the number of hash functions, which is the number of bits set per item.
+func (bf *BloomFilter) GetNumHashes() uint16 {
+	return bf.numHashes
+}
+
+// GetSeed returns the hash seed of the filter.
+func (bf *BloomFilter) GetSeed() uint64 {
+	return bf.seed
+}
+
+// GetCapacity returns the number of bits in the filter.
+func (bf *BloomFilter) GetCapacity() uint64 {
+	return uint64(len(bf.bitArray)) * 64
+}
+
+// GetBitsUsed returns the number of bits set in the filter.
+func (bf *BloomFilter) GetBitsUsed() uint64 {
+	return bf.numBitsSet
+}
+
+// GetFPP returns the estimated false positive probability of the filter in its current state,
+// which is the fraction of bits set to the power of the number of hash functions.
+func (bf *BloomFilter) GetFPP() float64 {
+	return math.Pow(float64(bf.numBitsSet)/float64(bf.GetCapacity()), float64(bf.numHashes))
+}
+
+// Update inserts the given bytes in the filter. Nil or empty input is ignored.
+func (bf *BloomFilter) Update(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	h1, h2 := murmur3.SeedSum128(bf.seed, bf.seed, data)
+	numBits := bf.GetCapacity()
+	for i := uint64(0); i < uint64(bf.numHashes); i++ {
+		idx := ((h1 + i*h2) >> 1) % numBits
+		word, mask := idx>>6, uint64(1)<<(idx&63)
+		if bf.bitArray[word]&mask == 0 {
+			bf.bitArray[word] |= mask
+			bf.numBitsSet++
+		}
+	}
+}
+
+// UpdateString inserts the UTF-8 bytes of the given string in the filter. An empty string is ignored.
+func (bf *BloomFilter) UpdateString(datum string) {
+	bf.Update([]byte(datum))
+}
+
+// UpdateInt64 inserts the 8 bytes little-endian of the given integer in the filter.
+func (bf *BloomFilter) UpdateInt64(datum int64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(datum))
+	bf.Update(buf[:])
+}
+
+// Query returns true if the given bytes may have been inserted in the filter,
+// and false if they certainly were not. Nil or empty input always returns false.
+func (bf *BloomFilter) Query(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	h1, h2 := murmur3.SeedSum128(bf.seed, bf.seed, data)
+	numBits := bf.GetCapacity()
+	for i := uint64(0); i < uint64(bf.numHashes); i++ {
+		idx := ((h1 + i*h2) >> 1) % numBits
+		if bf.bitArray[idx>>6]&(uint64(1)<<(idx&63)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryString returns true if the given string may have been inserted in the filter.
+func (bf *BloomFilter) QueryString(datum string) bool {
+	return bf.Query([]byte(datum))
+}
+
+// QueryInt64 returns true if the given integer may have been inserted in the filter.
+func (bf *BloomFilter) QueryInt64(datum int64) bool {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(datum))
+	return bf.Query(buf[:])
+}
+
+// Merge sets in this filter the bits set in the other filter, so this filter answers for the union of both.
+// The filters must have the same size, number of hash functions and seed.
+func (bf *BloomFilter) Merge(other *BloomFilter) error {
+	if other == nil {
+		return errors.New("no filter provided")
+	}
+	if !bf.isCompatible(other) {
+		return fmt.Errorf("incompatible filters, bits: %d vs %d, hashes: %d vs %d, seed: %d vs %d",
+			bf.GetCapacity(), other.GetCapacity(), bf.numHashes, other.numHashes, bf.seed, other.seed)
+	}
+	numBitsSet := uint64(0)
+	for i := range bf.bitArray {
+		bf.bitArray[i] |= other.bitArray[i]
+		numBitsSet += uint64(bits.OnesCount64(bf.bitArray[i]))
+	}
+	bf.numBitsSet = numBitsSet
+	return nil
+}
+
+// Reset clears all the bits of the filter.
+func (bf *BloomFilter) Reset() {
+	clear(bf.bitArray)
+	bf.numBitsSet = 0
+}
+
+// ToSlice returns the serialized form of the filter. The bit array is omitted if the filter is empty.
+func (bf *BloomFilter) ToSlice() []byte {
+	empty := bf.IsEmpty()
+	size := _BIT_ARRAY_START
+	if !empty {
+		size += len(bf.bitArray) * 8
+	}
+	out := make([]byte, size)
+	out[_PREAMBLE_LONGS_BYTE] = _PREAMBLE_LONGS
+	if empty {
+		out[_FLAGS_BYTE] = _EMPTY_FLAG_MASK
+	}
+	out[_SER_VER_BYTE] = _SER_VER
+	out[_FAMILY_BYTE] = byte(internal.FamilyEnum.GoBloom.Id)
+	binary.LittleEndian.PutUint16(out[_NUM_HASHES_SHORT:], bf.numHashes)
+	binary.LittleEndian.PutUint64(out[_SEED_LONG:], bf.seed)
+	binary.LittleEndian.PutUint32(out[_NUM_WORDS_INT:], uint32(len(bf.bitArray)))
+	if empty {
+		return out
+	}
+	binary.LittleEndian.PutUint64(out[_NUM_BITS_SET_LONG:], bf.numBitsSet)
+	for i, word := range bf.bitArray {
+		binary.LittleEndian.PutUint64(out[_BIT_ARRAY_START+i*8:], word)
+	}
+	return out
+}
+
+func (bf *BloomFilter) isCompatible(other *BloomFilter) bool {
+	return len(bf.bitArray) == len(other.bitArray) && bf.numHashes == other.numHashes && bf.seed == other.seed
+}
@@ -0,0 +1,173 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bloomfilter
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter_InvalidArgs(t *testing.T) {
+	_, err := New(0, 1000)
+	assert.Error(t, err)
+	_, err = New(1, 1000)
+	assert.Error(t, err)
+	_, err = New(0.01, 0)
+	assert.Error(t, err)
+	_, err = NewWithSize(0, 3, 1)
+	assert.Error(t, err)
+	_, err = NewWithSize(64, 0, 1)
+	assert.Error(t, err)
+}
+
+func TestBloomFilter_OptimalSize(t *testing.T) {
+	bf, err := New(0.01, 1000)
+	assert.NoError(t, err)
+	// m = 1000 * ln(100) / ln(2)^2 = 9585.06, rounded up to 9600 bits
+	assert.Equal(t, uint64(9600), bf.GetCapacity())
+	assert.Equal(t, uint16(7), bf.GetNumHashes())
+	assert.True(t, bf.IsEmpty())
+	assert.Equal(t, 0.0, bf.GetFPP())
+}
+
+func TestBloomFilter_NoFalseNegatives(t *testing.T) {
+	n := 10000
+	targetFPP := 0.01
+	bf, err := New(targetFPP, uint64(n))
+	assert.NoError(t, err)
+	for i := 0; i < n; i++ {
+		bf.UpdateInt64(int64(i))
+		bf.UpdateString("s" + strconv.Itoa(i))
+	}
+	for i := 0; i < n; i++ {
+		assert.True(t, bf.QueryInt64(int64(i)))
+		assert.True(t, bf.QueryString("s"+strconv.Itoa(i)))
+	}
+
+	bf, err = New(targetFPP, uint64(n))
+	assert.NoError(t, err)
+	for i := 0; i < n; i++ {
+		bf.UpdateInt64(int64(i))
+	}
+	falsePositives := 0
+	numQueries := 100000
+	for i := n; i < n+numQueries; i++ {
+		if bf.QueryInt64(int64(i)) {
+			falsePositives++
+		}
+	}
+	fpp := float64(falsePositives) / float64(numQueries)
+	assert.Less(t, fpp, 1.5*targetFPP)
+	assert.InDelta(t, targetFPP, bf.GetFPP(), 0.5*targetFPP)
+}
+
+func TestBloomFilter_EmptyInput(t *testing.T) {
+	bf, err := New(0.01, 100)
+	assert.NoError(t, err)
+	bf.Update(nil)
+	bf.UpdateString("")
+	assert.True(t, bf.IsEmpty())
+	assert.False(t, bf.Query(nil))
+	assert.False(t, bf.QueryString(""))
+}
+
+func TestBloomFilter_Merge(t *testing.T) {
+	bf1, err := New(0.01, 1000)
+	assert.NoError(t, err)
+	bf2, err := New(0.01, 1000)
+	assert.NoError(t, err)
+	for i := 0; i < 500; i++ {
+		bf1.UpdateInt64(int64(i))
+		bf2.UpdateInt64(int64(500 + i))
+	}
+	assert.NoError(t, bf1.Merge(bf2))
+	for i := 0; i < 1000; i++ {
+		assert.True(t, bf1.QueryInt64(int64(i)))
+	}
+
+	assert.Error(t, bf1.Merge(nil))
+	other, err := New(0.001, 1000)
+	assert.NoError(t, err)
+	assert.Error(t, bf1.Merge(other))
+	otherSeed, err := NewWithSize(bf1.GetCapacity(), bf1.GetNumHashes(), bf1.GetSeed()+1)
+	assert.NoError(t, err)
+	assert.Error(t, bf1.Merge(otherSeed))
+
+	bf1.Reset()
+	assert.True(t, bf1.IsEmpty())
+	assert.False(t, bf1.QueryInt64(1))
+}
+
+func TestBloomFilter_SerializeDeserialize(t *testing.T) {
+	for _, n := range []int{0, 1, 1000} {
+		bf, err := New(0.01, 1000)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			bf.UpdateString(strconv.Itoa(i))
+		}
+		bytes := bf.ToSlice()
+		if n == 0 {
+			assert.Equal(t, _BIT_ARRAY_START, len(bytes))
+		}
+		bf2, err := NewBloomFilterFromSlice(bytes)
+		assert.NoError(t, err)
+		assert.Equal(t, bf.GetCapacity(), bf2.GetCapacity())
+		assert.Equal(t, bf.GetNumHashes(), bf2.GetNumHashes())
+		assert.Equal(t, bf.GetSeed(), bf2.GetSeed())
+		assert.Equal(t, bf.GetBitsUsed(), bf2.GetBitsUsed())
+		assert.Equal(t, bytes, bf2.ToSlice())
+		for i := 0; i < n; i++ {
+			assert.True(t, bf2.QueryString(strconv.Itoa(i)))
+		}
+	}
+}
+
+func TestBloomFilter_DeserializeCorrupt(t *testing.T) {
+	bf, err := New(0.01, 100)
+	assert.NoError(t, err)
+	bf.UpdateInt64(1)
+	bytes := bf.ToSlice()
+
+	_, err = NewBloomFilterFromSlice(bytes[:16])
+	assert.Error(t, err)
+	_, err = NewBloomFilterFromSlice(bytes[:40])
+	assert.Error(t, err)
+
+	badFamily := append([]byte{}, bytes...)
+	badFamily[_FAMILY_BYTE] = 7
+	_, err = NewBloomFilterFromSlice(badFamily)
+	assert.Error(t, err)
+
+	badSerVer := append([]byte{}, bytes...)
+	badSerVer[_SER_VER_BYTE] = 2
+	_, err = NewBloomFilterFromSlice(badSerVer)
+	assert.Error(t, err)
+
+	// the Java and C++ format hashes differently, and must not be read as this one
+	javaFamily := append([]byte{}, bytes...)
+	javaFamily[_FAMILY_BYTE] = 21
+	_, err = NewBloomFilterFromSlice(javaFamily)
+	assert.ErrorContains(t, err, "Java")
+
+	badBitsSet := append([]byte{}, bytes...)
+	badBitsSet[_NUM_BITS_SET_LONG]++
+	_, err = NewBloomFilterFromSlice(badBitsSet)
+	assert.Error(t, err)
+}
@@ -0,0 +1,130 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Command genvectors writes the test vectors of a sketch family and configuration: for each of the
+// empty, single, exact and estimation cases, the constructor parameters, the update operations and
+// the expected serialization. The hll and kll packages replay the vectors in their tests.
+//
+// From the root of the repository:
+//
+//	go run ./cmd/genvectors -family hll -lgk 12 -type 4
+//	go run ./cmd/genvectors -family kll_doubles -k 200
+//
+// KLL sketches are built with the DeterministicCompactionStrategy, so that their serialization
+// does not depend on a random source.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/apache/datasketches-go/hll"
+	"github.com/apache/datasketches-go/internal"
+	"github.com/apache/datasketches-go/kll"
+)
+
+func main() {
+	family := flag.String("family", "", "sketch family: hll or kll_doubles")
+	lgK := flag.Int("lgk", 12, "lgConfigK of the hll sketches")
+	tgtType := flag.Int("type", 4, "register width of the hll sketches: 4, 6 or 8")
+	k := flag.Int("k", 200, "k of the kll sketches")
+	out := flag.String("out", "serialization_test_data/go_test_vectors", "output directory")
+	flag.Parse()
+
+	var (
+		file *internal.TestVectorFile
+		name string
+		err  error
+	)
+	switch *family {
+	case "hll":
+		params := map[string]int{"lgK": *lgK, "tgtHllType": *tgtType}
+		// a coupon set in exact mode, and 16 items per register in estimation mode
+		exactN := max(2, int64(1)<<*lgK/32)
+		file, err = generate(*family, params, exactN, int64(16)<<*lgK, buildHll)
+		name = fmt.Sprintf("hll_lgk%d_hll%d.json", *lgK, *tgtType)
+	case "kll_doubles":
+		params := map[string]int{"k": *k}
+		// level 0 holds k items before the first compaction
+		file, err = generate(*family, params, int64(*k), int64(*k)*100, buildKllDoubles)
+		name = fmt.Sprintf("kll_doubles_k%d.json", *k)
+	default:
+		err = fmt.Errorf("unknown family: %q", *family)
+	}
+	if err == nil {
+		err = os.MkdirAll(*out, 0755)
+	}
+	if err == nil {
+		err = internal.WriteTestVectorFile(filepath.Join(*out, name), file)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "genvectors:", err)
+		os.Exit(1)
+	}
+}
+
+func generate(family string, params map[string]int, exactN, estimationN int64, build func(map[string]int, []internal.UpdateRange) ([]byte, error)) (*internal.TestVectorFile, error) {
+	file := &internal.TestVectorFile{Family: family}
+	cases := internal.TestVectorCases(exactN, estimationN)
+	for _, c := range internal.TestVectorCaseNames {
+		serialized, err := build(params, cases[c])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c, err)
+		}
+		file.Vectors = append(file.Vectors, internal.TestVector{Case: c, Params: params, Updates: cases[c], Serialized: serialized})
+	}
+	return file, nil
+}
+
+// buildHll builds an HLL sketch and returns its compact serialization.
+func buildHll(params map[string]int, updates []internal.UpdateRange) ([]byte, error) {
+	tgtHllTypes := map[int]hll.TgtHllType{4: hll.TgtHllTypeHll4, 6: hll.TgtHllTypeHll6, 8: hll.TgtHllTypeHll8}
+	tgtHllType, ok := tgtHllTypes[params["tgtHllType"]]
+	if !ok {
+		return nil, fmt.Errorf("invalid tgtHllType: %d", params["tgtHllType"])
+	}
+	sk, err := hll.NewHllSketch(params["lgK"], tgtHllType)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		for i := u.From; i < u.From+u.Count; i++ {
+			if err := sk.UpdateInt64(i); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sk.ToCompactSlice()
+}
+
+// buildKllDoubles builds a KLL sketch of float64 items and returns its serialization.
+func buildKllDoubles(params map[string]int, updates []internal.UpdateRange) ([]byte, error) {
+	sk, err := kll.NewKllItemsSketch[float64](uint16(params["k"]), 8, common.ItemSketchDoubleComparator(false),
+		common.ItemSketchDoubleSerDe{}, kll.WithCompactionStrategy(kll.DeterministicCompactionStrategy{}))
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range updates {
+		for i := u.From; i < u.From+u.Count; i++ {
+			sk.Update(float64(i))
+		}
+	}
+	return sk.ToSlice()
+}
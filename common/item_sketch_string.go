@@ -118,10 +118,10 @@ func (f ItemSketchStringSerDe) DeserializeManyFromSlice(mem []byte, offsetBytes
 		}
 		strLength := int(binary.LittleEndian.Uint32(mem[offset:]))
 		offset += intSize
-		utf8Bytes := make([]byte, strLength)
 		if !checkBounds(offset, strLength, memCap) {
 			return nil, errors.New("offset out of bounds")
 		}
+		utf8Bytes := make([]byte, strLength)
 		copy(utf8Bytes, mem[offset:offset+strLength])
 		offset += strLength
 		array[i] = string(utf8Bytes)
@@ -23,7 +23,7 @@ type ItemSketchHasher[C comparable] interface {
 	Hash(item C) uint64
 }
 
-type ItemSketchSerde[C comparable] interface {
+type ItemSketchSerde[C any] interface {
 	SizeOf(item C) int
 	SizeOfMany(mem []byte, offsetBytes int, numItems int) (int, error)
 	SerializeManyToSlice(items []C) []byte
@@ -0,0 +1,303 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// The Count-Min sketch of Cormode and Muthukrishnan estimates the total count of any key
+// of a stream of weighted updates.
+//
+// Serialized format, all values little-endian:
+//
+//	Byte  0:     preamble longs (3)
+//	Byte  1:     serialization version (1)
+//	Byte  2:     family id (146)
+//	Byte  3:     flags (bit 2: empty)
+//	Bytes 4-7:   number of buckets per row (width)
+//	Byte  8:     number of rows (depth)
+//	Bytes 9-15:  unused
+//	Bytes 16-23: hash seed
+//	Bytes 24-31: total weight of the updates
+//	Bytes 32-:   counters, as int64, row after row (omitted if empty)
+//
+// Row i hashes a key with the 64 bit x64 MurmurHash3 seeded with seed + i, and the bucket is the hash modulo width.
+// Integers are hashed as their 8 bytes little-endian and strings as their UTF-8 bytes.
+//
+// The Java and C++ CountMinSketch use the family id 18 with another preamble and another hashing,
+// so this sketch has its own family id, 146 (18 with the high bit set), and rejects family 18
+// instead of misreading those sketches.
+
+package frequencies
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/apache/datasketches-go/internal"
+	"github.com/twmb/murmur3"
+)
+
+const (
+	_CM_PREAMBLE_LONGS_BYTE = 0
+	_CM_SER_VER_BYTE        = 1
+	_CM_FAMILY_BYTE         = 2
+	_CM_FLAGS_BYTE          = 3
+	_CM_NUM_BUCKETS_INT     = 4
+	_CM_NUM_HASHES_BYTE     = 8
+	_CM_SEED_LONG           = 16
+	_CM_TOTAL_WEIGHT_LONG   = 24
+	_CM_COUNTERS_START      = 32
+
+	_CM_SER_VER         = 1
+	_CM_PREAMBLE_LONGS  = 3
+	_CM_EMPTY_FLAG_MASK = 4
+
+	_CM_MAX_NUM_HASHES = math.MaxUint8
+	_CM_MAX_TABLE_SIZE = 1 << 30
+)
+
+// CountMinSketch is a two dimensional array of counters with one row per hash function.
+// An update adds its count to one counter of every row and the estimate of a key is the minimum
+// of its counters. With non-negative counts the estimate never underestimates the true count and,
+// with probability 1 - delta, overestimates it by at most epsilon times the total weight.
+type CountMinSketch struct {
+	numBuckets  uint32
+	numHashes   uint8
+	seed        uint64
+	totalWeight int64
+	empty       bool
+	table       []int64
+}
+
+// NewCountMinSketch constructs a sketch whose estimates are within epsilon times the total weight
+// of the true counts with probability 1 - delta.
+// The width is ceil(e / epsilon) and the depth is ceil(ln(1 / delta)).
+func NewCountMinSketch(epsilon, delta float64) (*CountMinSketch, error) {
+	if !(epsilon > 0 && epsilon < 1) {
+		return nil, fmt.Errorf("epsilon must be in (0, 1): %f", epsilon)
+	}
+	if !(delta > 0 && delta < 1) {
+		return nil, fmt.Errorf("delta must be in (0, 1): %f", delta)
+	}
+	numBuckets := math.Ceil(math.E / epsilon)
+	numHashes := math.Max(1, math.Ceil(math.Log(1/delta)))
+	if numBuckets > _CM_MAX_TABLE_SIZE || numHashes > _CM_MAX_NUM_HASHES {
+		return nil, fmt.Errorf("epsilon %f and delta %f need a table that is too large", epsilon, delta)
+	}
+	return NewCountMinSketchWithSize(uint32(numBuckets), uint8(numHashes), internal.DEFAULT_UPDATE_SEED)
+}
+
+// NewCountMinSketchWithSize constructs a sketch with the given width, depth and hash seed.
+func NewCountMinSketchWithSize(numBuckets uint32, numHashes uint8, seed uint64) (*CountMinSketch, error) {
+	if numBuckets < 3 {
+		return nil, fmt.Errorf("numBuckets must be at least 3: %d", numBuckets)
+	}
+	if numHashes == 0 {
+		return nil, errors.New("numHashes must be positive")
+	}
+	if uint64(numBuckets)*uint64(numHashes) > _CM_MAX_TABLE_SIZE {
+		return nil, fmt.Errorf("numBuckets * numHashes must be at most %d: %d", _CM_MAX_TABLE_SIZE, uint64(numBuckets)*uint64(numHashes))
+	}
+	return &CountMinSketch{
+		numBuckets: numBuckets,
+		numHashes:  numHashes,
+		seed:       seed,
+		empty:      true,
+		table:      make([]int64, int(numBuckets)*int(numHashes)),
+	}, nil
+}
+
+// NewCountMinSketchFromSlice constructs a sketch from its serialized form.
+func NewCountMinSketchFromSlice(sl []byte) (*CountMinSketch, error) {
+	if len(sl) < _CM_COUNTERS_START {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d", len(sl))
+	}
+	preLongs := int(sl[_CM_PREAMBLE_LONGS_BYTE])
+	serVer := int(sl[_CM_SER_VER_BYTE])
+	familyID := int(sl[_CM_FAMILY_BYTE])
+	flags := sl[_CM_FLAGS_BYTE]
+	if preLongs != _CM_PREAMBLE_LONGS {
+		return nil, fmt.Errorf("possible corruption: preLongs must be %d: %d", _CM_PREAMBLE_LONGS, preLongs)
+	}
+	if serVer != _CM_SER_VER {
+		return nil, fmt.Errorf("possible corruption: ser ver must be %d: %d", _CM_SER_VER, serVer)
+	}
+	if familyID == internal.FamilyEnum.CountMin.Id {
+		return nil, errors.New("unsupported sketch: family 18 is the Java and C++ CountMinSketch, which has another format and hashing")
+	}
+	if familyID != internal.FamilyEnum.GoCountMin.Id {
+		return nil, fmt.Errorf("possible corruption: familyID must be %d: %d", internal.FamilyEnum.GoCountMin.Id, familyID)
+	}
+
+	sketch, err := NewCountMinSketchWithSize(
+		binary.LittleEndian.Uint32(sl[_CM_NUM_BUCKETS_INT:]),
+		sl[_CM_NUM_HASHES_BYTE],
+		binary.LittleEndian.Uint64(sl[_CM_SEED_LONG:]),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if (flags & _CM_EMPTY_FLAG_MASK) != 0 {
+		return sketch, nil
+	}
+
+	if len(sl) < _CM_COUNTERS_START+len(sketch.table)*8 {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d, %d", len(sl), _CM_COUNTERS_START+len(sketch.table)*8)
+	}
+	for i := range sketch.table {
+		sketch.table[i] = int64(binary.LittleEndian.Uint64(sl[_CM_COUNTERS_START+i*8:]))
+	}
+	sketch.totalWeight = int64(binary.LittleEndian.Uint64(sl[_CM_TOTAL_WEIGHT_LONG:]))
+	sketch.empty = false
+	return sketch, nil
+}
+
+// IsEmpty returns true if the sketch has not been updated.
+func (s *CountMinSketch) IsEmpty() bool {
+	return s.empty
+}
+
+// GetNumBuckets returns the number of counters per row.
+func (s *CountMinSketch) GetNumBuckets() uint32 {
+	return s.numBuckets
+}
+
+// GetNumHashes returns the number of rows, one per hash function.
+func (s *CountMinSketch) GetNumHashes() uint8 {
+	return s.numHashes
+}
+
+// GetSeed returns the hash seed of the sketch.
+func (s *CountMinSketch) GetSeed() uint64 {
+	return s.seed
+}
+
+// GetTotalWeight returns the sum of the counts of all the updates.
+func (s *CountMinSketch) GetTotalWeight() int64 {
+	return s.totalWeight
+}
+
+// GetRelativeError returns epsilon, the bound on the overestimate of a count
+// as a fraction of the total weight, which is e / numBuckets.
+func (s *CountMinSketch) GetRelativeError() float64 {
+	return math.E / float64(s.numBuckets)
+}
+
+// Update adds count to the given key, given as bytes. Nil or empty keys are ignored.
+func (s *CountMinSketch) Update(key []byte, count int64) {
+	if len(key) == 0 {
+		return
+	}
+	s.empty = false
+	s.totalWeight += count
+	for i := 0; i < int(s.numHashes); i++ {
+		s.table[s.index(i, key)] += count
+	}
+}
+
+// UpdateInt64 adds count to the given integer key.
+func (s *CountMinSketch) UpdateInt64(key int64, count int64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(key))
+	s.Update(buf[:], count)
+}
+
+// UpdateString adds count to the given string key. An empty string is ignored.
+func (s *CountMinSketch) UpdateString(key string, count int64) {
+	s.Update([]byte(key), count)
+}
+
+// Estimate returns the estimated count of the given key, given as bytes.
+// Nil or empty keys have a zero estimate.
+func (s *CountMinSketch) Estimate(key []byte) int64 {
+	if len(key) == 0 {
+		return 0
+	}
+	estimate := int64(math.MaxInt64)
+	for i := 0; i < int(s.numHashes); i++ {
+		estimate = min(estimate, s.table[s.index(i, key)])
+	}
+	return estimate
+}
+
+// EstimateInt64 returns the estimated count of the given integer key.
+func (s *CountMinSketch) EstimateInt64(key int64) int64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(key))
+	return s.Estimate(buf[:])
+}
+
+// EstimateString returns the estimated count of the given string key.
+func (s *CountMinSketch) EstimateString(key string) int64 {
+	return s.Estimate([]byte(key))
+}
+
+// Merge adds the counters of the other sketch to this sketch.
+// The sketches must have the same width, depth and seed.
+func (s *CountMinSketch) Merge(other *CountMinSketch) error {
+	if other == nil {
+		return errors.New("no sketch provided")
+	}
+	if s.numBuckets != other.numBuckets || s.numHashes != other.numHashes || s.seed != other.seed {
+		return fmt.Errorf("incompatible sketches, buckets: %d vs %d, hashes: %d vs %d, seed: %d vs %d",
+			s.numBuckets, other.numBuckets, s.numHashes, other.numHashes, s.seed, other.seed)
+	}
+	if other.empty {
+		return nil
+	}
+	for i, c := range other.table {
+		s.table[i] += c
+	}
+	s.totalWeight += other.totalWeight
+	s.empty = false
+	return nil
+}
+
+// Reset sets all the counters to zero.
+func (s *CountMinSketch) Reset() {
+	clear(s.table)
+	s.totalWeight = 0
+	s.empty = true
+}
+
+// ToSlice returns the serialized form of the sketch. The counters are omitted if the sketch is empty.
+func (s *CountMinSketch) ToSlice() []byte {
+	size := _CM_COUNTERS_START
+	if !s.empty {
+		size += len(s.table) * 8
+	}
+	out := make([]byte, size)
+	out[_CM_PREAMBLE_LONGS_BYTE] = _CM_PREAMBLE_LONGS
+	out[_CM_SER_VER_BYTE] = _CM_SER_VER
+	out[_CM_FAMILY_BYTE] = byte(internal.FamilyEnum.GoCountMin.Id)
+	binary.LittleEndian.PutUint32(out[_CM_NUM_BUCKETS_INT:], s.numBuckets)
+	out[_CM_NUM_HASHES_BYTE] = s.numHashes
+	binary.LittleEndian.PutUint64(out[_CM_SEED_LONG:], s.seed)
+	if s.empty {
+		out[_CM_FLAGS_BYTE] = _CM_EMPTY_FLAG_MASK
+		return out
+	}
+	binary.LittleEndian.PutUint64(out[_CM_TOTAL_WEIGHT_LONG:], uint64(s.totalWeight))
+	for i, c := range s.table {
+		binary.LittleEndian.PutUint64(out[_CM_COUNTERS_START+i*8:], uint64(c))
+	}
+	return out
+}
+
+func (s *CountMinSketch) index(row int, key []byte) int {
+	h := murmur3.SeedSum64(s.seed+uint64(row), key)
+	return row*int(s.numBuckets) + int(h%uint64(s.numBuckets))
+}
@@ -0,0 +1,146 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package frequencies
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountMin_InvalidArgs(t *testing.T) {
+	_, err := NewCountMinSketch(0, 0.01)
+	assert.Error(t, err)
+	_, err = NewCountMinSketch(0.01, 1)
+	assert.Error(t, err)
+	_, err = NewCountMinSketch(1e-12, 0.01)
+	assert.Error(t, err)
+	_, err = NewCountMinSketchWithSize(2, 1, 0)
+	assert.Error(t, err)
+	_, err = NewCountMinSketchWithSize(10, 0, 0)
+	assert.Error(t, err)
+}
+
+func TestCountMin_Size(t *testing.T) {
+	sk, err := NewCountMinSketch(0.01, 0.01)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(272), sk.GetNumBuckets())
+	assert.Equal(t, uint8(5), sk.GetNumHashes())
+	assert.True(t, sk.IsEmpty())
+	assert.Equal(t, int64(0), sk.EstimateInt64(1))
+}
+
+func TestCountMin_ErrorBound(t *testing.T) {
+	epsilon := 0.001
+	sk, err := NewCountMinSketch(epsilon, 0.01)
+	assert.NoError(t, err)
+	n := 10000
+	trueCounts := make([]int64, n)
+	for i := 0; i < n; i++ {
+		count := int64(1 + i%10)
+		sk.UpdateInt64(int64(i), count)
+		sk.UpdateString("k"+strconv.Itoa(i), count)
+		trueCounts[i] = count
+	}
+	totalWeight := sk.GetTotalWeight()
+	assert.Equal(t, int64(2*5.5*float64(n)), totalWeight)
+
+	bound := int64(epsilon * float64(totalWeight))
+	numViolations := 0
+	for i := 0; i < n; i++ {
+		for _, est := range []int64{sk.EstimateInt64(int64(i)), sk.EstimateString("k" + strconv.Itoa(i))} {
+			assert.GreaterOrEqual(t, est, trueCounts[i])
+			if est-trueCounts[i] > bound {
+				numViolations++
+			}
+		}
+	}
+	assert.LessOrEqual(t, float64(numViolations), 0.01*float64(2*n))
+}
+
+func TestCountMin_Merge(t *testing.T) {
+	sk1, err := NewCountMinSketch(0.01, 0.01)
+	assert.NoError(t, err)
+	sk2, err := NewCountMinSketch(0.01, 0.01)
+	assert.NoError(t, err)
+	sk1.UpdateString("a", 10)
+	sk2.UpdateString("a", 5)
+	sk2.UpdateString("b", 7)
+	assert.NoError(t, sk1.Merge(sk2))
+	assert.GreaterOrEqual(t, sk1.EstimateString("a"), int64(15))
+	assert.GreaterOrEqual(t, sk1.EstimateString("b"), int64(7))
+	assert.Equal(t, int64(22), sk1.GetTotalWeight())
+
+	assert.Error(t, sk1.Merge(nil))
+	other, err := NewCountMinSketch(0.001, 0.01)
+	assert.NoError(t, err)
+	assert.Error(t, sk1.Merge(other))
+
+	sk1.Reset()
+	assert.True(t, sk1.IsEmpty())
+	assert.Equal(t, int64(0), sk1.EstimateString("a"))
+}
+
+func TestCountMin_SerializeDeserialize(t *testing.T) {
+	for _, n := range []int{0, 1, 1000} {
+		sk, err := NewCountMinSketch(0.01, 0.05)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			sk.UpdateInt64(int64(i%100), 1)
+		}
+		bytes := sk.ToSlice()
+		sk2, err := NewCountMinSketchFromSlice(bytes)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.IsEmpty(), sk2.IsEmpty())
+		assert.Equal(t, sk.GetNumBuckets(), sk2.GetNumBuckets())
+		assert.Equal(t, sk.GetNumHashes(), sk2.GetNumHashes())
+		assert.Equal(t, sk.GetSeed(), sk2.GetSeed())
+		assert.Equal(t, sk.GetTotalWeight(), sk2.GetTotalWeight())
+		assert.Equal(t, sk.EstimateInt64(1), sk2.EstimateInt64(1))
+		assert.Equal(t, bytes, sk2.ToSlice())
+	}
+}
+
+func TestCountMin_DeserializeCorrupt(t *testing.T) {
+	sk, err := NewCountMinSketch(0.1, 0.1)
+	assert.NoError(t, err)
+	sk.UpdateInt64(1, 1)
+	bytes := sk.ToSlice()
+
+	_, err = NewCountMinSketchFromSlice(bytes[:16])
+	assert.Error(t, err)
+	_, err = NewCountMinSketchFromSlice(bytes[:40])
+	assert.Error(t, err)
+
+	badFamily := append([]byte{}, bytes...)
+	badFamily[_CM_FAMILY_BYTE] = 7
+	_, err = NewCountMinSketchFromSlice(badFamily)
+	assert.Error(t, err)
+
+	// the Java and C++ family has another format and hashing, and must not be read as this one
+	javaFamily := append([]byte{}, bytes...)
+	javaFamily[_CM_FAMILY_BYTE] = 18
+	_, err = NewCountMinSketchFromSlice(javaFamily)
+	assert.ErrorContains(t, err, "Java")
+
+	badSerVer := append([]byte{}, bytes...)
+	badSerVer[_CM_SER_VER_BYTE] = 2
+	_, err = NewCountMinSketchFromSlice(badSerVer)
+	assert.Error(t, err)
+}
@@ -63,6 +63,16 @@ func deserializeAuxHashMap(byteArray []byte, offset int, lgConfigL int, auxCount
 		lgAuxArrInts = extractLgArr(byteArray)
 	}
 
+	if lgAuxArrInts > lgConfigL {
+		return nil, fmt.Errorf("possible Corruption: Invalid Aux Array Size: %d", lgAuxArrInts)
+	}
+	auxArrBytes := auxCount << 2
+	if !srcCompact {
+		auxArrBytes = 4 << lgAuxArrInts
+	}
+	if auxCount < 0 || auxCount > 1<<lgAuxArrInts || offset+auxArrBytes > len(byteArray) {
+		return nil, fmt.Errorf("possible Corruption: input array too small: %d", len(byteArray))
+	}
 	auxMap := newAuxHashMap(lgAuxArrInts, lgConfigL)
 	configKMask := (1 << lgConfigL) - 1
 
@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"fmt"
+	"math"
+)
+
+// CheckCompatibility checks that data, a sketch serialized by any DataSketches library such as
+// the Java or C++ ones, is deserialized with an estimate within tolerance of expectedEstimate,
+// and that the estimate survives a compact serialization round trip through this library.
+// It is meant for cross-language compatibility tests against golden files.
+func CheckCompatibility(data []byte, expectedEstimate float64, tolerance float64) error {
+	sketch, err := NewHllSketchFromSlice(data, true)
+	if err != nil {
+		return err
+	}
+	est, err := sketch.GetEstimate()
+	if err != nil {
+		return err
+	}
+	if math.Abs(est-expectedEstimate) > tolerance {
+		return fmt.Errorf("estimate %f is not within %f of %f", est, tolerance, expectedEstimate)
+	}
+	bytes, err := sketch.ToCompactSlice()
+	if err != nil {
+		return err
+	}
+	sketch, err = NewHllSketchFromSlice(bytes, true)
+	if err != nil {
+		return err
+	}
+	roundTripEst, err := sketch.GetEstimate()
+	if err != nil {
+		return err
+	}
+	if roundTripEst != est {
+		return fmt.Errorf("estimate %f changed to %f by a serialization round trip", est, roundTripEst)
+	}
+	return nil
+}
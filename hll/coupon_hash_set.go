@@ -47,6 +47,10 @@ func (c *couponHashSetImpl) GetUpperBound(numStdDev int) (float64, error) {
 	return getUpperBound(c, numStdDev)
 }
 
+func (c *couponHashSetImpl) GetCompactSerializationBytes() int {
+	return c.getMemDataStart() + (c.getCouponCount() << 2)
+}
+
 func (c *couponHashSetImpl) GetUpdatableSerializationBytes() int {
 	return c.getMemDataStart() + (4 << c.getLgCouponArrInts())
 }
@@ -97,6 +101,7 @@ func (c *couponHashSetImpl) copyAs(tgtHllType TgtHllType) (hllSketchStateI, erro
 		hllSketchConfig: newHllSketchConfig(c.lgConfigK, tgtHllType, curModeSet),
 		hllCouponState:  newHllCouponState(c.lgCouponArrInts, c.couponCount, make([]int, len(c.couponIntArr))),
 	}
+	newC.sparseThreshold = c.sparseThreshold
 
 	copy(newC.couponIntArr, c.couponIntArr)
 	return newC, nil
@@ -112,10 +117,20 @@ func (c *couponHashSetImpl) mergeTo(dest HllSketch) error {
 
 // checkGrowOrPromote checks if the couponHashSetImpl should grow or promote to HLL.
 func (c *couponHashSetImpl) checkGrowOrPromote() (bool, error) {
+	maxLgCouponArrInts := c.lgConfigK - 3
+	if c.sparseThreshold > 0 {
+		if float64(c.couponCount) > c.sparseThreshold*float64(int(1)<<c.lgConfigK) {
+			return true, nil // promote to HLL
+		}
+		// the largest set allowed by the serialization format
+		maxLgCouponArrInts = c.lgConfigK
+	} else if c.lgCouponArrInts > maxLgCouponArrInts {
+		return true, nil // a sparse set deserialized without its threshold
+	}
 	if (resizeDenom * c.couponCount) <= (resizeNumber * (1 << c.lgCouponArrInts)) {
 		return false, nil
 	}
-	if c.lgCouponArrInts == (c.lgConfigK - 3) {
+	if c.lgCouponArrInts >= maxLgCouponArrInts {
 		return true, nil // promote to HLL
 	}
 	c.lgCouponArrInts++
@@ -222,26 +237,59 @@ func deserializeCouponHashSet(byteArray []byte) (hllCoupon, error) {
 	memIsCompact := extractCompactFlag(byteArray)
 	couponCount := extractHashSetCount(byteArray)
 	lgCouponArrInts := extractLgArr(byteArray)
+	if couponCount < 0 || couponCount > 1<<lgConfigK {
+		return nil, fmt.Errorf("possible Corruption: Invalid Set Count: %d", couponCount)
+	}
 	if lgCouponArrInts < lgInitSetSize {
 		lgCouponArrInts, err = computeLgArr(byteArray, couponCount, lgConfigK)
 		if err != nil {
 			return nil, err
 		}
 	}
+	if lgCouponArrInts > lgConfigK || couponCount > 1<<lgCouponArrInts {
+		return nil, fmt.Errorf("possible Corruption: Invalid Set Size: %d", lgCouponArrInts)
+	}
+	arrInts := couponCount
+	if !memIsCompact {
+		arrInts = 1 << lgCouponArrInts
+	}
+	if len(byteArray) < memArrStart+arrInts*4 {
+		return nil, fmt.Errorf("possible Corruption: input array too small: %d", len(byteArray))
+	}
 	if memIsCompact {
-		for it := 0; it < couponCount && err == nil; it++ {
-			_, err = set.couponUpdate(int(binary.LittleEndian.Uint32(byteArray[memArrStart+(it<<2) : memArrStart+(it<<2)+4])))
+		// a set with a sparse threshold may hold more coupons than the default promotion allows,
+		// so the coupons are inserted in a set of the serialized size rather than updated one by one
+		if lgCouponArrInts < lgInitSetSize || (resizeDenom*couponCount) > (resizeNumber*(1<<lgCouponArrInts)) {
+			return nil, fmt.Errorf("possible Corruption: Invalid Set Size: %d", lgCouponArrInts)
 		}
-		if err != nil {
-			return nil, err
+		set.lgCouponArrInts = lgCouponArrInts
+		set.couponIntArr = make([]int, 1<<lgCouponArrInts)
+		for it := 0; it < couponCount; it++ {
+			coupon := int(binary.LittleEndian.Uint32(byteArray[memArrStart+(it<<2) : memArrStart+(it<<2)+4]))
+			index, err := findCoupon(set.couponIntArr, lgCouponArrInts, coupon)
+			if err != nil {
+				return nil, err
+			}
+			if index >= 0 || coupon>>keyBits26 == empty {
+				return nil, fmt.Errorf("possible Corruption: invalid or duplicate coupon: %d", coupon)
+			}
+			set.couponIntArr[^index] = coupon
 		}
+		set.couponCount = couponCount
 	} else {
 		set.couponCount = couponCount
 		set.lgCouponArrInts = lgCouponArrInts
 		couponArrInts := 1 << lgCouponArrInts
 		set.couponIntArr = make([]int, couponArrInts)
+		numValid := 0
 		for it := 0; it < couponArrInts; it++ {
 			set.couponIntArr[it] = int(binary.LittleEndian.Uint32(byteArray[hashSetIntArrStart+(it<<2) : hashSetIntArrStart+(it<<2)+4]))
+			if set.couponIntArr[it] != empty {
+				numValid++
+			}
+		}
+		if numValid != couponCount {
+			return nil, fmt.Errorf("possible Corruption: Set Count %d does not match %d coupons", couponCount, numValid)
 		}
 	}
 	return &set, nil
@@ -47,6 +47,10 @@ func (c *couponListImpl) GetUpperBound(numStdDev int) (float64, error) {
 	return getUpperBound(c, numStdDev)
 }
 
+func (c *couponListImpl) GetCompactSerializationBytes() int {
+	return c.getMemDataStart() + (c.getCouponCount() << 2)
+}
+
 func (c *couponListImpl) GetUpdatableSerializationBytes() int {
 	return c.getMemDataStart() + (4 << c.getLgCouponArrInts())
 }
@@ -103,6 +107,7 @@ func (c *couponListImpl) copyAs(tgtHllType TgtHllType) (hllSketchStateI, error)
 		hllSketchConfig: newHllSketchConfig(c.lgConfigK, tgtHllType, curModeList),
 		hllCouponState:  newHllCouponState(c.lgCouponArrInts, c.couponCount, make([]int, len(c.couponIntArr))),
 	}
+	newC.sparseThreshold = c.sparseThreshold
 
 	copy(newC.couponIntArr, c.couponIntArr)
 	return newC, nil
@@ -149,6 +154,7 @@ func promoteListToSet(c *couponListImpl) (hllSketchStateI, error) {
 	if err != nil {
 		return nil, err
 	}
+	chSet.sparseThreshold = c.sparseThreshold
 	for i := 0; i < couponCount && err == nil; i++ {
 		_, err = chSet.couponUpdate(arr[i])
 	}
@@ -190,6 +196,12 @@ func deserializeCouponList(byteArray []byte) (hllCoupon, error) {
 		return nil, err
 	}
 	couponCount := extractListCount(byteArray)
+	if couponCount > len(list.couponIntArr) {
+		return nil, fmt.Errorf("possible Corruption: Invalid List Count: %d", couponCount)
+	}
+	if len(byteArray) < listIntArrStart+couponCount*4 {
+		return nil, fmt.Errorf("possible Corruption: input array too small: %d", len(byteArray))
+	}
 	// TODO there must be a more efficient to reinterpret the byte array as an int array
 	for it := 0; it < couponCount; it++ {
 		list.couponIntArr[it] = int(binary.LittleEndian.Uint32(byteArray[listIntArrStart+it*4 : listIntArrStart+it*4+4]))
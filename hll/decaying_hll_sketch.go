@@ -0,0 +1,168 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// DecayingHllSketch estimates the number of distinct items of a stream, with the items of the past down-weighted.
+//
+// Time is split in windows of one half-life. Two HLL sketches hold the items of the current and of the
+// previous window, older items are dropped. The estimate is the estimate of the current window plus
+// the estimate of the items seen only in the previous window, weighted by 2^(-age / halfLife),
+// where age is the time elapsed since the previous window ended.
+type DecayingHllSketch struct {
+	lgK          int
+	halfLife     time.Duration
+	clock        func() time.Time
+	current      HllSketch
+	previous     HllSketch
+	currentStart time.Time
+}
+
+// NewDecayingHllSketch constructs a new empty sketch.
+//
+//   - lgK, the Log2 of K of the underlying HLL sketches, between 4 and 21 inclusively.
+//   - halfLifeSeconds, the half-life of the decay and the length of a window, it must be positive.
+//   - clock, the source of the current time, time.Now if nil.
+func NewDecayingHllSketch(lgK int, halfLifeSeconds float64, clock func() time.Time) (*DecayingHllSketch, error) {
+	if !(halfLifeSeconds > 0) || math.IsInf(halfLifeSeconds, 0) {
+		return nil, fmt.Errorf("halfLifeSeconds must be positive and finite: %f", halfLifeSeconds)
+	}
+	halfLife := time.Duration(halfLifeSeconds * float64(time.Second))
+	if halfLife <= 0 {
+		return nil, fmt.Errorf("halfLifeSeconds is too small: %f", halfLifeSeconds)
+	}
+	if clock == nil {
+		clock = time.Now
+	}
+	current, err := NewHllSketch(lgK, TgtHllTypeDefault)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := NewHllSketch(lgK, TgtHllTypeDefault)
+	if err != nil {
+		return nil, err
+	}
+	return &DecayingHllSketch{
+		lgK:          lgK,
+		halfLife:     halfLife,
+		clock:        clock,
+		current:      current,
+		previous:     previous,
+		currentStart: clock(),
+	}, nil
+}
+
+// UpdateString presents the given string as a potential unique item of the current window.
+func (d *DecayingHllSketch) UpdateString(datum string) error {
+	if err := d.advance(d.clock()); err != nil {
+		return err
+	}
+	return d.current.UpdateString(datum)
+}
+
+// UpdateInt64 presents the given signed 64-bit integer as a potential unique item of the current window.
+func (d *DecayingHllSketch) UpdateInt64(datum int64) error {
+	if err := d.advance(d.clock()); err != nil {
+		return err
+	}
+	return d.current.UpdateInt64(datum)
+}
+
+// UpdateSlice presents the given byte slice as a potential unique item of the current window.
+func (d *DecayingHllSketch) UpdateSlice(datum []byte) error {
+	if err := d.advance(d.clock()); err != nil {
+		return err
+	}
+	return d.current.UpdateSlice(datum)
+}
+
+// Estimate returns the time adjusted cardinality estimate.
+func (d *DecayingHllSketch) Estimate() (float64, error) {
+	now := d.clock()
+	if err := d.advance(now); err != nil {
+		return 0, err
+	}
+	currentEst, err := d.current.GetEstimate()
+	if err != nil {
+		return 0, err
+	}
+	if d.previous.IsEmpty() {
+		return currentEst, nil
+	}
+
+	union, err := NewUnion(d.lgK)
+	if err != nil {
+		return 0, err
+	}
+	if err = union.UpdateSketch(d.current); err != nil {
+		return 0, err
+	}
+	if err = union.UpdateSketch(d.previous); err != nil {
+		return 0, err
+	}
+	unionEst, err := union.GetEstimate()
+	if err != nil {
+		return 0, err
+	}
+	previousOnly := math.Max(0, unionEst-currentEst)
+	weight := math.Exp2(-float64(now.Sub(d.currentStart)) / float64(d.halfLife))
+	return currentEst + weight*previousOnly, nil
+}
+
+// Reset drops the items of both windows and starts a new window now.
+func (d *DecayingHllSketch) Reset() error {
+	if err := d.current.Reset(); err != nil {
+		return err
+	}
+	if err := d.previous.Reset(); err != nil {
+		return err
+	}
+	d.currentStart = d.clock()
+	return nil
+}
+
+// advance rotates the windows up to the given time.
+func (d *DecayingHllSketch) advance(now time.Time) error {
+	elapsed := now.Sub(d.currentStart)
+	if elapsed < d.halfLife {
+		return nil
+	}
+	if elapsed < 2*d.halfLife {
+		// the current window becomes the previous one
+		if err := d.previous.Reset(); err != nil {
+			return err
+		}
+		d.current, d.previous = d.previous, d.current
+		d.currentStart = d.currentStart.Add(d.halfLife)
+		return nil
+	}
+	// both windows are too old
+	if err := d.current.Reset(); err != nil {
+		return err
+	}
+	if err := d.previous.Reset(); err != nil {
+		return err
+	}
+	d.currentStart = d.currentStart.Add(elapsed.Truncate(d.halfLife))
+	return nil
+}
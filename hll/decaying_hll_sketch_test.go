@@ -0,0 +1,87 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecayingHllSketch(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+	sk, err := NewDecayingHllSketch(12, 60, clock)
+	assert.NoError(t, err)
+
+	est, err := sk.Estimate()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, est)
+
+	n := 10000
+	for i := 0; i < n; i++ {
+		assert.NoError(t, sk.UpdateString(strconv.Itoa(i)))
+	}
+	est, err = sk.Estimate()
+	assert.NoError(t, err)
+	assert.InDelta(t, n, est, float64(n)*0.05)
+
+	// the first window is now the previous one, weighted by 2^(-30 / 60)
+	now = now.Add(90 * time.Second)
+	est, err = sk.Estimate()
+	assert.NoError(t, err)
+	assert.InDelta(t, float64(n)*0.7071, est, float64(n)*0.05)
+
+	// items seen again in the current window are not decayed nor counted twice
+	for i := 0; i < n; i++ {
+		assert.NoError(t, sk.UpdateInt64(int64(i)))
+	}
+	for i := 0; i < n/2; i++ {
+		assert.NoError(t, sk.UpdateString(strconv.Itoa(i)))
+	}
+	est, err = sk.Estimate()
+	assert.NoError(t, err)
+	expected := float64(n) + float64(n)/2 + 0.7071*float64(n)/2
+	assert.InDelta(t, expected, est, expected*0.05)
+
+	// everything older than two windows is dropped
+	now = now.Add(150 * time.Second)
+	est, err = sk.Estimate()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, est)
+
+	assert.NoError(t, sk.UpdateSlice([]byte("a")))
+	assert.NoError(t, sk.Reset())
+	est, err = sk.Estimate()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, est)
+}
+
+func TestDecayingHllSketch_InvalidArgs(t *testing.T) {
+	_, err := NewDecayingHllSketch(12, 0, nil)
+	assert.Error(t, err)
+	_, err = NewDecayingHllSketch(12, 1e-12, nil)
+	assert.Error(t, err)
+	_, err = NewDecayingHllSketch(3, 60, nil)
+	assert.Error(t, err)
+	sk, err := NewDecayingHllSketch(12, 60, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, sk.UpdateString("a"))
+}
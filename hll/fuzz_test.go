@@ -0,0 +1,74 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"testing"
+)
+
+// FuzzDeserialize checks that NewHllSketchFromSlice, NewUnionFromSlice and NewHllSketchFromVlqSlice
+// never panic: they must either return an error or a sketch that can be queried and serialized.
+func FuzzDeserialize(f *testing.F) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		for _, n := range []int{0, 1, 10, 100, 1000} {
+			sk, err := NewHllSketch(8, tgtHllType)
+			if err != nil {
+				f.Fatal(err)
+			}
+			for i := 0; i < n; i++ {
+				_ = sk.UpdateInt64(int64(i))
+			}
+			compact, err := sk.ToCompactSlice()
+			if err != nil {
+				f.Fatal(err)
+			}
+			updatable, err := sk.ToUpdatableSlice()
+			if err != nil {
+				f.Fatal(err)
+			}
+			vlq, err := sk.ToVlqSlice()
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(compact)
+			f.Add(updatable)
+			f.Add(vlq)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if sk, err := NewHllSketchFromSlice(data, true); err == nil {
+			checkFuzzedSketch(sk)
+		}
+		if sk, err := NewHllSketchFromVlqSlice(data); err == nil {
+			checkFuzzedSketch(sk)
+		}
+		if u, err := NewUnionFromSlice(data); err == nil {
+			_, _ = u.GetEstimate()
+			_, _ = u.GetResult(TgtHllTypeHll4)
+		}
+	})
+}
+
+func checkFuzzedSketch(sk HllSketch) {
+	_, _ = sk.GetEstimate()
+	_, _ = sk.GetUpperBound(2)
+	_, _ = sk.ToCompactSlice()
+	_, _ = sk.ToUpdatableSlice()
+	_, _ = sk.ToVlqSlice()
+}
@@ -54,6 +54,14 @@ func (h *hll4ArrayImpl) ToUpdatableSlice() ([]byte, error) {
 	return toHllByteArr(h, false)
 }
 
+func (h *hll4ArrayImpl) GetCompactSerializationBytes() int {
+	auxBytes := 0
+	if auxHashMap := h.getAuxHashMap(); auxHashMap != nil {
+		auxBytes = auxHashMap.getCompactSizeBytes()
+	}
+	return hllByteArrStart + h.getHllByteArrBytes() + auxBytes
+}
+
 func (h *hll4ArrayImpl) GetUpdatableSerializationBytes() int {
 	auxHashMap := h.getAuxHashMap()
 	auxBytes := 0
@@ -104,7 +112,9 @@ func newHll4Array(lgConfigK int) hllArray {
 func deserializeHll4(byteArray []byte) (hllArray, error) {
 	lgConfigK := extractLgK(byteArray)
 	hll4 := newHll4Array(lgConfigK)
-	hll4.extractCommonHll(byteArray)
+	if err := hll4.extractCommonHll(byteArray); err != nil {
+		return nil, err
+	}
 
 	auxStart := hll4.getAuxStart()
 	auxCount := extractAuxCount(byteArray)
@@ -118,6 +128,21 @@ func deserializeHll4(byteArray []byte) (hllArray, error) {
 		hll4.putAuxHashMap(auxHashMap, false)
 	}
 
+	// every slot holding the aux token must have its value in the aux hash map
+	numAuxTokens := 0
+	for slotNo := 0; slotNo < 1<<lgConfigK; slotNo++ {
+		if hll4.(*hll4ArrayImpl).getNibble(slotNo) == auxToken {
+			numAuxTokens++
+		}
+	}
+	numAuxValues := 0
+	if auxHashMap := hll4.getAuxHashMap(); auxHashMap != nil {
+		numAuxValues = auxHashMap.getAuxCount()
+	}
+	if numAuxTokens != numAuxValues {
+		return nil, fmt.Errorf("possible Corruption: %d aux tokens for %d aux values", numAuxTokens, numAuxValues)
+	}
+
 	return hll4, nil
 }
 
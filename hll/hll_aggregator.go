@@ -0,0 +1,95 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"encoding"
+	"fmt"
+)
+
+// HllAggregator is the state of an approximate distinct count aggregate function, as needed by
+// SQL engines and other partial aggregation frameworks: values are added to partial states,
+// the partial states are checkpointed with MarshalBinary, restored with UnmarshalBinary,
+// merged, and the final result is an HllSketch.
+//
+// It is backed by a Union, so states built with different lgK can be merged.
+type HllAggregator struct {
+	union Union
+}
+
+var (
+	_ encoding.BinaryMarshaler   = (*HllAggregator)(nil)
+	_ encoding.BinaryUnmarshaler = (*HllAggregator)(nil)
+)
+
+// NewAggregator constructs a new empty aggregator with the given lgK, between 4 and 21 inclusively.
+func NewAggregator(lgK int) (*HllAggregator, error) {
+	union, err := NewUnion(lgK)
+	if err != nil {
+		return nil, err
+	}
+	return &HllAggregator{union: union}, nil
+}
+
+// Add presents the given byte slice as a potential unique item.
+func (a *HllAggregator) Add(v []byte) error {
+	return a.union.UpdateSlice(v)
+}
+
+// AddString presents the given string as a potential unique item.
+func (a *HllAggregator) AddString(v string) error {
+	return a.union.UpdateString(v)
+}
+
+// AddInt64 presents the given int64 as a potential unique item.
+func (a *HllAggregator) AddInt64(v int64) error {
+	return a.union.UpdateInt64(v)
+}
+
+// Merge merges the state of other into this aggregator. other is not modified.
+func (a *HllAggregator) Merge(other *HllAggregator) error {
+	if other == nil || other.union == nil {
+		return fmt.Errorf("no aggregator provided")
+	}
+	result, err := other.union.GetResult(TgtHllTypeHll8)
+	if err != nil {
+		return err
+	}
+	return a.union.UpdateSketch(result)
+}
+
+// Result returns the final HllSketch of the aggregation, with the default TgtHllType.
+func (a *HllAggregator) Result() (HllSketch, error) {
+	return a.union.GetResult(TgtHllTypeDefault)
+}
+
+// MarshalBinary returns the updatable serialized form of the state of the aggregator.
+func (a *HllAggregator) MarshalBinary() ([]byte, error) {
+	return a.union.ToUpdatableSlice()
+}
+
+// UnmarshalBinary restores the state of the aggregator from the bytes returned by MarshalBinary,
+// replacing its current state.
+func (a *HllAggregator) UnmarshalBinary(data []byte) error {
+	union, err := NewUnionFromSlice(data)
+	if err != nil {
+		return err
+	}
+	a.union = union
+	return nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHllAggregator(t *testing.T) {
+	_, err := NewAggregator(2)
+	assert.Error(t, err)
+
+	// three partial aggregates over overlapping ranges, one with a smaller lgK
+	a1, err := NewAggregator(12)
+	assert.NoError(t, err)
+	a2, err := NewAggregator(12)
+	assert.NoError(t, err)
+	a3, err := NewAggregator(10)
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, a1.AddInt64(int64(i)))
+		assert.NoError(t, a2.AddString(strconv.Itoa(i+5000)))
+		assert.NoError(t, a3.Add([]byte(strconv.Itoa(i+10000))))
+	}
+
+	// checkpoint and restore a partial aggregate
+	bytes, err := a2.MarshalBinary()
+	assert.NoError(t, err)
+	restored := &HllAggregator{}
+	assert.NoError(t, restored.UnmarshalBinary(bytes))
+	r1, err := a2.Result()
+	assert.NoError(t, err)
+	r2, err := restored.Result()
+	assert.NoError(t, err)
+	est1, err := r1.GetEstimate()
+	assert.NoError(t, err)
+	est2, err := r2.GetEstimate()
+	assert.NoError(t, err)
+	assert.Equal(t, est1, est2)
+
+	assert.NoError(t, a1.Merge(restored))
+	assert.NoError(t, a1.Merge(a3))
+	assert.Error(t, a1.Merge(nil))
+	result, err := a1.Result()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result.GetLgConfigK())
+	est, err := result.GetEstimate()
+	assert.NoError(t, err)
+	// 0 to 9999 as int64, 5000 to 19999 as strings
+	assert.InDelta(t, 25000, est, 25000*0.05)
+
+	assert.Error(t, restored.UnmarshalBinary([]byte{1, 2}))
+}
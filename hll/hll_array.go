@@ -45,7 +45,7 @@ type hllArray interface {
 	putNumAtCurMin(numAtCurMin int)
 	putOutOfOrder(oooFlag bool)
 
-	extractCommonHll(byteArr []byte)
+	extractCommonHll(byteArr []byte) error
 	hipAndKxQIncrementalUpdate(oldValue int, newValue int) error
 }
 
@@ -122,6 +122,10 @@ func (a *hllArrayImpl) GetLowerBound(numStdDev int) (float64, error) {
 	return hllLowerBound(a, numStdDev)
 }
 
+func (a *hllArrayImpl) GetCompactSerializationBytes() int {
+	return hllByteArrStart + a.getHllByteArrBytes()
+}
+
 func (a *hllArrayImpl) GetUpdatableSerializationBytes() int {
 	return hllByteArrStart + a.getHllByteArrBytes()
 }
@@ -308,7 +312,10 @@ func (a *hllArrayImpl) incrementalUpdateKxQ(oldValue int, newValue int, kxq0 flo
 }
 
 // extractCommonHll extracts the common fields from the given byte array.
-func (a *hllArrayImpl) extractCommonHll(byteArr []byte) {
+func (a *hllArrayImpl) extractCommonHll(byteArr []byte) error {
+	if len(byteArr) < hllByteArrStart+len(a.hllByteArr) {
+		return fmt.Errorf("possible Corruption: input array too small: %d", len(byteArr))
+	}
 	a.putOutOfOrder(extractOooFlag(byteArr))
 	a.putCurMin(extractCurMin(byteArr))
 	a.putHipAccum(extractHipAccum(byteArr))
@@ -317,5 +324,6 @@ func (a *hllArrayImpl) extractCommonHll(byteArr []byte) {
 	a.putNumAtCurMin(extractNumAtCurMin(byteArr))
 	a.putRebuildCurMinNumKxQFlag(extractRebuildCurMinNumKxQFlag(byteArr))
 
-	a.hllByteArr = byteArr[hllByteArrStart : hllByteArrStart+len(a.hllByteArr)]
+	copy(a.hllByteArr, byteArr[hllByteArrStart:])
+	return nil
 }
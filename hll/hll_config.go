@@ -23,6 +23,8 @@ type hllSketchConfig struct { // extends hllSketchConfig
 	curMode    curMode
 
 	slotNoMask int // mask from lgConfigK to extract slotNo
+
+	sparseThreshold float64 // see WithSparseThreshold, only used by coupon sketches
 }
 
 func newHllSketchConfig(lgConfigK int, tgtHllType TgtHllType, curMode curMode) hllSketchConfig {
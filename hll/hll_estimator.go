@@ -24,8 +24,13 @@ import (
 // hllCompositeEstimate is the (non-HIP) estimator.
 // It is called "composite" because multiple estimators are pasted together.
 func hllCompositeEstimate(hllArray *hllArrayImpl) (float64, error) {
-	lgConfigK := hllArray.lgConfigK
-	rawEst := getHllRawEstimate(lgConfigK, hllArray.kxq0+hllArray.kxq1)
+	return compositeEstimate(hllArray.lgConfigK, hllArray.kxq0+hllArray.kxq1, hllArray.curMin, hllArray.numAtCurMin)
+}
+
+// compositeEstimate is the composite estimator as a function of the sum of 2^-value over the registers,
+// the minimum register value, and the number of registers at that minimum.
+func compositeEstimate(lgConfigK int, kxqSum float64, curMin int, numAtCurMin int) (float64, error) {
+	rawEst := getHllRawEstimate(lgConfigK, kxqSum)
 
 	xArr := compositeInterpolationXarrs[lgConfigK-minLogK]
 	yStride := compositeInterpolationYstrides[lgConfigK-minLogK]
@@ -52,7 +57,7 @@ func hllCompositeEstimate(hllArray *hllArrayImpl) (float64, error) {
 		return adjEst, nil
 	}
 
-	linEst := getHllBitMapEstimate(lgConfigK, hllArray.curMin, hllArray.numAtCurMin)
+	linEst := getHllBitMapEstimate(lgConfigK, curMin, numAtCurMin)
 
 	// Bias is created when the value of an estimator is compared with a threshold to decide whether
 	// to use that estimator or a different one.
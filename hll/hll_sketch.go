@@ -25,7 +25,10 @@ package hll
 
 import (
 	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/bits"
 	"unsafe"
 
@@ -33,6 +36,11 @@ import (
 	"github.com/twmb/murmur3"
 )
 
+// HllSketch is a HyperLogLog sketch, created with NewHllSketch or deserialized with NewHllSketchFromSlice.
+//
+// Sketches support encoding/gob: their concrete type is registered with gob and implements
+// gob.GobEncoder with the compact form, so an HllSketch can be encoded and decoded as an
+// interface value or as a field of a struct.
 type HllSketch interface {
 	// Copy returns a clone of this sketch.
 	Copy() (HllSketch, error)
@@ -64,6 +72,21 @@ type HllSketch interface {
 	// UpdateString present the given string as a potential unique item.
 	UpdateString(datum string) error
 
+	// UpdateInt64Batch presents each of the given signed 64-bit integers as a potential unique item.
+	// The resulting sketch is the same as calling UpdateInt64 for each item in sequence.
+	// On error, the items before the failing one have already been applied to the sketch.
+	UpdateInt64Batch(data []int64) error
+
+	// UpdateBytesBatch presents each of the given byte slices as a potential unique item.
+	// The resulting sketch is the same as calling UpdateSlice for each item in sequence.
+	// On error, the items before the failing one have already been applied to the sketch.
+	UpdateBytesBatch(data [][]byte) error
+
+	// UpdateStringBatch presents each of the given strings as a potential unique item.
+	// The resulting sketch is the same as calling UpdateString for each item in sequence.
+	// On error, the items before the failing one have already been applied to the sketch.
+	UpdateStringBatch(data []string) error
+
 	// Reset resets the sketch to empty, but does not change the configured values of lgConfigK and tgtHllType.
 	Reset() error
 
@@ -89,6 +112,10 @@ type HllSketch interface {
 	// GetCurMode returns the current mode of the sketch: LIST, SET, HLL.
 	GetCurMode() curMode
 
+	// GetCompactSerializationBytes gets the size in bytes of the current sketch when serialized using
+	// ToCompactSlice, without serializing it.
+	GetCompactSerializationBytes() int
+
 	// GetUpdatableSerializationBytes gets the size in bytes of the current sketch when serialized using
 	// ToUpdatableSlice.
 	GetUpdatableSerializationBytes() int
@@ -101,8 +128,22 @@ type HllSketch interface {
 	// The updatable form is larger than the compact form.
 	ToUpdatableSlice() ([]byte, error)
 
+	// WriteTo writes the sketch to w in the compact form, the bytes written are identical to ToCompactSlice.
+	WriteTo(w io.Writer) (int64, error)
+
+	// WriteUpdatableTo writes the sketch to w in the updatable form, the bytes written are identical
+	// to ToUpdatableSlice.
+	WriteUpdatableTo(w io.Writer) (int64, error)
+
+	// ToVlqSlice serializes only the non-zero registers of the sketch, with variable-length
+	// encoded indices and 4-bit values, see NewHllSketchFromVlqSlice.
+	ToVlqSlice() ([]byte, error)
+
 	GetSerializationVersion() int
 
+	// String returns a JSON summary of the sketch for debugging, not a serialization.
+	String() string
+
 	couponUpdate(coupon int) (hllSketchStateI, error)
 	iterator() pairIterator
 }
@@ -119,6 +160,7 @@ type hllSketchStateI interface {
 	GetTgtHllType() TgtHllType
 	GetCurMode() curMode
 
+	GetCompactSerializationBytes() int
 	GetUpdatableSerializationBytes() int
 	ToCompactSlice() ([]byte, error)
 	ToUpdatableSlice() ([]byte, error)
@@ -141,6 +183,11 @@ type hllSketchStateI interface {
 type hllSketchState struct { // extends BaseHllSketch
 	sketch  hllSketchStateI
 	scratch [8]byte
+	options hllSketchOptions
+}
+
+func init() {
+	gob.Register(&hllSketchState{})
 }
 
 func newHllSketchState(coupon hllSketchStateI) HllSketch {
@@ -157,17 +204,24 @@ func newHllSketchState(coupon hllSketchStateI) HllSketch {
 // between 4 and 21 inclusively.
 //
 //   - tgtHllType. the desired HLL type.
-func NewHllSketch(lgConfigK int, tgtHllType TgtHllType) (HllSketch, error) {
+//
+// Optional behaviour, such as the promotion from the sparse modes, is set with opts.
+func NewHllSketch(lgConfigK int, tgtHllType TgtHllType, opts ...HllSketchOption) (HllSketch, error) {
 	lgK := lgConfigK
 	lgK, err := checkLgK(lgK)
 	if err != nil {
 		return nil, err
 	}
+	options, err := newHllSketchOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 	couponList, err := newCouponList(lgK, tgtHllType, curModeList)
 	if err != nil {
 		return nil, err
 	}
-	return newHllSketchState(&couponList), nil
+	couponList.sparseThreshold = options.sparseThreshold
+	return &hllSketchState{sketch: &couponList, options: options}, nil
 }
 
 // NewHllSketchWithDefault constructs a new on-heap sketch with the default lgK and tgtHllType.
@@ -195,9 +249,17 @@ func NewHllSketchFromSlice(bytes []byte, checkRebuild bool) (HllSketch, error) {
 			}
 			return newHllSketchState(sk), nil
 		} else if tgtHllType == TgtHllTypeHll6 {
-			return newHllSketchState(deserializeHll6(bytes)), nil
+			sk, err := deserializeHll6(bytes)
+			if err != nil {
+				return nil, err
+			}
+			return newHllSketchState(sk), nil
 		} else {
-			a := newHllSketchState(deserializeHll8(bytes))
+			sk, err := deserializeHll8(bytes)
+			if err != nil {
+				return nil, err
+			}
+			a := newHllSketchState(sk)
 			if checkRebuild {
 				err := checkRebuildCurMinNumKxQ(a)
 				if err != nil {
@@ -221,12 +283,98 @@ func NewHllSketchFromSlice(bytes []byte, checkRebuild bool) (HllSketch, error) {
 	}
 }
 
+// NewHllSketchFromReader deserializes a sketch read from r, in either the compact or the updatable form.
+// Exactly the bytes of one sketch are read, the length being computed from the preamble,
+// so several sketches written one after the other to the same stream can be read back in turn.
+func NewHllSketchFromReader(r io.Reader) (HllSketch, error) {
+	bytes := make([]byte, 8)
+	if _, err := io.ReadFull(r, bytes); err != nil {
+		return nil, fmt.Errorf("reading sketch: %w", err)
+	}
+	bytes, err := readSketchBytes(r, bytes, extractPreInts(bytes)*4)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkPreamble(bytes); err != nil {
+		return nil, err
+	}
+	serializedBytes, err := getSerializedBytes(bytes)
+	if err != nil {
+		return nil, err
+	}
+	if bytes, err = readSketchBytes(r, bytes, serializedBytes); err != nil {
+		return nil, err
+	}
+	return NewHllSketchFromSlice(bytes, true)
+}
+
+// getSerializedBytes returns the length of the serialized sketch starting with the given checked preamble.
+func getSerializedBytes(preamble []byte) (int, error) {
+	lgConfigK := extractLgK(preamble)
+	compact := extractCompactFlag(preamble)
+	curMode := extractCurMode(preamble)
+	if curMode == curModeHll {
+		tgtHllType := extractTgtHllType(preamble)
+		arrBytes := 1 << lgConfigK
+		if tgtHllType == TgtHllTypeHll4 {
+			arrBytes = 1 << (lgConfigK - 1)
+			auxCount := extractAuxCount(preamble)
+			if compact {
+				if auxCount < 0 || auxCount > 1<<lgConfigK {
+					return 0, fmt.Errorf("possible Corruption: Invalid Aux Count: %d", auxCount)
+				}
+				arrBytes += auxCount << 2
+			} else {
+				// an updatable sketch without aux hash map still has room for the default one
+				lgAuxArr := max(extractLgArr(preamble), lgAuxArrInts[lgConfigK])
+				if lgAuxArr > lgConfigK {
+					return 0, fmt.Errorf("possible Corruption: Invalid Aux Array Size: %d", lgAuxArr)
+				}
+				arrBytes += 4 << lgAuxArr
+			}
+		} else if tgtHllType == TgtHllTypeHll6 {
+			arrBytes = (((1 << lgConfigK) * 3) >> 2) + 1
+		}
+		return hllByteArrStart + arrBytes, nil
+	}
+	dataStart := listIntArrStart
+	couponCount := extractListCount(preamble)
+	if curMode == curModeSet {
+		dataStart = hashSetIntArrStart
+		couponCount = extractHashSetCount(preamble)
+	}
+	if compact {
+		if couponCount < 0 || couponCount > 1<<lgConfigK {
+			return 0, fmt.Errorf("possible Corruption: Invalid Coupon Count: %d", couponCount)
+		}
+		return dataStart + couponCount<<2, nil
+	}
+	lgCouponArrInts := extractLgArr(preamble)
+	if lgCouponArrInts > lgConfigK {
+		return 0, fmt.Errorf("possible Corruption: Invalid Coupon Array Size: %d", lgCouponArrInts)
+	}
+	return dataStart + 4<<lgCouponArrInts, nil
+}
+
+// readSketchBytes grows bytes to n bytes with bytes read from r.
+func readSketchBytes(r io.Reader, bytes []byte, n int) ([]byte, error) {
+	if n <= len(bytes) {
+		return bytes, nil
+	}
+	start := len(bytes)
+	bytes = append(bytes, make([]byte, n-start)...)
+	if _, err := io.ReadFull(r, bytes[start:]); err != nil {
+		return nil, fmt.Errorf("reading sketch: %w", err)
+	}
+	return bytes, nil
+}
+
 func (h *hllSketchState) Copy() (HllSketch, error) {
 	sketch, err := h.sketch.copy()
 	if err != nil {
 		return nil, err
 	}
-	return newHllSketchState(sketch), nil
+	return &hllSketchState{sketch: sketch, options: h.options}, nil
 }
 
 func (h *hllSketchState) CopyAs(tgtHllType TgtHllType) (HllSketch, error) {
@@ -234,7 +382,7 @@ func (h *hllSketchState) CopyAs(tgtHllType TgtHllType) (HllSketch, error) {
 	if err != nil {
 		return nil, err
 	}
-	return newHllSketchState(sketch), nil
+	return &hllSketchState{sketch: sketch, options: h.options}, nil
 }
 
 func (h *hllSketchState) GetCompositeEstimate() (float64, error) {
@@ -257,6 +405,38 @@ func (h *hllSketchState) GetLowerBound(numStdDev int) (float64, error) {
 	return h.sketch.GetLowerBound(numStdDev)
 }
 
+func (h *hllSketchState) GetCompactSerializationBytes() int {
+	return h.sketch.GetCompactSerializationBytes()
+}
+
+func (h *hllSketchState) String() string {
+	summary := struct {
+		Type           string  `json:"type"`
+		LgConfigK      int     `json:"lg_config_k"`
+		TgtHllType     string  `json:"tgt_hll_type"`
+		CurMode        string  `json:"cur_mode"`
+		Estimate       float64 `json:"estimate"`
+		IsEmpty        bool    `json:"is_empty"`
+		IsOutOfOrder   bool    `json:"is_out_of_order"`
+		SerializedSize int     `json:"compact_serialization_bytes"`
+	}{
+		Type:           "hll",
+		LgConfigK:      h.GetLgConfigK(),
+		TgtHllType:     h.GetTgtHllType().String(),
+		CurMode:        h.GetCurMode().String(),
+		IsEmpty:        h.IsEmpty(),
+		IsOutOfOrder:   h.sketch.isOutOfOrder(),
+		SerializedSize: h.GetCompactSerializationBytes(),
+	}
+	// the estimate cannot fail on a valid sketch, and is left at 0 otherwise
+	summary.Estimate, _ = h.GetEstimate()
+	out, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Sprintf("%+v", summary)
+	}
+	return string(out)
+}
+
 func (h *hllSketchState) GetUpdatableSerializationBytes() int {
 	return h.sketch.GetUpdatableSerializationBytes()
 }
@@ -284,6 +464,46 @@ func (h *hllSketchState) UpdateString(datum string) error {
 	return h.UpdateSlice(unsafe.Slice(unsafe.StringData(datum), len(datum)))
 }
 
+func (h *hllSketchState) UpdateInt64Batch(data []int64) error {
+	for _, datum := range data {
+		binary.LittleEndian.PutUint64(h.scratch[:], uint64(datum))
+		sk, err := h.sketch.couponUpdate(coupon(h.hash(h.scratch[:])))
+		if err != nil {
+			return err
+		}
+		h.sketch = sk
+	}
+	return nil
+}
+
+func (h *hllSketchState) UpdateBytesBatch(data [][]byte) error {
+	for _, datum := range data {
+		if len(datum) == 0 {
+			continue
+		}
+		sk, err := h.sketch.couponUpdate(coupon(h.hash(datum)))
+		if err != nil {
+			return err
+		}
+		h.sketch = sk
+	}
+	return nil
+}
+
+func (h *hllSketchState) UpdateStringBatch(data []string) error {
+	for _, datum := range data {
+		if len(datum) == 0 {
+			continue
+		}
+		sk, err := h.sketch.couponUpdate(coupon(h.hashString(datum)))
+		if err != nil {
+			return err
+		}
+		h.sketch = sk
+	}
+	return nil
+}
+
 func (h *hllSketchState) IsEmpty() bool {
 	return h.sketch.IsEmpty()
 }
@@ -296,6 +516,50 @@ func (h *hllSketchState) ToUpdatableSlice() ([]byte, error) {
 	return h.sketch.ToUpdatableSlice()
 }
 
+func (h *hllSketchState) WriteTo(w io.Writer) (int64, error) {
+	bytes, err := h.sketch.ToCompactSlice()
+	if err != nil {
+		return 0, err
+	}
+	return writeSketch(w, bytes)
+}
+
+func (h *hllSketchState) WriteUpdatableTo(w io.Writer) (int64, error) {
+	bytes, err := h.sketch.ToUpdatableSlice()
+	if err != nil {
+		return 0, err
+	}
+	return writeSketch(w, bytes)
+}
+
+// GobEncode implements gob.GobEncoder with the bytes returned by ToCompactSlice.
+// It is not part of HllSketch, since gob would then treat every HllSketch field as a
+// GobDecoder and not as an interface.
+func (h *hllSketchState) GobEncode() ([]byte, error) {
+	return h.sketch.ToCompactSlice()
+}
+
+// GobDecode implements gob.GobDecoder, replacing the state of the sketch with the one serialized in data.
+func (h *hllSketchState) GobDecode(data []byte) error {
+	sk, err := NewHllSketchFromSlice(data, true)
+	if err != nil {
+		return err
+	}
+	h.sketch = sk.(*hllSketchState).sketch
+	return nil
+}
+
+func writeSketch(w io.Writer, bytes []byte) (int64, error) {
+	n, err := w.Write(bytes)
+	if err == nil && n < len(bytes) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		return int64(n), fmt.Errorf("writing sketch: %w", err)
+	}
+	return int64(n), nil
+}
+
 func (h *hllSketchState) GetLgConfigK() int {
 	return h.sketch.GetLgConfigK()
 }
@@ -317,6 +581,7 @@ func (h *hllSketchState) Reset() error {
 	if err != nil {
 		return err
 	}
+	couponList.sparseThreshold = h.options.sparseThreshold
 	h.sketch = &couponList
 	return nil
 }
@@ -357,3 +622,8 @@ func (h *hllSketchState) GetSerializationVersion() int {
 func (h *hllSketchState) hash(bs []byte) (uint64, uint64) {
 	return murmur3.SeedSum128(internal.DEFAULT_UPDATE_SEED, internal.DEFAULT_UPDATE_SEED, bs)
 }
+
+// hashString returns the same hash as hash for the bytes of s, without copying them.
+func (h *hllSketchState) hashString(s string) (uint64, uint64) {
+	return murmur3.SeedStringSum128(internal.DEFAULT_UPDATE_SEED, internal.DEFAULT_UPDATE_SEED, s)
+}
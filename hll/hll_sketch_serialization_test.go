@@ -18,6 +18,9 @@
 package hll
 
 import (
+	"bytes"
+	"encoding/gob"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -72,8 +75,8 @@ func TestJavaCompat(t *testing.T) {
 			bytes, err := os.ReadFile(fmt.Sprintf("%s/hll4_n%d_java.sk", internal.JavaPath, n))
 			assert.NoError(t, err)
 			sketch, err := NewHllSketchFromSlice(bytes, true)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, 12, sketch.GetLgConfigK())
@@ -90,8 +93,8 @@ func TestJavaCompat(t *testing.T) {
 			assert.NoError(t, err)
 
 			sketch, err := NewHllSketchFromSlice(bytes, true)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, 12, sketch.GetLgConfigK())
@@ -107,8 +110,8 @@ func TestJavaCompat(t *testing.T) {
 			bytes, err := os.ReadFile(fmt.Sprintf("%s/hll8_n%d_java.sk", internal.JavaPath, n))
 			assert.NoError(t, err)
 			sketch, err := NewHllSketchFromSlice(bytes, true)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, 12, sketch.GetLgConfigK())
@@ -126,8 +129,8 @@ func TestCppCompat(t *testing.T) {
 			bytes, err := os.ReadFile(fmt.Sprintf("%s/hll4_n%d_cpp.sk", internal.CppPath, n))
 			assert.NoError(t, err)
 			sketch, err := NewHllSketchFromSlice(bytes, true)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, 12, sketch.GetLgConfigK())
@@ -144,8 +147,8 @@ func TestCppCompat(t *testing.T) {
 			assert.NoError(t, err)
 
 			sketch, err := NewHllSketchFromSlice(bytes, true)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, 12, sketch.GetLgConfigK())
@@ -161,8 +164,8 @@ func TestCppCompat(t *testing.T) {
 			bytes, err := os.ReadFile(fmt.Sprintf("%s/hll8_n%d_cpp.sk", internal.CppPath, n))
 			assert.NoError(t, err)
 			sketch, err := NewHllSketchFromSlice(bytes, true)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, 12, sketch.GetLgConfigK())
@@ -173,6 +176,72 @@ func TestCppCompat(t *testing.T) {
 	})
 }
 
+// TestCppFixtures checks the fields decoded from the C++ files, one case per kind of sketch,
+// so that a mismatch names the field rather than only the estimate.
+func TestCppFixtures(t *testing.T) {
+	cases := []struct {
+		name      string
+		n         int
+		curMode   curMode
+		isEmpty   bool
+		exact     bool
+		sameBytes bool // whether ToCompactSlice reproduces the C++ file byte for byte
+	}{
+		{"empty", 0, curModeList, true, true, true},
+		{"single", 1, curModeList, false, true, true},
+		{"exact", 10, curModeSet, false, true, false},
+		{"exact", 100, curModeSet, false, true, false},
+		{"estimation", 1000, curModeHll, false, false, false},
+		{"estimation", 1000000, curModeHll, false, false, false},
+	}
+	prefixes := map[TgtHllType]string{TgtHllTypeHll4: "hll4", TgtHllTypeHll6: "hll6", TgtHllTypeHll8: "hll8"}
+	for tgtHllType, prefix := range prefixes {
+		for _, c := range cases {
+			t.Run(fmt.Sprintf("%s %s n=%d", tgtHllType, c.name, c.n), func(t *testing.T) {
+				file := fmt.Sprintf("%s/%s_n%d_cpp.sk", internal.CppPath, prefix, c.n)
+				bytes, err := os.ReadFile(file)
+				if !assert.NoError(t, err, "reading %s", file) {
+					return
+				}
+				sketch, err := NewHllSketchFromSlice(bytes, true)
+				if !assert.NoError(t, err, "deserializing %s", file) {
+					return
+				}
+				assert.Equal(t, 12, sketch.GetLgConfigK(), "lgConfigK")
+				assert.Equal(t, tgtHllType, sketch.GetTgtHllType(), "tgtHllType")
+				assert.Equal(t, c.curMode, sketch.GetCurMode(), "curMode")
+				assert.Equal(t, c.isEmpty, sketch.IsEmpty(), "isEmpty")
+				est, err := sketch.GetEstimate()
+				assert.NoError(t, err, "estimate")
+				if c.exact {
+					assert.InDelta(t, c.n, est, 1e-3, "exact estimate")
+				} else {
+					assert.InDelta(t, c.n, est, float64(c.n)*0.02, "estimate")
+				}
+				lb, err := sketch.GetLowerBound(2)
+				assert.NoError(t, err, "lower bound")
+				assert.LessOrEqual(t, lb, est, "lower bound")
+				ub, err := sketch.GetUpperBound(2)
+				assert.NoError(t, err, "upper bound")
+				assert.GreaterOrEqual(t, ub, est, "upper bound")
+
+				compact, err := sketch.ToCompactSlice()
+				assert.NoError(t, err, "serializing")
+				if c.sameBytes {
+					assert.Equal(t, bytes, compact, "compact bytes")
+				}
+				sketch2, err := NewHllSketchFromSlice(compact, true)
+				if !assert.NoError(t, err, "deserializing the compact bytes") {
+					return
+				}
+				est2, err := sketch2.GetEstimate()
+				assert.NoError(t, err)
+				assert.Equal(t, est, est2, "estimate after round trip")
+			})
+		}
+	}
+}
+
 func TestGoCompat(t *testing.T) {
 	nArr := []int{0, 1, 10, 100, 1000, 10000, 100000, 1000000}
 	for _, n := range nArr {
@@ -254,3 +323,204 @@ func TestGoCompat(t *testing.T) {
 func clearCompactFlag(flags byte) byte {
 	return flags & ^(uint8(1) << 3)
 }
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		for _, n := range []int{0, 1, 100, 10000} {
+			sk, err := NewHllSketch(12, tgtHllType)
+			assert.NoError(t, err)
+			for i := 0; i < n; i++ {
+				assert.NoError(t, sk.UpdateInt64(int64(i)))
+			}
+
+			compact, err := sk.ToCompactSlice()
+			assert.NoError(t, err)
+			var buf bytes.Buffer
+			written, err := sk.WriteTo(&buf)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(compact)), written)
+			assert.Equal(t, compact, buf.Bytes())
+
+			sk2, err := NewHllSketchFromReader(&buf)
+			assert.NoError(t, err)
+			est1, err := sk.GetEstimate()
+			assert.NoError(t, err)
+			est2, err := sk2.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, est1, est2)
+
+			updatable, err := sk.ToUpdatableSlice()
+			assert.NoError(t, err)
+			buf.Reset()
+			written, err = sk.WriteUpdatableTo(&buf)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(updatable)), written)
+			assert.Equal(t, updatable, buf.Bytes())
+
+			sk3, err := NewHllSketchFromReader(&buf)
+			assert.NoError(t, err)
+			est3, err := sk3.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, est1, est3)
+		}
+	}
+}
+
+func TestReadFromConsecutiveSketches(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		// 0 and 5 are in list mode, 100 in set mode, 10000 in HLL mode with an aux hash map for HLL4
+		ns := []int{0, 5, 100, 10000}
+		var buf bytes.Buffer
+		var estimates []float64
+		for _, n := range ns {
+			sk, err := NewHllSketch(10, tgtHllType)
+			assert.NoError(t, err)
+			for i := 0; i < n; i++ {
+				assert.NoError(t, sk.UpdateInt64(int64(i)))
+			}
+			est, err := sk.GetEstimate()
+			assert.NoError(t, err)
+			_, err = sk.WriteTo(&buf)
+			assert.NoError(t, err)
+			_, err = sk.WriteUpdatableTo(&buf)
+			assert.NoError(t, err)
+			estimates = append(estimates, est, est)
+		}
+
+		for _, expected := range estimates {
+			sk, err := NewHllSketchFromReader(&buf)
+			assert.NoError(t, err)
+			est, err := sk.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, est)
+		}
+		assert.Equal(t, 0, buf.Len())
+		_, err := NewHllSketchFromReader(&buf)
+		assert.Error(t, err)
+	}
+}
+
+func TestWriteToError(t *testing.T) {
+	sk, err := NewHllSketch(12, TgtHllTypeHll8)
+	assert.NoError(t, err)
+	_, err = sk.WriteTo(failingWriter{})
+	assert.Error(t, err)
+	_, err = sk.WriteUpdatableTo(failingWriter{})
+	assert.Error(t, err)
+	_, err = NewHllSketchFromReader(bytes.NewReader([]byte{1, 2}))
+	assert.Error(t, err)
+}
+
+func TestHllSketchGob(t *testing.T) {
+	type record struct {
+		Name   string
+		Sketch HllSketch
+	}
+	for _, n := range []int{0, 10, 1000, 100000} {
+		sk, err := NewHllSketch(12, TgtHllTypeHll6)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, sk.UpdateInt64(int64(i)))
+		}
+
+		var buf bytes.Buffer
+		assert.NoError(t, gob.NewEncoder(&buf).Encode(&record{Name: "users", Sketch: sk}))
+		var decoded record
+		assert.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+		assert.Equal(t, "users", decoded.Name)
+		assert.Equal(t, sk.GetLgConfigK(), decoded.Sketch.GetLgConfigK())
+		assert.Equal(t, sk.GetTgtHllType(), decoded.Sketch.GetTgtHllType())
+		assert.Equal(t, sk.GetCurMode(), decoded.Sketch.GetCurMode())
+		expected, err := sk.GetEstimate()
+		assert.NoError(t, err)
+		actual, err := decoded.Sketch.GetEstimate()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	sk, err := NewHllSketch(12, TgtHllTypeHll4)
+	assert.NoError(t, err)
+	assert.Error(t, sk.(gob.GobDecoder).GobDecode([]byte{1, 2, 3}))
+}
+
+func TestDeserializeCorruptPreamble(t *testing.T) {
+	sk, err := NewHllSketch(12, TgtHllTypeHll8)
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, sk.UpdateInt64(int64(i)))
+	}
+	bytes, err := sk.ToCompactSlice()
+	assert.NoError(t, err)
+
+	for _, lgK := range []byte{0, 3, 22, 255} {
+		corrupt := append([]byte{}, bytes...)
+		corrupt[lgKByte] = lgK
+		_, err = NewHllSketchFromSlice(corrupt, true)
+		assert.ErrorIs(t, err, ErrInvalidLgK)
+	}
+	_, err = NewHllSketch(22, TgtHllTypeHll8)
+	assert.ErrorIs(t, err, ErrInvalidLgK)
+
+	corrupt := append([]byte{}, bytes...)
+	corrupt[familyByte] = byte(internal.FamilyEnum.Kll.Id)
+	_, err = NewHllSketchFromSlice(corrupt, true)
+	assert.Error(t, err)
+
+	_, err = NewHllSketchFromSlice(bytes[:len(bytes)-1], true)
+	assert.Error(t, err)
+}
+
+func TestSerializationBytes(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		for _, n := range []int{0, 5, 100, 1000, 10000} {
+			sk, err := NewHllSketch(10, tgtHllType)
+			assert.NoError(t, err)
+			for i := 0; i < n; i++ {
+				assert.NoError(t, sk.UpdateInt64(int64(i)))
+			}
+			compact, err := sk.ToCompactSlice()
+			assert.NoError(t, err)
+			assert.Equal(t, len(compact), sk.GetCompactSerializationBytes(), "type %d, n=%d", tgtHllType, n)
+			updatable, err := sk.ToUpdatableSlice()
+			assert.NoError(t, err)
+			assert.Equal(t, len(updatable), sk.GetUpdatableSerializationBytes(), "type %d, n=%d", tgtHllType, n)
+		}
+	}
+
+	// the HLL4 result of a union may carry an aux map
+	u, err := NewUnion(10)
+	assert.NoError(t, err)
+	for i := 0; i < 100000; i++ {
+		assert.NoError(t, u.UpdateInt64(int64(i)))
+	}
+	compact, err := u.ToCompactSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, len(compact), u.GetCompactSerializationBytes())
+	sk, err := u.GetResult(TgtHllTypeHll4)
+	assert.NoError(t, err)
+	compact, err = sk.ToCompactSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, len(compact), sk.GetCompactSerializationBytes())
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	for _, path := range []struct{ dir, suffix string }{{internal.JavaPath, "java"}, {internal.CppPath, "cpp"}} {
+		for _, name := range []string{"hll4", "hll6", "hll8"} {
+			for _, n := range []int{0, 1, 10, 100, 1000, 10000, 100000, 1000000} {
+				bytes, err := os.ReadFile(fmt.Sprintf("%s/%s_n%d_%s.sk", path.dir, name, n, path.suffix))
+				assert.NoError(t, err)
+				assert.NoError(t, CheckCompatibility(bytes, float64(n), float64(n)*0.02), "%s %s n=%d", path.suffix, name, n)
+				if n > 0 {
+					assert.Error(t, CheckCompatibility(bytes, float64(n)*2, float64(n)*0.02))
+				}
+			}
+		}
+	}
+	assert.Error(t, CheckCompatibility([]byte{1, 2, 3}, 0, 0))
+}
@@ -19,7 +19,9 @@ package hll
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"math"
 	"strconv"
 	"testing"
 
@@ -459,6 +461,46 @@ func TestHLL4RawStoredOldNibbleAndShiftedNewValueAuxToken(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestBatchUpdates(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		// cover LIST, SET and HLL modes
+		for _, n := range []int{0, 5, 100, 10000} {
+			ints := make([]int64, n)
+			strs := make([]string, n+1)
+			bytes := make([][]byte, n+1)
+			for i := 0; i < n; i++ {
+				ints[i] = int64(i)
+				strs[i] = strconv.Itoa(i)
+				bytes[i] = []byte(strs[i])
+			}
+			// empty items are ignored like in the single item methods
+			strs[n] = ""
+			bytes[n] = nil
+
+			single, err := NewHllSketch(10, tgtHllType)
+			assert.NoError(t, err)
+			batch, err := NewHllSketch(10, tgtHllType)
+			assert.NoError(t, err)
+			for i := 0; i < n; i++ {
+				assert.NoError(t, single.UpdateInt64(ints[i]))
+			}
+			for i := 0; i <= n; i++ {
+				assert.NoError(t, single.UpdateString(strs[i]))
+				assert.NoError(t, single.UpdateSlice(bytes[i]))
+			}
+			assert.NoError(t, batch.UpdateInt64Batch(ints))
+			assert.NoError(t, batch.UpdateStringBatch(strs))
+			assert.NoError(t, batch.UpdateBytesBatch(bytes))
+
+			singleBytes, err := single.ToUpdatableSlice()
+			assert.NoError(t, err)
+			batchBytes, err := batch.ToUpdatableSlice()
+			assert.NoError(t, err)
+			assert.Equal(t, singleBytes, batchBytes)
+		}
+	}
+}
+
 func BenchmarkHLLMerge(b *testing.B) {
 	hll1, err := NewHllSketch(11, TgtHllTypeHll8)
 	for i := uint64(0); i < 29197004; i++ {
@@ -473,3 +515,150 @@ func BenchmarkHLLMerge(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkHLLBatchUpdate(b *testing.B) {
+	const batchSize = 1000
+	strs := make([]string, batchSize)
+	ints := make([]int64, batchSize)
+	for i := range strs {
+		strs[i] = strconv.Itoa(i)
+		ints[i] = int64(i)
+	}
+
+	b.Run("string single", func(b *testing.B) {
+		hll, _ := NewHllSketch(12, TgtHllTypeHll8)
+		for i := 0; i < b.N; i++ {
+			for _, s := range strs {
+				_ = hll.UpdateString(s)
+			}
+		}
+	})
+	b.Run("string batch", func(b *testing.B) {
+		hll, _ := NewHllSketch(12, TgtHllTypeHll8)
+		for i := 0; i < b.N; i++ {
+			_ = hll.UpdateStringBatch(strs)
+		}
+	})
+	b.Run("int64 single", func(b *testing.B) {
+		hll, _ := NewHllSketch(12, TgtHllTypeHll8)
+		for i := 0; i < b.N; i++ {
+			for _, v := range ints {
+				_ = hll.UpdateInt64(v)
+			}
+		}
+	})
+	b.Run("int64 batch", func(b *testing.B) {
+		hll, _ := NewHllSketch(12, TgtHllTypeHll8)
+		for i := 0; i < b.N; i++ {
+			_ = hll.UpdateInt64Batch(ints)
+		}
+	})
+}
+
+func TestHllSketchString(t *testing.T) {
+	sk, err := NewHllSketch(10, TgtHllTypeHll6)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"hll","lg_config_k":10,"tgt_hll_type":"HLL_6","cur_mode":"LIST","estimate":0,`+
+		`"is_empty":true,"is_out_of_order":false,"compact_serialization_bytes":8}`, sk.String())
+
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, sk.UpdateInt64(int64(i)))
+	}
+	var summary map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(sk.String()), &summary))
+	assert.Equal(t, "HLL", summary["cur_mode"])
+	est, err := sk.GetEstimate()
+	assert.NoError(t, err)
+	assert.Equal(t, est, summary["estimate"])
+	assert.Equal(t, float64(sk.GetCompactSerializationBytes()), summary["compact_serialization_bytes"])
+}
+
+func TestSparseThreshold(t *testing.T) {
+	_, err := NewHllSketch(12, TgtHllTypeHll4, WithSparseThreshold(0))
+	assert.Error(t, err)
+	_, err = NewHllSketch(12, TgtHllTypeHll4, WithSparseThreshold(math.NaN()))
+	assert.Error(t, err)
+
+	update := func(sk HllSketch, from, to int) {
+		for i := from; i < to; i++ {
+			assert.NoError(t, sk.UpdateInt64(int64(i)))
+		}
+	}
+
+	// by default the sketch is promoted at about 0.09 * K coupons
+	sk, err := NewHllSketch(12, TgtHllTypeHll4)
+	assert.NoError(t, err)
+	update(sk, 0, 1000)
+	assert.Equal(t, curModeHll, sk.GetCurMode())
+
+	sk, err = NewHllSketch(12, TgtHllTypeHll4, WithSparseThreshold(0.02))
+	assert.NoError(t, err)
+	update(sk, 0, 200)
+	assert.Equal(t, curModeHll, sk.GetCurMode())
+
+	sk, err = NewHllSketch(12, TgtHllTypeHll4, WithSparseThreshold(0.5))
+	assert.NoError(t, err)
+	update(sk, 0, 1000)
+	assert.Equal(t, curModeSet, sk.GetCurMode())
+	est, err := sk.GetEstimate()
+	assert.NoError(t, err)
+	assert.InDelta(t, 1000, est, 1)
+
+	// the threshold is kept by copies
+	skCopy, err := sk.CopyAs(TgtHllTypeHll8)
+	assert.NoError(t, err)
+	update(skCopy, 1000, 2000)
+	assert.Equal(t, curModeSet, skCopy.GetCurMode())
+	update(skCopy, 2000, 2100)
+	assert.Equal(t, curModeHll, skCopy.GetCurMode())
+	est, err = skCopy.GetEstimate()
+	assert.NoError(t, err)
+	assert.InDelta(t, 2100, est, 2100*0.05)
+
+	// sparse sets larger than the default survive serialization
+	for _, compact := range []bool{true, false} {
+		var bytes []byte
+		if compact {
+			bytes, err = sk.ToCompactSlice()
+		} else {
+			bytes, err = sk.ToUpdatableSlice()
+		}
+		assert.NoError(t, err)
+		sk2, err := NewHllSketchFromSlice(bytes, true)
+		assert.NoError(t, err)
+		assert.Equal(t, curModeSet, sk2.GetCurMode())
+		est2, err := sk2.GetEstimate()
+		assert.NoError(t, err)
+		est, err = sk.GetEstimate()
+		assert.NoError(t, err)
+		assert.Equal(t, est, est2)
+		// without the threshold, the next update promotes it
+		update(sk2, 1000, 1010)
+		assert.Equal(t, curModeHll, sk2.GetCurMode())
+		est2, err = sk2.GetEstimate()
+		assert.NoError(t, err)
+		assert.InDelta(t, 1010, est2, 1010*0.05)
+	}
+
+	// the threshold is kept by Reset
+	assert.NoError(t, skCopy.Reset())
+	update(skCopy, 0, 1000)
+	assert.Equal(t, curModeSet, skCopy.GetCurMode())
+
+	sk, err = NewHllSketch(12, TgtHllTypeHll6, ForceAlwaysSparse())
+	assert.NoError(t, err)
+	update(sk, 0, 3000)
+	assert.Equal(t, curModeSet, sk.GetCurMode())
+	// until the set would outgrow K slots
+	update(sk, 3000, 3500)
+	assert.Equal(t, curModeHll, sk.GetCurMode())
+	est, err = sk.GetEstimate()
+	assert.NoError(t, err)
+	assert.InDelta(t, 3500, est, 3500*0.05)
+
+	// no SET mode below lgConfigK 8
+	sk, err = NewHllSketch(7, TgtHllTypeHll8, ForceAlwaysSparse())
+	assert.NoError(t, err)
+	update(sk, 0, 100)
+	assert.Equal(t, curModeHll, sk.GetCurMode())
+}
@@ -0,0 +1,66 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"fmt"
+	"math"
+)
+
+type hllSketchOptions struct {
+	// sparseThreshold is the fraction of K above which a SET sketch is promoted to HLL,
+	// 0 for the default promotion.
+	sparseThreshold float64
+}
+
+// HllSketchOption configures optional behaviour of an HllSketch at construction.
+type HllSketchOption func(*hllSketchOptions) error
+
+// WithSparseThreshold keeps the sketch in the sparse SET mode until it holds more than
+// threshold * K coupons, instead of promoting it to HLL when the coupon hash set reaches K/8 slots,
+// at about 0.09 * K coupons. Sparse sketches are smaller, and exact in their estimates as long as
+// the coupons do not collide, while the stream stays small relative to K.
+//
+// The hash set can grow to K slots at most, so the sketch is promoted at about 0.75 * K coupons
+// whatever the threshold. Sketches with lgConfigK < 8 have no SET mode and ignore the threshold.
+// The threshold is not serialized: a deserialized sketch is promoted as by default.
+func WithSparseThreshold(threshold float64) HllSketchOption {
+	return func(o *hllSketchOptions) error {
+		if !(threshold > 0) {
+			return fmt.Errorf("sparse threshold must be > 0: %f", threshold)
+		}
+		o.sparseThreshold = threshold
+		return nil
+	}
+}
+
+// ForceAlwaysSparse keeps the sketch in the sparse SET mode for as long as the coupon hash set can grow,
+// see WithSparseThreshold. It is meant for testing the sparse code paths.
+func ForceAlwaysSparse() HllSketchOption {
+	return WithSparseThreshold(math.Inf(1))
+}
+
+func newHllSketchOptions(opts []HllSketchOption) (hllSketchOptions, error) {
+	var o hllSketchOptions
+	for _, opt := range opts {
+		if err := opt(&o); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
@@ -0,0 +1,128 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// The differentially private estimates are computed from two statistics of the registers of the
+// sketch, seen in HLL mode whatever the current mode: kxq, the sum of 2^-value over the registers,
+// and the number of registers at zero. Noise is added to these statistics, and the estimate is
+// computed from the noisy statistics with the composite estimator. The HIP estimator is not used:
+// it depends on the order of the updates, not only on the registers.
+//
+// The registers are the maximum over the items of the stream, so adding or removing one item
+// raises or lowers at most one register. That changes kxq by less than 1 and the number of zero
+// registers by at most 1: the L1 sensitivity of the pair is 2 and its L2 sensitivity sqrt(2),
+// whatever the stream. The noise is calibrated to these bounds, so the released estimate is
+// differentially private with respect to the addition or removal of one item, and computing it
+// from the noisy statistics is post-processing.
+//
+// The noise on kxq is independent of the cardinality n, while kxq decreases as about K^2/n, so the
+// relative error of the estimate due to the noise grows as about n / (K^2 * epsilon). The estimates
+// are useful while n is small compared with K^2 * epsilon.
+//
+// The guarantee holds for a fixed hash seed, and only for the released estimate, not the sketch.
+// The noise is drawn from the global source of math/rand, which is not a cryptographic source,
+// and the floating-point arithmetic is not hardened against the known attacks on naive
+// implementations of the Laplace mechanism.
+const (
+	dpL1Sensitivity = 2.0
+	dpL2Sensitivity = math.Sqrt2
+)
+
+// EstimateWithLaplaceMechanism returns an epsilon-differentially private cardinality estimate
+// of the sketch, with Laplace noise of scale 2/epsilon added to the register statistics.
+func EstimateWithLaplaceMechanism(sketch HllSketch, epsilon float64) (float64, error) {
+	if !(epsilon > 0) || math.IsInf(epsilon, 1) {
+		return 0, fmt.Errorf("epsilon must be > 0 and finite: %f", epsilon)
+	}
+	scale := dpL1Sensitivity / epsilon
+	return privateEstimate(sketch, func() float64 { return scale * laplaceNoise() })
+}
+
+// EstimateWithGaussianMechanism returns an (epsilon, delta)-differentially private cardinality
+// estimate of the sketch, with Gaussian noise added to the register statistics. The standard
+// deviation is sqrt(2) * sqrt(2 * ln(1.25 / delta)) / epsilon, the calibration of the classic
+// Gaussian mechanism, which only holds for epsilon < 1.
+func EstimateWithGaussianMechanism(sketch HllSketch, epsilon float64, delta float64) (float64, error) {
+	if !(epsilon > 0 && epsilon < 1) {
+		return 0, fmt.Errorf("epsilon must be in (0, 1): %f", epsilon)
+	}
+	if !(delta > 0 && delta < 1) {
+		return 0, fmt.Errorf("delta must be in (0, 1): %f", delta)
+	}
+	sigma := dpL2Sensitivity * math.Sqrt(2*math.Log(1.25/delta)) / epsilon
+	return privateEstimate(sketch, func() float64 { return sigma * rand.NormFloat64() })
+}
+
+// privateEstimate computes the composite estimate from the register statistics of the sketch,
+// each with an independent draw of noise.
+func privateEstimate(sketch HllSketch, noise func() float64) (float64, error) {
+	kxq, numZeros, err := registerStatistics(sketch)
+	if err != nil {
+		return 0, err
+	}
+	lgConfigK := sketch.GetLgConfigK()
+	configK := float64(uint64(1) << lgConfigK)
+	// clamp the noisy statistics to the range of the true ones
+	minKxq := configK * math.Ldexp(1, -(64-lgConfigK+1))
+	noisyKxq := min(max(kxq+noise(), minKxq), configK)
+	noisyZeros := min(max(math.Round(float64(numZeros)+noise()), 0), configK)
+	return compositeEstimate(lgConfigK, noisyKxq, 0, int(noisyZeros))
+}
+
+// registerStatistics returns the sum of 2^-value and the number of zero values over the registers
+// of the sketch, with the registers of a coupon list or set built from its coupons.
+func registerStatistics(sketch HllSketch) (float64, int, error) {
+	if sketch == nil {
+		return 0, 0, fmt.Errorf("sketch must not be nil")
+	}
+	configK := 1 << sketch.GetLgConfigK()
+	registers := make([]int, configK)
+	itr := sketch.iterator()
+	for itr.nextAll() {
+		value, err := itr.getValue()
+		if err != nil {
+			return 0, 0, err
+		}
+		if value == empty {
+			continue
+		}
+		slot := itr.getSlot()
+		registers[slot] = max(registers[slot], value)
+	}
+	kxq := 0.0
+	numZeros := 0
+	for _, value := range registers {
+		kxq += math.Ldexp(1, -value)
+		if value == 0 {
+			numZeros++
+		}
+	}
+	return kxq, numZeros, nil
+}
+
+// laplaceNoise draws from the Laplace distribution centered on 0 with scale 1,
+// as the difference of two exponential variables.
+func laplaceNoise() float64 {
+	return rand.ExpFloat64() - rand.ExpFloat64()
+}
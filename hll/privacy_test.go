@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterStatisticsSensitivity(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		sk, err := NewHllSketch(8, tgtHllType)
+		assert.NoError(t, err)
+		kxq, numZeros, err := registerStatistics(sk)
+		assert.NoError(t, err)
+		assert.Equal(t, 256.0, kxq)
+		assert.Equal(t, 256, numZeros)
+		// one more item moves the statistics by at most the sensitivity, through all the modes
+		for i := 0; i < 20000; i++ {
+			assert.NoError(t, sk.UpdateInt64(int64(i)))
+			kxq2, numZeros2, err := registerStatistics(sk)
+			assert.NoError(t, err)
+			assert.Less(t, kxq-kxq2, 1.0)
+			assert.GreaterOrEqual(t, kxq-kxq2, 0.0)
+			assert.LessOrEqual(t, numZeros-numZeros2, 1)
+			assert.GreaterOrEqual(t, numZeros-numZeros2, 0)
+			kxq, numZeros = kxq2, numZeros2
+		}
+		assert.Equal(t, curModeHll, sk.GetCurMode())
+		// in HLL mode the statistics are the ones the sketch maintains
+		arr := sk.(*hllSketchState).sketch.(hllArray)
+		assert.InDelta(t, arr.getKxQ0()+arr.getKxQ1(), kxq, 1e-9)
+		if arr.getCurMin() == 0 {
+			assert.Equal(t, arr.getNumAtCurMin(), numZeros)
+		} else {
+			assert.Equal(t, 0, numZeros)
+		}
+	}
+}
+
+func TestDifferentialPrivacyMechanisms(t *testing.T) {
+	sk, err := NewHllSketch(12, TgtHllTypeHll8)
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, sk.UpdateInt64(int64(i)))
+	}
+	est, err := sk.GetCompositeEstimate()
+	assert.NoError(t, err)
+
+	// the noise on the statistics is small for n much smaller than K^2
+	const trials = 2000
+	var sumL, sumG float64
+	for i := 0; i < trials; i++ {
+		noisy, err := EstimateWithLaplaceMechanism(sk, 0.5)
+		assert.NoError(t, err)
+		assert.InDelta(t, est, noisy, est*0.1)
+		sumL += noisy
+		noisy, err = EstimateWithGaussianMechanism(sk, 0.5, 1e-5)
+		assert.NoError(t, err)
+		assert.InDelta(t, est, noisy, est*0.1)
+		sumG += noisy
+	}
+	assert.InDelta(t, est, sumL/trials, est*0.002)
+	assert.InDelta(t, est, sumG/trials, est*0.002)
+
+	empty, err := NewHllSketch(12, TgtHllTypeHll8)
+	assert.NoError(t, err)
+	noisy, err := EstimateWithLaplaceMechanism(empty, 0.5)
+	assert.NoError(t, err)
+	assert.InDelta(t, 0, noisy, 60)
+
+	for _, eps := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		_, err = EstimateWithLaplaceMechanism(sk, eps)
+		assert.Error(t, err, "epsilon %f", eps)
+	}
+	for _, eps := range []float64{0, -1, 1, 2, math.NaN()} {
+		_, err = EstimateWithGaussianMechanism(sk, eps, 1e-5)
+		assert.Error(t, err, "epsilon %f", eps)
+	}
+	for _, d := range []float64{0, 1, -0.1, math.NaN()} {
+		_, err = EstimateWithGaussianMechanism(sk, 0.5, d)
+		assert.Error(t, err, "delta %f", d)
+	}
+	_, err = EstimateWithLaplaceMechanism(nil, 0.5)
+	assert.Error(t, err)
+}
@@ -0,0 +1,84 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"fmt"
+)
+
+// SubtractEstimate estimates the number of distinct items in a that are not in b, |A \ B|.
+//
+// There is no exact set difference of HLL sketches, so the estimate relies on inclusion-exclusion,
+// |A| - |A ∩ B| = |A ∪ B| - |B|, with both terms taken from unions folded to the same lgK so that
+// they share their registers. The lowerBound is the lower bound of |A ∪ B| minus the upper bound
+// of |B| at the given number of standard deviations, and is clamped to 0.
+//
+// When the estimate is negative the error margins of the two terms overlap, and the difference
+// cannot be told apart from 0: the estimate is then clamped to 0 and negative is true.
+func SubtractEstimate(a, b HllSketch, numStdDev int) (lowerBound, estimate float64, negative bool, err error) {
+	if a == nil || b == nil {
+		return 0, 0, false, fmt.Errorf("sketches must not be nil")
+	}
+	lgMaxK := max(a.GetLgConfigK(), b.GetLgConfigK())
+	unionAB, err := NewUnion(lgMaxK)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if err = unionAB.UpdateSketch(a); err != nil {
+		return 0, 0, false, err
+	}
+	if err = unionAB.UpdateSketch(b); err != nil {
+		return 0, 0, false, err
+	}
+	unionB, err := NewUnion(lgMaxK)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if err = unionB.UpdateSketch(b); err != nil {
+		return 0, 0, false, err
+	}
+	// a may have folded unionAB to a smaller lgK than b's
+	skB, err := unionB.GetResultWithLgK(unionAB.GetLgConfigK(), TgtHllTypeHll8)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	estAB, err := unionAB.GetCompositeEstimate()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	estB, err := skB.GetCompositeEstimate()
+	if err != nil {
+		return 0, 0, false, err
+	}
+	lbAB, err := unionAB.GetLowerBound(numStdDev)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	ubB, err := skB.GetUpperBound(numStdDev)
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	estimate = estAB - estB
+	if estimate < 0 {
+		estimate = 0
+		negative = true
+	}
+	return max(0, lbAB-ubB), estimate, negative, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtractEstimate(t *testing.T) {
+	build := func(lgK int, from, to int) HllSketch {
+		sk, err := NewHllSketch(lgK, TgtHllTypeHll4)
+		assert.NoError(t, err)
+		for i := from; i < to; i++ {
+			assert.NoError(t, sk.UpdateInt64(int64(i)))
+		}
+		return sk
+	}
+
+	for _, n := range []int{100, 10000, 1000000} {
+		for _, lgKs := range [][2]int{{12, 12}, {10, 12}, {12, 10}} {
+			// A and B overlap on half of A
+			a := build(lgKs[0], 0, n)
+			b := build(lgKs[1], n/2, n+n/2)
+			lb, est, negative, err := SubtractEstimate(a, b, 2)
+			assert.NoError(t, err)
+			assert.False(t, negative)
+			assert.InDelta(t, n/2, est, float64(n)*0.1, "n=%d lgKs=%v", n, lgKs)
+			assert.LessOrEqual(t, lb, est)
+			assert.GreaterOrEqual(t, lb, 0.0)
+
+			// disjoint sets
+			_, est, _, err = SubtractEstimate(a, build(lgKs[1], n, 2*n), 2)
+			assert.NoError(t, err)
+			assert.InDelta(t, n, est, float64(n)*0.1, "n=%d lgKs=%v", n, lgKs)
+		}
+	}
+
+	// A is a subset of B: the difference is 0, or lost in the error of the estimates
+	a := build(12, 0, 100000)
+	b := build(12, 0, 200000)
+	lb, est, negative, err := SubtractEstimate(a, b, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, lb)
+	assert.InDelta(t, 0, est, 200000*0.01)
+	if negative {
+		assert.Equal(t, 0.0, est)
+	}
+
+	// nothing is left when subtracting A from itself
+	_, est, negative, err = SubtractEstimate(a, a, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, est)
+	assert.False(t, negative)
+
+	_, _, _, err = SubtractEstimate(a, nil, 2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/datasketches-go/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVectors replays the HLL test vectors written by cmd/genvectors.
+func TestVectors(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(internal.VectorsPath, "hll_*.json"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			checkTestVectors(t, file)
+		})
+	}
+}
+
+// checkTestVectors replays the updates of each vector of the file and fails if the compact
+// serialization of the resulting sketch differs from the expected one.
+func checkTestVectors(t *testing.T, vectorFile string) {
+	file, err := internal.ReadTestVectorFile(vectorFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "hll", file.Family)
+	tgtHllTypes := map[int]TgtHllType{4: TgtHllTypeHll4, 6: TgtHllTypeHll6, 8: TgtHllTypeHll8}
+	for _, v := range file.Vectors {
+		tgtHllType, ok := tgtHllTypes[v.Params["tgtHllType"]]
+		if !assert.True(t, ok, "%s: tgtHllType %d", v.Case, v.Params["tgtHllType"]) {
+			continue
+		}
+		sk, err := NewHllSketch(v.Params["lgK"], tgtHllType)
+		if !assert.NoError(t, err, v.Case) {
+			continue
+		}
+		for _, u := range v.Updates {
+			for i := u.From; i < u.From+u.Count; i++ {
+				assert.NoError(t, sk.UpdateInt64(i))
+			}
+		}
+		bytes, err := sk.ToCompactSlice()
+		assert.NoError(t, err, v.Case)
+		assert.Equal(t, v.Serialized, bytes, "%s: serialized bytes", v.Case)
+
+		expected, err := NewHllSketchFromSlice(v.Serialized, true)
+		if !assert.NoError(t, err, v.Case) {
+			continue
+		}
+		est1, err := expected.GetEstimate()
+		assert.NoError(t, err)
+		est2, err := sk.GetEstimate()
+		assert.NoError(t, err)
+		assert.Equal(t, est1, est2, "%s: estimate", v.Case)
+	}
+}
@@ -43,13 +43,23 @@ type Union interface {
 	GetTgtHllType() TgtHllType
 	GetCurMode() curMode
 
+	GetCompactSerializationBytes() int
 	GetUpdatableSerializationBytes() int
 	ToCompactSlice() ([]byte, error)
 	ToUpdatableSlice() ([]byte, error)
 
 	UpdateSketch(sketch HllSketch) error
+
+	// Merge folds the state of another union into this one, as if this union had been
+	// presented with all the sketches and items presented to the other one.
+	Merge(other Union) error
+
 	GetResult(tgtHllType TgtHllType) (HllSketch, error)
 
+	// GetResultWithLgK returns the result of this union operator folded to the given lgK,
+	// which must not be greater than the current lgK of the union, with the given TgtHllType.
+	GetResultWithLgK(lgK int, tgtHllType TgtHllType) (HllSketch, error)
+
 	couponUpdate(coupon int) (hllSketchStateI, error)
 	iterator() pairIterator
 }
@@ -68,10 +78,18 @@ func (u *unionImpl) iterator() pairIterator {
 //}
 
 func (u *unionImpl) GetUpperBound(numStdDev int) (float64, error) {
+	err := checkRebuildCurMinNumKxQ(u.gadget)
+	if err != nil {
+		return 0, err
+	}
 	return u.gadget.GetUpperBound(numStdDev)
 }
 
 func (u *unionImpl) GetLowerBound(numStdDev int) (float64, error) {
+	err := checkRebuildCurMinNumKxQ(u.gadget)
+	if err != nil {
+		return 0, err
+	}
 	return u.gadget.GetLowerBound(numStdDev)
 }
 
@@ -92,6 +110,28 @@ func (u *unionImpl) GetResult(tgtHllType TgtHllType) (HllSketch, error) {
 	return u.gadget.CopyAs(tgtHllType)
 }
 
+func (u *unionImpl) GetResultWithLgK(lgK int, tgtHllType TgtHllType) (HllSketch, error) {
+	gdgtLgK := u.gadget.GetLgConfigK()
+	if lgK > gdgtLgK {
+		return nil, fmt.Errorf("lgK must not be greater than the lgK of the union %d: %d", gdgtLgK, lgK)
+	}
+	if lgK == gdgtLgK {
+		return u.GetResult(tgtHllType)
+	}
+	// a union with a smaller lgMaxK folds the gadget
+	folder, err := NewUnion(lgK)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkRebuildCurMinNumKxQ(u.gadget); err != nil {
+		return nil, err
+	}
+	if err = folder.UpdateSketch(u.gadget); err != nil {
+		return nil, err
+	}
+	return folder.GetResult(tgtHllType)
+}
+
 func NewUnionWithDefault() (Union, error) {
 	return NewUnion(defaultLgK)
 }
@@ -108,15 +148,11 @@ func NewUnion(lgMaxK int) (Union, error) {
 }
 
 func NewUnionFromSlice(byteArray []byte) (Union, error) {
-	lgK, err := checkLgK(extractLgK(byteArray))
+	sk, err := NewHllSketchFromSlice(byteArray, false)
 	if err != nil {
 		return nil, err
 	}
-	sk, e := NewHllSketchFromSlice(byteArray, false)
-	if e != nil {
-		return nil, e
-	}
-	union, err := NewUnion(lgK)
+	union, err := NewUnion(sk.GetLgConfigK())
 	if err != nil {
 		return nil, err
 	}
@@ -125,10 +161,18 @@ func NewUnionFromSlice(byteArray []byte) (Union, error) {
 }
 
 func (u *unionImpl) GetCompositeEstimate() (float64, error) {
+	err := checkRebuildCurMinNumKxQ(u.gadget)
+	if err != nil {
+		return 0, err
+	}
 	return u.gadget.GetCompositeEstimate()
 }
 
 func (u *unionImpl) GetEstimate() (float64, error) {
+	err := checkRebuildCurMinNumKxQ(u.gadget)
+	if err != nil {
+		return 0, err
+	}
 	return u.gadget.GetEstimate()
 }
 
@@ -157,6 +201,23 @@ func (u *unionImpl) UpdateSketch(sketch HllSketch) error {
 	return nil
 }
 
+func (u *unionImpl) Merge(other Union) error {
+	if other == nil {
+		return fmt.Errorf("other union must not be nil")
+	}
+	o, ok := other.(*unionImpl)
+	if !ok {
+		return fmt.Errorf("unsupported union implementation: %T", other)
+	}
+	if o == u {
+		return nil
+	}
+	if err := checkRebuildCurMinNumKxQ(o.gadget); err != nil {
+		return err
+	}
+	return u.UpdateSketch(o.gadget)
+}
+
 func (u *unionImpl) GetLgConfigK() int {
 	return u.gadget.GetLgConfigK()
 }
@@ -189,6 +250,10 @@ func (u *unionImpl) ToUpdatableSlice() ([]byte, error) {
 	return u.gadget.ToUpdatableSlice()
 }
 
+func (u *unionImpl) GetCompactSerializationBytes() int {
+	return u.gadget.GetCompactSerializationBytes()
+}
+
 func (u *unionImpl) GetUpdatableSerializationBytes() int {
 	return u.gadget.GetUpdatableSerializationBytes()
 }
@@ -242,7 +307,7 @@ func (u *unionImpl) unionImpl(source HllSketch) (hllSketchStateI, error) {
 	}
 
 	if srcLgK < gdgtLgK {
-		bit3 = 0
+		bit3 = 8
 	}
 
 	if srcLgK > u.lgMaxK {
@@ -270,7 +335,12 @@ func (u *unionImpl) unionImpl(source HllSketch) (hllSketchStateI, error) {
 		// case 16: src >  max, src >= gdt, gdtList, gdtHeap
 		// case 18: src >  max, src >= gdt, gdtSet,  gdtHeap
 		{ //Action: downsample src to MaxLgK, reverse merge w/autofold, ooof=src
-			return nil, fmt.Errorf("not implemented cas 16,18")
+			srcHll8, err := downsample(source, u.lgMaxK)
+			if err != nil {
+				return nil, err
+			}
+			err = gadgetC.mergeTo(srcHll8)
+			return srcHll8.(*hllSketchState).sketch, err
 		}
 	case 4, 20:
 		// case 4: src <= max, src >= gdt, gdtHLL, gdtHeap
@@ -286,7 +356,16 @@ func (u *unionImpl) unionImpl(source HllSketch) (hllSketchStateI, error) {
 		}
 	case 12: //src <= max, src <  gdt, gdtHLL, gdtHeap
 		{ //Action: downsample gdt to srcLgK, forward HLL merge w/autofold, ooof=True
-			return nil, fmt.Errorf("not implemented case 12")
+			gdtHll8, err := downsample(u.gadget, srcLgK)
+			if err != nil {
+				return nil, err
+			}
+			err = mergeHlltoHLLmode(source, gdtHll8, srcLgK, srcLgK)
+			if err != nil {
+				return nil, err
+			}
+			gdtHll8.(*hllSketchState).sketch.putOutOfOrder(true)
+			return gdtHll8.(*hllSketchState).sketch, nil
 		}
 	case 6, 14:
 		// case 6: src <= max, src >= gdt, gdtEmpty, gdtHeap
@@ -300,13 +379,45 @@ func (u *unionImpl) unionImpl(source HllSketch) (hllSketchStateI, error) {
 		}
 	case 22: //src >  max, src >= gdt, gdtEmpty, gdtHeap
 		{ //Action: downsample src to lgMaxK, replace gdt, ooof=src
-			return nil, fmt.Errorf("not implemented")
+			srcHll8, err := downsample(source, u.lgMaxK)
+			if err != nil {
+				return nil, err
+			}
+			return srcHll8.(*hllSketchState).sketch, nil
 		}
 	default:
 		return nil, fmt.Errorf("impossible")
 	}
 }
 
+// downsample folds the given sketch, which must be in HLL mode, into a new HLL_8 sketch with the given lgK.
+func downsample(candidate HllSketch, tgtLgK int) (HllSketch, error) {
+	candArr := candidate.(*hllSketchState).sketch.(hllArray)
+	tgtHllArr, err := newHllArray(tgtLgK, TgtHllTypeHll8)
+	if err != nil {
+		return nil, err
+	}
+	candItr := candArr.iterator()
+	for candItr.nextAll() {
+		p, err := candItr.getPair()
+		if err != nil {
+			return nil, err
+		}
+		if p>>keyBits26 == empty {
+			continue
+		}
+		//rebuilds KxQ, etc.
+		if _, err = tgtHllArr.couponUpdate(p); err != nil {
+			return nil, err
+		}
+	}
+	//both of these are required for isomorphism
+	tgtHllArr.putHipAccum(candArr.getHipAccum())
+	tgtHllArr.putOutOfOrder(candArr.isOutOfOrder())
+	tgtHllArr.putRebuildCurMinNumKxQFlag(false)
+	return newHllSketchState(tgtHllArr), nil
+}
+
 func checkRebuildCurMinNumKxQ(sketch HllSketch) error {
 	sketchImpl := sketch.(*hllSketchState).sketch
 	curMode := sketch.GetCurMode()
@@ -446,7 +557,31 @@ func mergeHlltoHLLmode(src HllSketch, tgt HllSketch, srcLgK int, tgtLgK int) err
 				}
 			}
 		}
-		// TODO continue implementing
+	case 4: //HLL_8, srcLgK>tgtLgK, src=heap, tgt=heap
+		{
+			tgtLgKMask := (1 << tgtLgK) - 1
+			srcArr := src.(*hllSketchState).sketch.(*hll8ArrayImpl).hllByteArr
+			tgtArr := tgt.(*hllSketchState).sketch.(*hll8ArrayImpl).hllByteArr
+			for i, srcV := range srcArr {
+				j := i & tgtLgKMask
+				if srcV > tgtArr[j] {
+					tgtArr[j] = srcV
+				}
+			}
+		}
+	case 12, 13: //!HLL_8, srcLgK>tgtLgK, src=heap, tgt=heap/mem
+		{
+			tgtLgKMask := (1 << tgtLgK) - 1
+			tgtAbsHllArr := tgt.(*hllSketchState).sketch.(*hll8ArrayImpl)
+			srcItr := src.iterator()
+			for srcItr.nextAll() {
+				p, err := srcItr.getPair()
+				if err != nil {
+					return err
+				}
+				tgtAbsHllArr.updateSlotNoKxQ(p&tgtLgKMask, p>>keyBits26)
+			}
+		}
 	default:
 		return fmt.Errorf("not implemented")
 	}
@@ -335,3 +335,200 @@ func TestCheckUnionDeserializeRebuildAfterMerge(t *testing.T) {
 	assert.False(t, rebuild)
 
 }
+
+func TestUnionDifferentLgK(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		n := 100000
+		h12, err := NewHllSketch(12, tgtHllType)
+		assert.NoError(t, err)
+		h8, err := NewHllSketch(8, tgtHllType)
+		assert.NoError(t, err)
+		control, err := NewHllSketch(8, TgtHllTypeHll8)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, h12.UpdateInt64(int64(i)))
+			assert.NoError(t, control.UpdateInt64(int64(i)))
+		}
+		for i := n / 2; i < n+n/2; i++ {
+			assert.NoError(t, h8.UpdateInt64(int64(i)))
+			assert.NoError(t, control.UpdateInt64(int64(i)))
+		}
+		controlEst, err := control.GetCompositeEstimate()
+		assert.NoError(t, err)
+
+		// src with a smaller lgK than the HLL gadget, and the other way around
+		for _, order := range [][]HllSketch{{h12, h8}, {h8, h12}} {
+			union, err := NewUnion(12)
+			assert.NoError(t, err)
+			assert.NoError(t, union.UpdateSketch(order[0]))
+			assert.NoError(t, union.UpdateSketch(order[1]))
+			result, err := union.GetResultWithLgK(8, TgtHllTypeHll8)
+			assert.NoError(t, err)
+			assert.Equal(t, 8, result.GetLgConfigK())
+			est, err := result.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, controlEst, est)
+		}
+
+		// src with a larger lgK than lgMaxK
+		union, err := NewUnion(8)
+		assert.NoError(t, err)
+		assert.NoError(t, union.UpdateSketch(h12))
+		assert.NoError(t, union.UpdateSketch(h8))
+		result, err := union.GetResult(TgtHllTypeHll8)
+		assert.NoError(t, err)
+		assert.Equal(t, 8, result.GetLgConfigK())
+		est, err := result.GetEstimate()
+		assert.NoError(t, err)
+		assert.Equal(t, controlEst, est)
+	}
+}
+
+func TestUnionGetResultWithLgK(t *testing.T) {
+	union, err := NewUnion(12)
+	assert.NoError(t, err)
+	control, err := NewHllSketch(10, TgtHllTypeHll8)
+	assert.NoError(t, err)
+	// LIST, SET and HLL modes of the gadget
+	for _, n := range []int{5, 100, 10000} {
+		for i := 0; i < n; i++ {
+			assert.NoError(t, union.UpdateInt64(int64(i)))
+			assert.NoError(t, control.UpdateInt64(int64(i)))
+		}
+		result, err := union.GetResultWithLgK(10, TgtHllTypeHll4)
+		assert.NoError(t, err)
+		assert.Equal(t, 10, result.GetLgConfigK())
+		assert.Equal(t, TgtHllTypeHll4, result.GetTgtHllType())
+		est, err := result.GetEstimate()
+		assert.NoError(t, err)
+		controlEst, err := control.GetEstimate()
+		assert.NoError(t, err)
+		assert.InDelta(t, controlEst, est, controlEst*0.05)
+	}
+	_, err = union.GetResultWithLgK(13, TgtHllTypeHll8)
+	assert.Error(t, err)
+	result, err := union.GetResultWithLgK(12, TgtHllTypeHll8)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, result.GetLgConfigK())
+}
+
+func TestUnionEstimateAfterHllMerge(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		n := 10000
+		h1, err := NewHllSketch(12, tgtHllType)
+		assert.NoError(t, err)
+		h2, err := NewHllSketch(12, tgtHllType)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, h1.UpdateInt64(int64(i)))
+			assert.NoError(t, h2.UpdateInt64(int64(n+i)))
+		}
+		union, err := NewUnion(12)
+		assert.NoError(t, err)
+		assert.NoError(t, union.UpdateSketch(h1))
+		assert.NoError(t, union.UpdateSketch(h2))
+
+		// the union must not read stale KxQ registers after merging HLL arrays
+		est, err := union.GetEstimate()
+		assert.NoError(t, err)
+		assert.InDelta(t, 2*n, est, float64(2*n)*0.05)
+		result, err := union.GetResult(TgtHllTypeHll8)
+		assert.NoError(t, err)
+		resultEst, err := result.GetEstimate()
+		assert.NoError(t, err)
+		assert.Equal(t, resultEst, est)
+		lb, err := union.GetLowerBound(2)
+		assert.NoError(t, err)
+		ub, err := union.GetUpperBound(2)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, lb, est)
+		assert.GreaterOrEqual(t, ub, est)
+	}
+}
+
+func TestUnionEstimatesMatchResultAfterEachMerge(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		union, err := NewUnion(12)
+		assert.NoError(t, err)
+		// overlapping streams in sketches of several lgK, all in HLL mode
+		for j, lgK := range []int{12, 11, 12, 10} {
+			sk, err := NewHllSketch(lgK, tgtHllType)
+			assert.NoError(t, err)
+			for i := 0; i < 20000; i++ {
+				assert.NoError(t, sk.UpdateInt64(int64(j*10000+i)))
+			}
+			assert.NoError(t, union.UpdateSketch(sk))
+
+			// the estimates are read right after the merge, before anything rebuilds the KxQ registers
+			est, err := union.GetEstimate()
+			assert.NoError(t, err)
+			compositeEst, err := union.GetCompositeEstimate()
+			assert.NoError(t, err)
+			lb, err := union.GetLowerBound(1)
+			assert.NoError(t, err)
+			ub, err := union.GetUpperBound(1)
+			assert.NoError(t, err)
+
+			result, err := union.GetResult(TgtHllTypeHll8)
+			assert.NoError(t, err)
+			expected, err := result.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, est)
+			expected, err = result.GetCompositeEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, compositeEst)
+			expected, err = result.GetLowerBound(1)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, lb)
+			expected, err = result.GetUpperBound(1)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, ub)
+		}
+	}
+}
+
+func TestUnionMerge(t *testing.T) {
+	for _, n := range []int{10, 1000, 100000} {
+		for _, lgK := range []int{12, 10} {
+			// two partitions of the input, one of them checkpointed and restored
+			single, err := NewUnion(12)
+			assert.NoError(t, err)
+			u1, err := NewUnion(12)
+			assert.NoError(t, err)
+			u2, err := NewUnion(lgK)
+			assert.NoError(t, err)
+			for p, u := range []Union{u1, u2} {
+				sk, err := NewHllSketch(lgK, TgtHllTypeHll4)
+				assert.NoError(t, err)
+				for i := p * n / 2; i < (p+1)*n/2+n/4; i++ {
+					assert.NoError(t, sk.UpdateInt64(int64(i)))
+				}
+				assert.NoError(t, u.UpdateSketch(sk))
+				assert.NoError(t, single.UpdateSketch(sk))
+			}
+			bytes, err := u2.ToUpdatableSlice()
+			assert.NoError(t, err)
+			restored, err := NewUnionFromSlice(bytes)
+			assert.NoError(t, err)
+
+			assert.NoError(t, u1.Merge(restored))
+			assert.Equal(t, single.GetLgConfigK(), u1.GetLgConfigK(), "n=%d lgK=%d", n, lgK)
+			assert.Equal(t, single.GetCurMode(), u1.GetCurMode(), "n=%d lgK=%d", n, lgK)
+			est1, err := single.GetEstimate()
+			assert.NoError(t, err)
+			est2, err := u1.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, est1, est2, "n=%d lgK=%d", n, lgK)
+
+			// merging a union into itself is a no-op
+			assert.NoError(t, u1.Merge(u1))
+			est3, err := u1.GetEstimate()
+			assert.NoError(t, err)
+			assert.Equal(t, est2, est3)
+		}
+	}
+
+	union, err := NewUnion(12)
+	assert.NoError(t, err)
+	assert.Error(t, union.Merge(nil))
+}
@@ -18,6 +18,7 @@
 package hll
 
 import (
+	"errors"
 	"fmt"
 	"math"
 
@@ -95,6 +96,30 @@ const (
 	TgtHllTypeDefault = TgtHllTypeHll4
 )
 
+func (t TgtHllType) String() string {
+	switch t {
+	case TgtHllTypeHll4:
+		return "HLL_4"
+	case TgtHllTypeHll6:
+		return "HLL_6"
+	case TgtHllTypeHll8:
+		return "HLL_8"
+	}
+	return fmt.Sprintf("TgtHllType(%d)", int(t))
+}
+
+func (c curMode) String() string {
+	switch c {
+	case curModeList:
+		return "LIST"
+	case curModeSet:
+		return "SET"
+	case curModeHll:
+		return "HLL"
+	}
+	return fmt.Sprintf("curMode(%d)", int(c))
+}
+
 var (
 	// lgAuxArrInts is the Log2 table sizes for exceptions based on lgK from 0 to 26.
 	//However, only lgK from 4 to 21 are used.
@@ -105,12 +130,16 @@ var (
 	}
 )
 
+// ErrInvalidLgK is returned, possibly wrapped, when a log K is not between 4 and 21 inclusively,
+// in particular by the deserializers when the lgK of the preamble is corrupted.
+var ErrInvalidLgK = errors.New("log K must be between 4 and 21, inclusive")
+
 // CheckLgK checks the given lgK and returns it if it is valid and return an error otherwise.
 func checkLgK(lgK int) (int, error) {
 	if lgK >= minLogK && lgK <= maxLogK {
 		return lgK, nil
 	}
-	return 0, fmt.Errorf("log K must be between 4 and 21, inclusive: %d", lgK)
+	return 0, fmt.Errorf("%w: %d", ErrInvalidLgK, lgK)
 }
 
 // pair returns a value where the lower 26 bits are the slotNo and the upper 6 bits are the value.
@@ -177,6 +206,18 @@ func checkPreamble(preamble []byte) (curMode, error) {
 		return 0, fmt.Errorf("possible Corruption: Invalid Preamble Ints: %d", preInts)
 	}
 
+	if curMode != curModeList && curMode != curModeSet && curMode != curModeHll {
+		return 0, fmt.Errorf("possible Corruption: Invalid Mode: %d", curMode)
+	}
+
+	if tgtHllType := extractTgtHllType(preamble); tgtHllType != TgtHllTypeHll4 && tgtHllType != TgtHllTypeHll6 && tgtHllType != TgtHllTypeHll8 {
+		return 0, fmt.Errorf("possible Corruption: Invalid TgtHllType: %d", tgtHllType)
+	}
+
+	if _, err := checkLgK(extractLgK(preamble)); err != nil {
+		return 0, err
+	}
+
 	return curMode, nil
 }
 
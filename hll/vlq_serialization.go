@@ -0,0 +1,213 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// The VLQ format stores only the non-zero registers (or coupons) of a sketch:
+//
+//	byte 0    : VLQ serialization version
+//	byte 1    : lgConfigK
+//	byte 2    : TgtHllType
+//	byte 3    : curMode
+//	byte 4    : flags
+//	[8 bytes] : HIP accumulator, little-endian float64, HLL mode only
+//	uvarint   : number of entries
+//	uvarints  : the keys in ascending order, each as the delta from the previous key
+//	nibbles   : the values, two per byte, low nibble first, vlqNibbleEscape if the value does not fit
+//	bytes     : one byte for each escaped value, in order
+//
+// The keys are the 26-bit coupon addresses in LIST and SET modes, and the slot numbers in HLL mode.
+const (
+	vlqSerVer       = 1
+	vlqHeaderBytes  = 5
+	vlqOooFlagMask  = 1
+	vlqNibbleEscape = 15
+)
+
+// ToVlqSlice serializes the sketch keeping only its non-zero registers, with the register
+// indices encoded as variable-length quantities and the register values as 4-bit nibbles.
+// It is smaller than ToCompactSlice while most of the registers are zero, in LIST and SET modes
+// and in early HLL mode, and larger once the HLL array fills up.
+// The result can only be deserialized with NewHllSketchFromVlqSlice.
+func (h *hllSketchState) ToVlqSlice() ([]byte, error) {
+	mode := h.sketch.GetCurMode()
+	// each entry is packed as key<<6 | value, so sorting the entries sorts the keys
+	entries := make([]int, 0)
+	itr := h.sketch.iterator()
+	for itr.nextAll() {
+		value, err := itr.getValue()
+		if err != nil {
+			return nil, err
+		}
+		if value == empty {
+			continue
+		}
+		entries = append(entries, itr.getKey()<<6|value)
+	}
+	if mode != curModeHll {
+		// coupons are stored in hash order
+		slices.Sort(entries)
+	}
+
+	out := make([]byte, vlqHeaderBytes, vlqHeaderBytes+8+len(entries)*3)
+	out[0] = vlqSerVer
+	out[1] = byte(h.sketch.GetLgConfigK())
+	out[2] = byte(h.sketch.GetTgtHllType())
+	out[3] = byte(mode)
+	if h.sketch.isOutOfOrder() {
+		out[4] |= vlqOooFlagMask
+	}
+	if mode == curModeHll {
+		out = binary.LittleEndian.AppendUint64(out, math.Float64bits(h.sketch.(hllArray).getHipAccum()))
+	}
+	out = binary.AppendUvarint(out, uint64(len(entries)))
+	prev := 0
+	for _, entry := range entries {
+		key := entry >> 6
+		out = binary.AppendUvarint(out, uint64(key-prev))
+		prev = key
+	}
+	nibbles := make([]byte, (len(entries)+1)/2)
+	exceptions := make([]byte, 0)
+	for i, entry := range entries {
+		value := entry & 0x3F
+		nibble := value
+		if value >= vlqNibbleEscape {
+			nibble = vlqNibbleEscape
+			exceptions = append(exceptions, byte(value))
+		}
+		nibbles[i/2] |= byte(nibble) << ((i & 1) * 4)
+	}
+	out = append(out, nibbles...)
+	return append(out, exceptions...), nil
+}
+
+// NewHllSketchFromVlqSlice deserializes a sketch from the bytes returned by ToVlqSlice.
+func NewHllSketchFromVlqSlice(data []byte) (HllSketch, error) {
+	if len(data) < vlqHeaderBytes {
+		return nil, fmt.Errorf("possible corruption: VLQ slice too small: %d", len(data))
+	}
+	if data[0] != vlqSerVer {
+		return nil, fmt.Errorf("possible corruption: invalid VLQ serialization version: %d", data[0])
+	}
+	lgK, err := checkLgK(int(data[1]))
+	if err != nil {
+		return nil, err
+	}
+	tgtHllType := TgtHllType(data[2])
+	if tgtHllType != TgtHllTypeHll4 && tgtHllType != TgtHllTypeHll6 && tgtHllType != TgtHllTypeHll8 {
+		return nil, fmt.Errorf("possible corruption: invalid TgtHllType: %d", data[2])
+	}
+	mode := curMode(data[3])
+	if mode != curModeList && mode != curModeSet && mode != curModeHll {
+		return nil, fmt.Errorf("possible corruption: invalid curMode: %d", data[3])
+	}
+	oooFlag := data[4]&vlqOooFlagMask != 0
+	offset := vlqHeaderBytes
+
+	hipAccum := 0.0
+	maxKey := uint64(1<<keyBits26) - 1
+	if mode == curModeHll {
+		if len(data) < offset+8 {
+			return nil, fmt.Errorf("possible corruption: VLQ slice too small: %d", len(data))
+		}
+		hipAccum = math.Float64frombits(binary.LittleEndian.Uint64(data[offset:]))
+		offset += 8
+		maxKey = uint64(1<<lgK) - 1
+	}
+
+	count, n := binary.Uvarint(data[offset:])
+	// every key takes at least one byte
+	if n <= 0 || count > maxKey+1 || count > uint64(len(data)-offset-n) {
+		return nil, fmt.Errorf("possible corruption: invalid number of entries")
+	}
+	offset += n
+	keys := make([]int, count)
+	key := uint64(0)
+	for i := range keys {
+		delta, n := binary.Uvarint(data[offset:])
+		if n <= 0 || (i > 0 && delta == 0) || delta > maxKey-key {
+			return nil, fmt.Errorf("possible corruption: invalid key at entry %d", i)
+		}
+		offset += n
+		key += delta
+		keys[i] = int(key)
+	}
+	numNibbleBytes := (int(count) + 1) / 2
+	if len(data) < offset+numNibbleBytes {
+		return nil, fmt.Errorf("possible corruption: VLQ slice too small: %d", len(data))
+	}
+	nibbles := data[offset : offset+numNibbleBytes]
+	offset += numNibbleBytes
+	values := make([]int, count)
+	for i := range values {
+		value := int(nibbles[i/2]>>((i&1)*4)) & 0xF
+		if value == vlqNibbleEscape {
+			if offset >= len(data) {
+				return nil, fmt.Errorf("possible corruption: VLQ slice too small: %d", len(data))
+			}
+			value = int(data[offset])
+			offset++
+		}
+		if value == empty || value > 63 {
+			return nil, fmt.Errorf("possible corruption: invalid value at entry %d: %d", i, value)
+		}
+		values[i] = value
+	}
+	if offset != len(data) {
+		return nil, fmt.Errorf("possible corruption: %d trailing bytes", len(data)-offset)
+	}
+
+	if mode == curModeHll {
+		arr, err := newHllArray(lgK, tgtHllType)
+		if err != nil {
+			return nil, err
+		}
+		for i, key := range keys {
+			if _, err := arr.couponUpdate(pair(key, values[i])); err != nil {
+				return nil, err
+			}
+		}
+		arr.putOutOfOrder(oooFlag)
+		if !oooFlag {
+			arr.putHipAccum(hipAccum)
+		}
+		return newHllSketchState(arr), nil
+	}
+
+	sk, err := NewHllSketch(lgK, tgtHllType)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		if _, err := sk.couponUpdate(pair(key, values[i])); err != nil {
+			return nil, err
+		}
+	}
+	if sk.GetCurMode() != mode {
+		return nil, fmt.Errorf("possible corruption: %d entries do not match curMode %d", count, mode)
+	}
+	sk.(*hllSketchState).sketch.putOutOfOrder(oooFlag)
+	return sk, nil
+}
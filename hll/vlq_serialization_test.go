@@ -0,0 +1,150 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package hll
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVlqSerialization(t *testing.T) {
+	for _, tgtHllType := range []TgtHllType{TgtHllTypeHll4, TgtHllTypeHll6, TgtHllTypeHll8} {
+		for _, n := range []int{0, 5, 100, 1000, 10000, 100000} {
+			for _, lgK := range []int{4, 12} {
+				sk, err := NewHllSketch(lgK, tgtHllType)
+				assert.NoError(t, err)
+				for i := 0; i < n; i++ {
+					assert.NoError(t, sk.UpdateInt64(int64(i)))
+				}
+				bytes, err := sk.ToVlqSlice()
+				assert.NoError(t, err)
+				sk2, err := NewHllSketchFromVlqSlice(bytes)
+				assert.NoError(t, err)
+				assert.Equal(t, sk.GetCurMode(), sk2.GetCurMode())
+				assert.Equal(t, sk.GetLgConfigK(), sk2.GetLgConfigK())
+				assert.Equal(t, sk.GetTgtHllType(), sk2.GetTgtHllType())
+				est1, err := sk.GetEstimate()
+				assert.NoError(t, err)
+				est2, err := sk2.GetEstimate()
+				assert.NoError(t, err)
+				assert.InDelta(t, est1, est2, est1*1e-12)
+				if sk.GetCurMode() == curModeHll {
+					// the layout of coupon lists and sets depends on the insertion order
+					compact1, err := sk.ToCompactSlice()
+					assert.NoError(t, err)
+					compact2, err := sk2.ToCompactSlice()
+					assert.NoError(t, err)
+					assert.Equal(t, compact1, compact2)
+				}
+
+				// the deserialized sketch keeps updating as the original one
+				for i := n; i < n+1000; i++ {
+					assert.NoError(t, sk.UpdateInt64(int64(i)))
+					assert.NoError(t, sk2.UpdateInt64(int64(i)))
+				}
+				est1, _ = sk.GetEstimate()
+				est2, _ = sk2.GetEstimate()
+				assert.InDelta(t, est1, est2, est1*1e-12)
+			}
+		}
+	}
+}
+
+func TestVlqSerializationOutOfOrder(t *testing.T) {
+	u, err := NewUnion(12)
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, u.UpdateInt64(int64(i)))
+	}
+	sk, err := u.GetResult(TgtHllTypeHll4)
+	assert.NoError(t, err)
+	bytes, err := sk.ToVlqSlice()
+	assert.NoError(t, err)
+	sk2, err := NewHllSketchFromVlqSlice(bytes)
+	assert.NoError(t, err)
+	compact1, err := sk.ToCompactSlice()
+	assert.NoError(t, err)
+	compact2, err := sk2.ToCompactSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, compact1, compact2)
+}
+
+func TestVlqSerializationSize(t *testing.T) {
+	for _, n := range []int{100, 1000, 10000} {
+		sk, err := NewHllSketch(14, TgtHllTypeHll8)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, sk.UpdateInt64(int64(i)))
+		}
+		vlq, err := sk.ToVlqSlice()
+		assert.NoError(t, err)
+		compact, err := sk.ToCompactSlice()
+		assert.NoError(t, err)
+		assert.Less(t, len(vlq), len(compact), "n=%d", n)
+	}
+}
+
+func TestVlqSerializationCorruption(t *testing.T) {
+	sk, err := NewHllSketch(10, TgtHllTypeHll4)
+	assert.NoError(t, err)
+	for i := 0; i < 5000; i++ {
+		assert.NoError(t, sk.UpdateInt64(int64(i)))
+	}
+	bytes, err := sk.ToVlqSlice()
+	assert.NoError(t, err)
+
+	_, err = NewHllSketchFromVlqSlice(nil)
+	assert.Error(t, err)
+	for _, l := range []int{1, 5, 12, len(bytes) / 2, len(bytes) - 1} {
+		_, err = NewHllSketchFromVlqSlice(bytes[:l])
+		assert.Error(t, err, "length %d", l)
+	}
+	_, err = NewHllSketchFromVlqSlice(append(bytes, 0))
+	assert.Error(t, err)
+	for _, idx := range []int{0, 1, 2, 3} {
+		corrupt := append([]byte{}, bytes...)
+		corrupt[idx] = 99
+		_, err = NewHllSketchFromVlqSlice(corrupt)
+		assert.Error(t, err, "byte %d", idx)
+	}
+}
+
+func BenchmarkVlqSerialization(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		sk, _ := NewHllSketch(14, TgtHllTypeHll4)
+		for i := 0; i < n; i++ {
+			_ = sk.UpdateInt64(int64(i))
+		}
+		b.Run(fmt.Sprintf("compact n=%d", n), func(b *testing.B) {
+			var bytes []byte
+			for i := 0; i < b.N; i++ {
+				bytes, _ = sk.ToCompactSlice()
+			}
+			b.ReportMetric(float64(len(bytes)), "bytes")
+		})
+		b.Run(fmt.Sprintf("vlq n=%d", n), func(b *testing.B) {
+			var bytes []byte
+			for i := 0; i < b.N; i++ {
+				bytes, _ = sk.ToVlqSlice()
+			}
+			b.ReportMetric(float64(len(bytes)), "bytes")
+		})
+	}
+}
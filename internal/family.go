@@ -26,6 +26,18 @@ type families struct {
 	HLL       family
 	Frequency family
 	Kll       family
+	Reservoir family
+	VarOpt    family
+	// CountMin is the family id of the Java and C++ CountMinSketch. No sketch of this library
+	// has this format, the id only exists so that GoCountMin can reject those sketches.
+	CountMin family
+	Bloom    family
+	// GoCountMin is the Count-Min sketch of this library, whose format and hashing differ from
+	// the CountMin family of the Java and C++ libraries.
+	GoCountMin family
+	// GoBloom is the Bloom filter of this library, whose hashing differs from the Bloom family
+	// of the Java and C++ libraries.
+	GoBloom family
 }
 
 var FamilyEnum = &families{
@@ -41,4 +53,28 @@ var FamilyEnum = &families{
 		Id:          15,
 		MaxPreLongs: 2,
 	},
+	Reservoir: family{
+		Id:          11,
+		MaxPreLongs: 2,
+	},
+	VarOpt: family{
+		Id:          13,
+		MaxPreLongs: 4,
+	},
+	CountMin: family{
+		Id:          18,
+		MaxPreLongs: 3,
+	},
+	Bloom: family{
+		Id:          21,
+		MaxPreLongs: 4,
+	},
+	GoCountMin: family{
+		Id:          146,
+		MaxPreLongs: 3,
+	},
+	GoBloom: family{
+		Id:          149,
+		MaxPreLongs: 4,
+	},
 }
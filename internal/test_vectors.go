@@ -0,0 +1,87 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// A TestVector describes a sketch by how it is built and the bytes it serializes to, so that the
+// serialization of a library can be checked by replaying the updates.
+type TestVector struct {
+	// Case is one of empty, single, exact or estimation.
+	Case string `json:"case"`
+	// Params holds the constructor parameters of the family, such as lgK and tgtHllType for HLL
+	// or k for KLL.
+	Params map[string]int `json:"params"`
+	// Updates is the sequence of update operations.
+	Updates []UpdateRange `json:"updates"`
+	// Serialized is the expected serialization, base64 encoded in JSON.
+	Serialized []byte `json:"serialized"`
+}
+
+// An UpdateRange updates the sketch with the Count consecutive integers starting at From.
+type UpdateRange struct {
+	From  int64 `json:"from"`
+	Count int64 `json:"count"`
+}
+
+// TestVectorFile is the content of a file of test vectors, one vector per case.
+type TestVectorFile struct {
+	// Family is the sketch family, hll or kll_doubles.
+	Family  string       `json:"family"`
+	Vectors []TestVector `json:"vectors"`
+}
+
+// TestVectorCases returns the update operations of the empty, single, exact and estimation cases,
+// given the number of items of the exact and estimation cases for the family and its parameters.
+func TestVectorCases(exactN, estimationN int64) map[string][]UpdateRange {
+	return map[string][]UpdateRange{
+		"empty":      {},
+		"single":     {{From: 0, Count: 1}},
+		"exact":      {{From: 0, Count: exactN}},
+		"estimation": {{From: 0, Count: estimationN / 2}, {From: estimationN, Count: estimationN / 2}},
+	}
+}
+
+// TestVectorCaseNames lists the cases in the order they are written.
+var TestVectorCaseNames = []string{"empty", "single", "exact", "estimation"}
+
+// ReadTestVectorFile reads a file written by WriteTestVectorFile.
+func ReadTestVectorFile(path string) (*TestVectorFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file TestVectorFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// WriteTestVectorFile writes the vectors to path as indented JSON.
+func WriteTestVectorFile(path string, file *TestVectorFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -41,6 +41,8 @@ const (
 	JavaPath = "../serialization_test_data/java_generated_files"
 	CppPath  = "../serialization_test_data/cpp_generated_files"
 	GoPath   = "../serialization_test_data/go_generated_files"
+	// VectorsPath holds the test vectors written by cmd/genvectors.
+	VectorsPath = "../serialization_test_data/go_test_vectors"
 )
 
 // GetShortLE gets a short value from a byte array in little endian format.
@@ -0,0 +1,119 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math/rand"
+)
+
+// CompactionStrategy selects which half of a level is kept when the level is compacted:
+// the items at even (offset 0) or at odd (offset 1) positions of the sorted level.
+//
+// Random offsets make the sketch unbiased. The other strategies make the sketch reproducible,
+// at the cost of a possible bias of the rank estimates for adversarial input orders.
+//
+// A stateful strategy should also implement the Cloner interface, so that a copy of the sketch,
+// such as ReadOnlyView.Snapshot, gets its own state. Otherwise the copy shares the strategy.
+type CompactionStrategy interface {
+	// SelectOffset returns 0 or 1 for the compaction of a level of levelSize items.
+	// rng is the random source set with WithRng, or nil for the global source of math/rand.
+	SelectOffset(levelSize int, rng *rand.Rand) int
+}
+
+// Cloner is implemented by the stateful compaction strategies, see CompactionStrategy.
+type Cloner interface {
+	// Clone returns a copy of the strategy with the same state.
+	Clone() CompactionStrategy
+}
+
+// RandomCompactionStrategy selects a uniformly random offset. It is the default strategy.
+type RandomCompactionStrategy struct{}
+
+func (RandomCompactionStrategy) SelectOffset(levelSize int, rng *rand.Rand) int {
+	if rng == nil {
+		return rand.Intn(2)
+	}
+	return rng.Intn(2)
+}
+
+// AlternatingCompactionStrategy selects offsets 0 and 1 alternately, starting with 0.
+// It is stateful, so it must not be shared by sketches used concurrently.
+type AlternatingCompactionStrategy struct {
+	next int
+}
+
+func (s *AlternatingCompactionStrategy) SelectOffset(levelSize int, rng *rand.Rand) int {
+	offset := s.next
+	s.next = 1 - s.next
+	return offset
+}
+
+func (s *AlternatingCompactionStrategy) Clone() CompactionStrategy {
+	return &AlternatingCompactionStrategy{next: s.next}
+}
+
+// DeterministicCompactionStrategy always selects offset 0.
+type DeterministicCompactionStrategy struct{}
+
+func (DeterministicCompactionStrategy) SelectOffset(levelSize int, rng *rand.Rand) int {
+	return 0
+}
+
+type itemsSketchOptions struct {
+	compaction CompactionStrategy
+	rng        *rand.Rand
+}
+
+// ItemsSketchOption configures optional behaviour of an ItemsSketch at construction.
+type ItemsSketchOption func(*itemsSketchOptions)
+
+// WithCompactionStrategy sets the strategy selecting the offset of the compactions.
+func WithCompactionStrategy(s CompactionStrategy) ItemsSketchOption {
+	return func(o *itemsSketchOptions) {
+		o.compaction = s
+	}
+}
+
+// WithRng sets the random source passed to the compaction strategy, for reproducible random compactions.
+// A rand.Rand is not safe for concurrent use, so it must not be shared by sketches used concurrently.
+func WithRng(rng *rand.Rand) ItemsSketchOption {
+	return func(o *itemsSketchOptions) {
+		o.rng = rng
+	}
+}
+
+// clone returns options that can be used concurrently with o: a stateful strategy is cloned,
+// and a random source is replaced with a new one seeded from the global source of math/rand,
+// as a rand.Rand can neither be copied nor be drawn from concurrently.
+func (o itemsSketchOptions) clone() itemsSketchOptions {
+	if c, ok := o.compaction.(Cloner); ok {
+		o.compaction = c.Clone()
+	}
+	if o.rng != nil {
+		o.rng = rand.New(rand.NewSource(rand.Int63()))
+	}
+	return o
+}
+
+func newItemsSketchOptions(opts []ItemsSketchOption) itemsSketchOptions {
+	o := itemsSketchOptions{compaction: RandomCompactionStrategy{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildLongsSketch(t *testing.T, n int, opts ...ItemsSketchOption) *ItemsSketch[int64] {
+	sk, err := NewKllItemsSketch[int64](200, _DEFAULT_M, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{}, opts...)
+	assert.NoError(t, err)
+	for i := 0; i < n; i++ {
+		sk.Update(int64((i * 7919) % n))
+	}
+	return sk
+}
+
+func TestCompactionStrategy_Reproducible(t *testing.T) {
+	n := 100000
+	testCases := []struct {
+		newStrategy func() []ItemsSketchOption
+		unbiased    bool
+	}{
+		{
+			// always keeping the even items biases the ranks
+			newStrategy: func() []ItemsSketchOption {
+				return []ItemsSketchOption{WithCompactionStrategy(DeterministicCompactionStrategy{})}
+			},
+		},
+		{
+			newStrategy: func() []ItemsSketchOption {
+				return []ItemsSketchOption{WithCompactionStrategy(&AlternatingCompactionStrategy{})}
+			},
+			unbiased: true,
+		},
+		{
+			newStrategy: func() []ItemsSketchOption {
+				return []ItemsSketchOption{WithRng(rand.New(rand.NewSource(42)))}
+			},
+			unbiased: true,
+		},
+	}
+	for _, tc := range testCases {
+		newStrategy := tc.newStrategy
+		sk1 := buildLongsSketch(t, n, newStrategy()...)
+		sk2 := buildLongsSketch(t, n, newStrategy()...)
+		bytes1, err := sk1.ToSlice()
+		assert.NoError(t, err)
+		bytes2, err := sk2.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, bytes1, bytes2)
+
+		// merges are reproducible as well
+		sk1.Merge(buildLongsSketch(t, n, newStrategy()...))
+		sk2.Merge(buildLongsSketch(t, n, newStrategy()...))
+		bytes1, err = sk1.ToSlice()
+		assert.NoError(t, err)
+		bytes2, err = sk2.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, bytes1, bytes2)
+
+		if tc.unbiased {
+			median, err := sk1.GetQuantile(0.5, true)
+			assert.NoError(t, err)
+			assert.InDelta(t, n/2, median, float64(n)*PMF_EPS_FOR_K_256)
+		}
+	}
+}
+
+func TestCompactionStrategy_Offsets(t *testing.T) {
+	assert.Equal(t, 0, DeterministicCompactionStrategy{}.SelectOffset(10, nil))
+	alternating := &AlternatingCompactionStrategy{}
+	assert.Equal(t, []int{0, 1, 0, 1}, []int{
+		alternating.SelectOffset(10, nil),
+		alternating.SelectOffset(10, nil),
+		alternating.SelectOffset(10, nil),
+		alternating.SelectOffset(10, nil),
+	})
+	offset := RandomCompactionStrategy{}.SelectOffset(10, nil)
+	assert.True(t, offset == 0 || offset == 1)
+
+	sk := buildLongsSketch(t, 10000, WithCompactionStrategy(DeterministicCompactionStrategy{}))
+	down, err := Downsample(sk, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, DeterministicCompactionStrategy{}, down.options.compaction)
+}
@@ -0,0 +1,52 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// CheckCompatibility checks that data, a compact KLL doubles sketch serialized by any DataSketches library
+// such as the Java or C++ ones, is deserialized with expectedN items and a median within tolerance
+// of expectedMedian, and that this library serializes it back to exactly the same bytes.
+// It is meant for cross-language compatibility tests against golden files.
+func CheckCompatibility(data []byte, expectedN uint64, expectedMedian float64, tolerance float64) error {
+	sketch, err := NewDoublesSketchFromSlice(data)
+	if err != nil {
+		return err
+	}
+	if sketch.GetN() != expectedN {
+		return fmt.Errorf("n %d is not %d", sketch.GetN(), expectedN)
+	}
+	if expectedN > 0 {
+		median := sketch.GetQuantile(0.5)
+		if math.Abs(median-expectedMedian) > tolerance {
+			return fmt.Errorf("median %f is not within %f of %f", median, tolerance, expectedMedian)
+		}
+	}
+	sl, err := sketch.ToSlice()
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sl, data) {
+		return fmt.Errorf("sketch serialized back to different bytes")
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+// ImportStats counts the rows seen by ImportCSVFloat64.
+type ImportStats struct {
+	RowsRead    uint64 // data rows read, excluding the header
+	RowsSkipped uint64 // data rows not presented to the sketch, for any of the reasons below
+	ErrorCount  uint64 // rows skipped because they are not valid CSV
+}
+
+// ImportCSVFloat64 streams the rows of a CSV from r into a new sketch with the given k,
+// parsing the column at columnIndex as a float64. The first row is ignored if hasHeader is true.
+// Rows that are not valid CSV, that do not have the column, or whose column is not a number
+// (or is NaN) are skipped and counted in the returned ImportStats.
+// An error is only returned for an invalid argument or a failure to read from r.
+func ImportCSVFloat64(r io.Reader, k uint16, columnIndex int, hasHeader bool) (*ItemsSketch[float64], ImportStats, error) {
+	var stats ImportStats
+	if columnIndex < 0 {
+		return nil, stats, fmt.Errorf("columnIndex must be >= 0: %d", columnIndex)
+	}
+	sketch, err := NewKllItemsSketch[float64](k, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+	if err != nil {
+		return nil, stats, err
+	}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if hasHeader {
+			// the header is not validated, it may even be malformed
+			hasHeader = false
+			if err == nil || errors.As(err, new(*csv.ParseError)) {
+				continue
+			}
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, stats, err
+			}
+			stats.RowsRead++
+			stats.RowsSkipped++
+			stats.ErrorCount++
+			continue
+		}
+		stats.RowsRead++
+		if columnIndex >= len(record) {
+			stats.RowsSkipped++
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(record[columnIndex]), 64)
+		if err != nil || math.IsNaN(v) {
+			stats.RowsSkipped++
+			continue
+		}
+		sketch.Update(v)
+	}
+	return sketch, stats, nil
+}
+
+// ExportQuantilesCSV writes to w a two-column CSV with a "rank,quantile" header and the
+// INCLUSIVE quantile of sketch at each of the given normalized ranks.
+func ExportQuantilesCSV(w io.Writer, ranks []float64, sketch *DoublesSketch) error {
+	if sketch == nil {
+		return errors.New("sketch is nil")
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"rank", "quantile"}); err != nil {
+		return err
+	}
+	for _, rank := range ranks {
+		quantile, err := sketch.sketch.GetQuantile(rank, true)
+		if err != nil {
+			return err
+		}
+		err = writer.Write([]string{
+			strconv.FormatFloat(rank, 'g', -1, 64),
+			strconv.FormatFloat(quantile, 'g', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,85 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportCSVFloat64(t *testing.T) {
+	input := strings.Join([]string{
+		"id,value,label",
+		"1,10.5,a",
+		"2, 20 ,b",
+		"3,abc,c",
+		"4",
+		"5,a\"b,d",
+		"6,NaN,e",
+		"7,-3,f",
+	}, "\n")
+	sk, stats, err := ImportCSVFloat64(strings.NewReader(input), 200, 1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, ImportStats{RowsRead: 7, RowsSkipped: 4, ErrorCount: 1}, stats)
+	assert.Equal(t, uint64(3), sk.GetN())
+	minItem, err := sk.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, -3.0, minItem)
+	maxItem, err := sk.GetMaxItem()
+	assert.NoError(t, err)
+	assert.Equal(t, 20.0, maxItem)
+
+	// without header the first row is data
+	_, stats, err = ImportCSVFloat64(strings.NewReader(input), 200, 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ImportStats{RowsRead: 8, RowsSkipped: 5, ErrorCount: 1}, stats)
+
+	_, _, err = ImportCSVFloat64(strings.NewReader(input), 200, -1, true)
+	assert.Error(t, err)
+	_, _, err = ImportCSVFloat64(strings.NewReader(input), 1, 0, true)
+	assert.Error(t, err)
+	_, _, err = ImportCSVFloat64(failingReader{}, 200, 0, true)
+	assert.Error(t, err)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestExportQuantilesCSV(t *testing.T) {
+	sk, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.Error(t, ExportQuantilesCSV(&buf, []float64{0.5}, sk))
+	assert.Error(t, ExportQuantilesCSV(&buf, []float64{0.5}, nil))
+
+	for i := 1; i <= 100; i++ {
+		sk.Update(float64(i))
+	}
+	buf.Reset()
+	assert.NoError(t, ExportQuantilesCSV(&buf, []float64{0, 0.5, 1}, sk))
+	assert.Equal(t, "rank,quantile\n0,1\n0.5,50\n1,100\n", buf.String())
+
+	assert.Error(t, ExportQuantilesCSV(&buf, []float64{2}, sk))
+}
@@ -0,0 +1,102 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+// KllDiagnostics describes the internal state of a sketch, for capacity planning and debugging.
+// It is not part of the algorithm API and its content may change.
+type KllDiagnostics struct {
+	K         uint16
+	M         uint8
+	N         uint64
+	NumLevels int
+	// LevelSizes holds the number of items retained in each level, level 0 first.
+	LevelSizes []uint32
+	// LevelCapacities holds the nominal capacity of each level, level 0 first.
+	LevelCapacities []uint32
+	NumRetained     uint32
+	// ItemsCapacity is the number of item slots allocated, retained or free.
+	ItemsCapacity int
+	// NumCompactions is the number of level compactions since the sketch was created,
+	// deserialized or reset, during updates and merges. It is not serialized.
+	NumCompactions uint64
+}
+
+// LevelStat describes one level of a sketch, as returned by GetLevelStats.
+type LevelStat struct {
+	Level int
+	// StartIndex and EndIndex delimit the items of the level in the items array, EndIndex exclusive.
+	StartIndex int
+	EndIndex   int
+	Size       int
+	// Capacity is the nominal capacity of the level.
+	Capacity int
+}
+
+// GetNumLevels returns the number of levels of the sketch, including empty ones.
+func (s *ItemsSketch[C]) GetNumLevels() int {
+	return s.getNumLevels()
+}
+
+// GetLevelStats returns the layout of each level of the sketch, level 0 first.
+// Level 0 receives the updates, and the items of level i have a weight of 2^i.
+func (s *ItemsSketch[C]) GetLevelStats() []LevelStat {
+	numLevels := s.getNumLevels()
+	stats := make([]LevelStat, numLevels)
+	for level := range stats {
+		stats[level] = LevelStat{
+			Level:      level,
+			StartIndex: int(s.levels[level]),
+			EndIndex:   int(s.levels[level+1]),
+			Size:       int(s.levels[level+1] - s.levels[level]),
+			Capacity:   int(levelCapacity(s.k, uint8(numLevels), uint8(level), s.m)),
+		}
+	}
+	return stats
+}
+
+// GetTotalCapacity returns the number of item slots allocated by the sketch, retained or free.
+func (s *ItemsSketch[C]) GetTotalCapacity() int {
+	return len(s.items)
+}
+
+// Diagnostics returns the current internal state of the sketch.
+func (s *ItemsSketch[C]) Diagnostics() KllDiagnostics {
+	numLevels := s.getNumLevels()
+	levelSizes := make([]uint32, numLevels)
+	levelCapacities := make([]uint32, numLevels)
+	for _, stat := range s.GetLevelStats() {
+		levelSizes[stat.Level] = uint32(stat.Size)
+		levelCapacities[stat.Level] = uint32(stat.Capacity)
+	}
+	return KllDiagnostics{
+		K:               s.k,
+		M:               s.m,
+		N:               s.n,
+		NumLevels:       numLevels,
+		LevelSizes:      levelSizes,
+		LevelCapacities: levelCapacities,
+		NumRetained:     s.GetNumRetained(),
+		ItemsCapacity:   s.GetTotalCapacity(),
+		NumCompactions:  s.numCompactions,
+	}
+}
+
+// Diagnostics returns the current internal state of the sketch.
+func (s *DoublesSketch) Diagnostics() KllDiagnostics {
+	return s.sketch.Diagnostics()
+}
@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	sk, err := NewDoublesSketch(20)
+	assert.NoError(t, err)
+	d := sk.Diagnostics()
+	assert.Equal(t, uint16(20), d.K)
+	assert.Equal(t, 1, d.NumLevels)
+	assert.Equal(t, []uint32{0}, d.LevelSizes)
+	assert.Equal(t, []uint32{20}, d.LevelCapacities)
+	assert.Equal(t, uint64(0), d.NumCompactions)
+
+	for i := 0; i < 20; i++ {
+		sk.Update(float64(i))
+	}
+	assert.Equal(t, uint64(0), sk.Diagnostics().NumCompactions)
+
+	for i := 20; i < 10000; i++ {
+		sk.Update(float64(i))
+	}
+	d = sk.Diagnostics()
+	assert.Equal(t, uint64(10000), d.N)
+	assert.Greater(t, d.NumLevels, 1)
+	assert.Len(t, d.LevelSizes, d.NumLevels)
+	assert.Len(t, d.LevelCapacities, d.NumLevels)
+	retained := uint32(0)
+	for _, size := range d.LevelSizes {
+		retained += size
+	}
+	assert.Equal(t, d.NumRetained, retained)
+	assert.GreaterOrEqual(t, d.ItemsCapacity, int(d.NumRetained))
+	assert.Greater(t, d.NumCompactions, uint64(0))
+
+	// merges compact too
+	other, err := NewDoublesSketch(20)
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		other.Update(float64(i))
+	}
+	before := sk.Diagnostics().NumCompactions
+	assert.NoError(t, sk.Merge(other))
+	assert.Greater(t, sk.Diagnostics().NumCompactions, before)
+
+	sk.Reset()
+	assert.Equal(t, uint64(0), sk.Diagnostics().NumCompactions)
+}
+
+func TestLevelStats(t *testing.T) {
+	sk, err := NewKllItemsSketch[float64](20, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sk.GetNumLevels())
+	assert.Equal(t, []LevelStat{{Level: 0, StartIndex: 20, EndIndex: 20, Size: 0, Capacity: 20}}, sk.GetLevelStats())
+	assert.Equal(t, 20, sk.GetTotalCapacity())
+
+	for i := 0; i < 10000; i++ {
+		sk.Update(float64(i))
+	}
+	stats := sk.GetLevelStats()
+	assert.Len(t, stats, sk.GetNumLevels())
+	assert.Equal(t, sk.GetTotalCapacity()-int(sk.GetNumRetained()), stats[0].StartIndex)
+	assert.Equal(t, sk.GetTotalCapacity(), stats[len(stats)-1].EndIndex)
+	weight := uint64(0)
+	for level, stat := range stats {
+		assert.Equal(t, level, stat.Level)
+		assert.Equal(t, stat.EndIndex-stat.StartIndex, stat.Size)
+		if level > 0 {
+			assert.Equal(t, stats[level-1].EndIndex, stat.StartIndex)
+		}
+		weight += uint64(stat.Size) << level
+	}
+	// the retained items keep the weight of the whole stream
+	assert.Equal(t, sk.GetN(), weight)
+}
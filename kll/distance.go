@@ -0,0 +1,97 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"errors"
+	"math"
+)
+
+// WassersteinDistance returns an approximation of the 1-Wasserstein (Earth Mover's) distance between
+// the distributions summarized by the two sketches, which is the integral of |F_a(x) - F_b(x)|.
+//
+// The empirical CDFs are the INCLUSIVE ranks of the retained items of both sketches, evaluated at the
+// union of the retained items. They are step functions, constant between two consecutive points, so the
+// integral is the exact sum of |F_a - F_b| at each point times the distance to the next point.
+// toFloat64 maps an item to the real line and must be increasing with respect to the compare function
+// of the sketches.
+func WassersteinDistance[C comparable](a, b *ItemsSketch[C], toFloat64 func(C) float64) (float64, error) {
+	xs, cdfA, cdfB, err := mergedCDFs(a, b, toFloat64)
+	if err != nil {
+		return 0, err
+	}
+	distance := 0.0
+	for i := 1; i < len(xs); i++ {
+		distance += math.Abs(cdfA[i-1]-cdfB[i-1]) * (xs[i] - xs[i-1])
+	}
+	return distance, nil
+}
+
+// mergedCDFs evaluates the INCLUSIVE CDFs of both sketches at every distinct retained item of either sketch,
+// in ascending order.
+func mergedCDFs[C comparable](a, b *ItemsSketch[C], toFloat64 func(C) float64) ([]float64, []float64, []float64, error) {
+	if a == nil || b == nil {
+		return nil, nil, nil, errors.New("no sketch provided")
+	}
+	if toFloat64 == nil {
+		return nil, nil, nil, errors.New("no toFloat64 function provided")
+	}
+	if a.IsEmpty() || b.IsEmpty() {
+		return nil, nil, nil, errors.New("operation is undefined for an empty sketch")
+	}
+	svA, err := a.GetSortedView()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	svB, err := b.GetSortedView()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	numA, numB := len(svA.quantiles), len(svB.quantiles)
+	totalA, totalB := float64(svA.totalN), float64(svB.totalN)
+	xs := make([]float64, 0, numA+numB)
+	cdfA := make([]float64, 0, numA+numB)
+	cdfB := make([]float64, 0, numA+numB)
+	fa, fb := 0.0, 0.0
+	i, j := 0, 0
+	for i < numA || j < numB {
+		x := math.Inf(1)
+		if i < numA {
+			x = toFloat64(svA.quantiles[i])
+		}
+		if j < numB {
+			x = math.Min(x, toFloat64(svB.quantiles[j]))
+		}
+		if math.IsNaN(x) {
+			return nil, nil, nil, errors.New("toFloat64 returned NaN")
+		}
+		for i < numA && toFloat64(svA.quantiles[i]) == x {
+			fa = float64(svA.cumWeights[i]) / totalA
+			i++
+		}
+		for j < numB && toFloat64(svB.quantiles[j]) == x {
+			fb = float64(svB.cumWeights[j]) / totalB
+			j++
+		}
+		xs = append(xs, x)
+		cdfA = append(cdfA, fa)
+		cdfB = append(cdfB, fb)
+	}
+	return xs, cdfA, cdfB, nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func identity(v float64) float64 {
+	return v
+}
+
+func newDistanceTestSketch(t *testing.T, values func(i int) float64, n int) *ItemsSketch[float64] {
+	sk, err := NewKllItemsSketch[float64](200, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	for i := 0; i < n; i++ {
+		sk.Update(values(i))
+	}
+	return sk
+}
+
+func TestWassersteinDistance(t *testing.T) {
+	n := 100000
+	a := newDistanceTestSketch(t, func(i int) float64 { return float64(i % 1000) }, n)
+	b := newDistanceTestSketch(t, func(i int) float64 { return float64(i%1000) + 100 }, n)
+
+	d, err := WassersteinDistance(a, a, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, d)
+
+	// shifting a distribution moves all its mass by the shift
+	d, err = WassersteinDistance(a, b, identity)
+	assert.NoError(t, err)
+	assert.InDelta(t, 100, d, 100*0.05)
+	d2, err := WassersteinDistance(b, a, identity)
+	assert.NoError(t, err)
+	assert.InDelta(t, d, d2, 1e-9)
+}
+
+func TestWassersteinDistance_Exact(t *testing.T) {
+	// point masses at 0 and 1
+	zero := newDistanceTestSketch(t, func(int) float64 { return 0 }, 1)
+	one := newDistanceTestSketch(t, func(int) float64 { return 1 }, 1)
+	d, err := WassersteinDistance(zero, one, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, d)
+
+	// {0, 1, 2, 3} against {1, 2, 3, 4}: every unit of mass moves by 1
+	a := newDistanceTestSketch(t, func(i int) float64 { return float64(i) }, 4)
+	b := newDistanceTestSketch(t, func(i int) float64 { return float64(i + 1) }, 4)
+	d, err = WassersteinDistance(a, b, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, d)
+
+	// {0, 0, 0, 4} against {0, 0, 0, 0}: a quarter of the mass moves by 4
+	c := newDistanceTestSketch(t, func(i int) float64 { return float64(i / 3 * 4) }, 4)
+	z := newDistanceTestSketch(t, func(int) float64 { return 0 }, 4)
+	d, err = WassersteinDistance(c, z, identity)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, d)
+}
+
+func TestWassersteinDistance_Errors(t *testing.T) {
+	a := newDistanceTestSketch(t, func(i int) float64 { return float64(i) }, 10)
+	empty := newDistanceTestSketch(t, func(i int) float64 { return float64(i) }, 0)
+	_, err := WassersteinDistance(a, empty, identity)
+	assert.Error(t, err)
+	_, err = WassersteinDistance(nil, a, identity)
+	assert.Error(t, err)
+	_, err = WassersteinDistance(a, a, nil)
+	assert.Error(t, err)
+	_, err = WassersteinDistance(a, a, func(float64) float64 { return math.NaN() })
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"context"
+	"fmt"
+	"github.com/apache/datasketches-go/common"
+	"math"
+)
+
+// DoublesSketch is a KLL sketch for float64 items.
+//
+// It is backed by an ItemsSketch[float64] with the natural ordering of float64 and
+// the 8 bytes little-endian serde, so the binary format is the one of the Java KllDoublesSketch.
+// All queries use the INCLUSIVE search criterion, which is the default of the Java library.
+type DoublesSketch struct {
+	sketch *ItemsSketch[float64]
+}
+
+var doublesSketchComparator = common.ItemSketchDoubleComparator(false)
+
+// NewDoublesSketch create a new DoublesSketch with the given k and the default m.
+// The default k = 200 results in a normalized rank error of about 1.65%.
+func NewDoublesSketch(k uint16) (*DoublesSketch, error) {
+	sketch, err := NewKllItemsSketch[float64](k, _DEFAULT_M, doublesSketchComparator, common.ItemSketchDoubleSerDe{})
+	if err != nil {
+		return nil, err
+	}
+	return &DoublesSketch{sketch: sketch}, nil
+}
+
+// NewDoublesSketchFromSlice create a new DoublesSketch from the given byte slice (serialized sketch).
+func NewDoublesSketchFromSlice(sl []byte) (*DoublesSketch, error) {
+	sketch, err := NewKllItemsSketchFromSlice[float64](sl, doublesSketchComparator, common.ItemSketchDoubleSerDe{})
+	if err != nil {
+		return nil, err
+	}
+	return &DoublesSketch{sketch: sketch}, nil
+}
+
+// IsEmpty returns true if the sketch is empty, otherwise false.
+func (s *DoublesSketch) IsEmpty() bool {
+	return s.sketch.IsEmpty()
+}
+
+// IsEstimationMode returns true if the sketch is in estimation mode, otherwise false.
+func (s *DoublesSketch) IsEstimationMode() bool {
+	return s.sketch.IsEstimationMode()
+}
+
+// GetN returns the length of the input stream offered to the sketch.
+func (s *DoublesSketch) GetN() uint64 {
+	return s.sketch.GetN()
+}
+
+// GetK returns the value of k (which controls the accuracy of the sketch and its memory space usage)
+func (s *DoublesSketch) GetK() uint16 {
+	return s.sketch.GetK()
+}
+
+// GetNumRetained returns the number of quantiles retained by the sketch.
+func (s *DoublesSketch) GetNumRetained() uint32 {
+	return s.sketch.GetNumRetained()
+}
+
+// GetMinItem returns the minimum item of the stream, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetMinItem() float64 {
+	if s.IsEmpty() {
+		return math.NaN()
+	}
+	return *s.sketch.minItem
+}
+
+// GetMaxItem returns the maximum item of the stream, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetMaxItem() float64 {
+	if s.IsEmpty() {
+		return math.NaN()
+	}
+	return *s.sketch.maxItem
+}
+
+// GetNormalizedRankError return the approximate rank error of this sketch normalized as a fraction between zero and one.
+// See ItemsSketch.GetNormalizedRankError.
+func (s *DoublesSketch) GetNormalizedRankError(pmf bool) float64 {
+	return s.sketch.GetNormalizedRankError(pmf)
+}
+
+// Update this sketch with the given item. NaN values are ignored.
+func (s *DoublesSketch) Update(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	s.sketch.Update(v)
+}
+
+// Merge the given sketch into this sketch.
+func (s *DoublesSketch) Merge(other *DoublesSketch) error {
+	if other == nil {
+		return fmt.Errorf("no sketch provided")
+	}
+	s.sketch.Merge(other.sketch)
+	return nil
+}
+
+// MergeCtx merges other into this sketch unless ctx is done first. See ItemsSketch.MergeCtx.
+func (s *DoublesSketch) MergeCtx(ctx context.Context, other *DoublesSketch) error {
+	if other == nil {
+		return fmt.Errorf("no sketch provided")
+	}
+	return s.sketch.MergeCtx(ctx, other.sketch)
+}
+
+// Reset this sketch to the empty state.
+func (s *DoublesSketch) Reset() {
+	s.sketch.Reset()
+}
+
+// GetRank returns the normalized rank of the given item, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetRank(v float64) float64 {
+	rank, err := s.sketch.GetRank(v, true)
+	if err != nil {
+		return math.NaN()
+	}
+	return rank
+}
+
+// GetQuantile returns the approximate quantile of the given normalized rank.
+// It returns NaN if the sketch is empty or the rank is not in [0, 1].
+func (s *DoublesSketch) GetQuantile(rank float64) float64 {
+	quantile, err := s.sketch.GetQuantile(rank, true)
+	if err != nil {
+		return math.NaN()
+	}
+	return quantile
+}
+
+// GetPercentile returns the approximate quantile of the given percentile, that is GetQuantile(p / 100).
+// It returns NaN if the sketch is empty or p is not in [0, 100].
+func (s *DoublesSketch) GetPercentile(p float64) float64 {
+	return s.GetQuantile(p / 100)
+}
+
+// GetP50 returns the approximate median, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetP50() float64 {
+	return s.GetPercentile(50)
+}
+
+// GetP90 returns the approximate 90th percentile, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetP90() float64 {
+	return s.GetPercentile(90)
+}
+
+// GetP95 returns the approximate 95th percentile, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetP95() float64 {
+	return s.GetPercentile(95)
+}
+
+// GetP99 returns the approximate 99th percentile, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetP99() float64 {
+	return s.GetPercentile(99)
+}
+
+// GetP999 returns the approximate 99.9th percentile, or NaN if the sketch is empty.
+func (s *DoublesSketch) GetP999() float64 {
+	return s.GetPercentile(99.9)
+}
+
+// GetCDF returns an approximation to the Cumulative Distribution Function of the input stream
+// given a set of unique, monotonically increasing split points. See ItemsSketch.GetCDF.
+// It returns nil if the sketch is empty or the split points are invalid.
+func (s *DoublesSketch) GetCDF(splits []float64) []float64 {
+	cdf, err := s.sketch.GetCDF(splits, true)
+	if err != nil {
+		return nil
+	}
+	return cdf
+}
+
+// GetPMF returns an approximation to the Probability Mass Function of the input stream
+// given a set of unique, monotonically increasing split points. See ItemsSketch.GetPMF.
+// It returns nil if the sketch is empty or the split points are invalid.
+func (s *DoublesSketch) GetPMF(splits []float64) []float64 {
+	pmf, err := s.sketch.GetPMF(splits, true)
+	if err != nil {
+		return nil
+	}
+	return pmf
+}
+
+// String returns a JSON summary of the sketch for debugging. See ItemsSketch.String.
+func (s *DoublesSketch) String() string {
+	return s.sketch.String()
+}
+
+// ToSlice returns the serialized byte array of this sketch. See ItemsSketch.ToSlice.
+func (s *DoublesSketch) ToSlice() ([]byte, error) {
+	return s.sketch.ToSlice()
+}
+
+// GobEncode implements gob.GobEncoder with the bytes returned by ToSlice.
+func (s *DoublesSketch) GobEncode() ([]byte, error) {
+	return s.ToSlice()
+}
+
+// GobDecode implements gob.GobDecoder, replacing the state of the sketch with the one serialized in data.
+// It can decode into a zero DoublesSketch.
+func (s *DoublesSketch) GobDecode(data []byte) error {
+	sketch, err := NewDoublesSketchFromSlice(data)
+	if err != nil {
+		return err
+	}
+	s.sketch = sketch.sketch
+	return nil
+}
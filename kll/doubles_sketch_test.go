@@ -0,0 +1,151 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"fmt"
+	"github.com/apache/datasketches-go/internal"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"testing"
+)
+
+func TestDoublesSketch_Empty(t *testing.T) {
+	sk, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	assert.True(t, sk.IsEmpty())
+	assert.Equal(t, uint64(0), sk.GetN())
+	assert.True(t, math.IsNaN(sk.GetMinItem()))
+	assert.True(t, math.IsNaN(sk.GetMaxItem()))
+	assert.True(t, math.IsNaN(sk.GetQuantile(0.5)))
+	assert.True(t, math.IsNaN(sk.GetRank(0)))
+	assert.True(t, math.IsNaN(sk.GetP99()))
+	assert.Nil(t, sk.GetCDF([]float64{0}))
+	assert.Nil(t, sk.GetPMF([]float64{0}))
+	sl, err := sk.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, 8, len(sl))
+}
+
+func TestDoublesSketch_KLimits(t *testing.T) {
+	_, err := NewDoublesSketch(_MIN_K - 1)
+	assert.Error(t, err)
+}
+
+func TestDoublesSketch_ManyValues(t *testing.T) {
+	sk, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	n := 10000
+	for i := 1; i <= n; i++ {
+		sk.Update(float64(i))
+	}
+	sk.Update(math.NaN())
+	assert.Equal(t, uint64(n), sk.GetN())
+	assert.True(t, sk.IsEstimationMode())
+	assert.Equal(t, 1.0, sk.GetMinItem())
+	assert.Equal(t, float64(n), sk.GetMaxItem())
+	assert.InDelta(t, float64(n)/2, sk.GetQuantile(0.5), float64(n)*PMF_EPS_FOR_K_256)
+	assert.InDelta(t, 0.5, sk.GetRank(float64(n)/2), PMF_EPS_FOR_K_256)
+	assert.True(t, math.IsNaN(sk.GetQuantile(1.5)))
+
+	assert.Equal(t, sk.GetQuantile(0.5), sk.GetP50())
+	assert.Equal(t, sk.GetQuantile(0.9), sk.GetPercentile(90))
+	assert.InDelta(t, 1, sk.GetPercentile(0), float64(n)*PMF_EPS_FOR_K_256)
+	assert.Equal(t, float64(n), sk.GetPercentile(100))
+	for _, c := range []struct{ p, actual float64 }{{90, sk.GetP90()}, {95, sk.GetP95()}, {99, sk.GetP99()}, {99.9, sk.GetP999()}} {
+		assert.InDelta(t, float64(n)*c.p/100, c.actual, float64(n)*PMF_EPS_FOR_K_256, "p%v", c.p)
+	}
+	assert.True(t, math.IsNaN(sk.GetPercentile(-1)))
+	assert.True(t, math.IsNaN(sk.GetPercentile(101)))
+
+	cdf := sk.GetCDF([]float64{float64(n) / 4, float64(n) / 2})
+	assert.Equal(t, 3, len(cdf))
+	assert.InDelta(t, 0.25, cdf[0], PMF_EPS_FOR_K_256)
+	assert.InDelta(t, 0.5, cdf[1], PMF_EPS_FOR_K_256)
+	assert.Equal(t, 1.0, cdf[2])
+
+	pmf := sk.GetPMF([]float64{float64(n) / 4, float64(n) / 2})
+	assert.Equal(t, 3, len(pmf))
+	assert.InDelta(t, 0.25, pmf[0], PMF_EPS_FOR_K_256)
+	assert.InDelta(t, 0.25, pmf[1], PMF_EPS_FOR_K_256)
+	assert.InDelta(t, 0.5, pmf[2], PMF_EPS_FOR_K_256)
+
+	assert.Nil(t, sk.GetCDF([]float64{2, 1}))
+}
+
+func TestDoublesSketch_Merge(t *testing.T) {
+	sk1, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	sk2, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	n := 10000
+	for i := 0; i < n; i++ {
+		sk1.Update(float64(i))
+		sk2.Update(float64(2*n - i - 1))
+	}
+	assert.NoError(t, sk1.Merge(sk2))
+	assert.Error(t, sk1.Merge(nil))
+	assert.Equal(t, uint64(2*n), sk1.GetN())
+	assert.Equal(t, 0.0, sk1.GetMinItem())
+	assert.Equal(t, float64(2*n-1), sk1.GetMaxItem())
+	assert.InDelta(t, float64(n), sk1.GetQuantile(0.5), float64(n)*PMF_EPS_FOR_K_256)
+}
+
+func TestDoublesSketch_SerializeDeserialize(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 1000} {
+		sk, err := NewDoublesSketch(200)
+		assert.NoError(t, err)
+		for i := 1; i <= n; i++ {
+			sk.Update(float64(i))
+		}
+		sl, err := sk.ToSlice()
+		assert.NoError(t, err)
+		sk2, err := NewDoublesSketchFromSlice(sl)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetN(), sk2.GetN())
+		assert.Equal(t, sk.GetNumRetained(), sk2.GetNumRetained())
+		sl2, err := sk2.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, sl, sl2)
+		if n > 0 {
+			assert.Equal(t, sk.GetMinItem(), sk2.GetMinItem())
+			assert.Equal(t, sk.GetMaxItem(), sk2.GetMaxItem())
+			assert.Equal(t, sk.GetQuantile(0.5), sk2.GetQuantile(0.5))
+		}
+	}
+}
+
+func TestDoublesSketch_JavaCompat(t *testing.T) {
+	nArr := []int{0, 1, 10, 100, 1000, 10000, 100000, 1000000}
+	for _, n := range nArr {
+		bytes, err := os.ReadFile(fmt.Sprintf("%s/kll_double_n%d_java.sk", internal.JavaPath, n))
+		assert.NoError(t, err)
+		sk, err := NewDoublesSketchFromSlice(bytes)
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(200), sk.GetK())
+		assert.Equal(t, uint64(n), sk.GetN())
+		if n > 0 {
+			assert.Equal(t, 1.0, sk.GetMinItem())
+			assert.Equal(t, float64(n), sk.GetMaxItem())
+		}
+		sl, err := sk.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, bytes, sl)
+	}
+}
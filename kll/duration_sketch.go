@@ -0,0 +1,189 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+// durationSerDe serializes each duration as its int64 number of nanoseconds with common.ItemSketchLongSerDe.
+type durationSerDe struct {
+	longs common.ItemSketchLongSerDe
+}
+
+func durationComparator(a, b time.Duration) bool {
+	return a < b
+}
+
+func (f durationSerDe) SizeOf(item time.Duration) int {
+	return f.longs.SizeOf(int64(item))
+}
+
+func (f durationSerDe) SizeOfMany(mem []byte, offsetBytes int, numItems int) (int, error) {
+	return f.longs.SizeOfMany(mem, offsetBytes, numItems)
+}
+
+func (f durationSerDe) SerializeOneToSlice(item time.Duration) []byte {
+	return f.longs.SerializeOneToSlice(int64(item))
+}
+
+func (f durationSerDe) SerializeManyToSlice(items []time.Duration) []byte {
+	nanos := make([]int64, len(items))
+	for i, item := range items {
+		nanos[i] = int64(item)
+	}
+	return f.longs.SerializeManyToSlice(nanos)
+}
+
+func (f durationSerDe) DeserializeManyFromSlice(mem []byte, offsetBytes int, numItems int) ([]time.Duration, error) {
+	// ItemSketchLongSerDe does not check the bounds of mem
+	if offsetBytes < 0 || len(mem) < offsetBytes+numItems*8 {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d, %d", len(mem), offsetBytes+numItems*8)
+	}
+	nanos, err := f.longs.DeserializeManyFromSlice(mem, offsetBytes, numItems)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]time.Duration, numItems)
+	for i, n := range nanos {
+		items[i] = time.Duration(n)
+	}
+	return items, nil
+}
+
+// DurationSketch is a KLL sketch of time.Duration items, typically latencies.
+// It wraps an ItemsSketch[time.Duration] with the natural ordering of durations, and serializes
+// every duration as an int64 number of nanoseconds.
+// All the queries use the INCLUSIVE search criterion.
+type DurationSketch struct {
+	sketch *ItemsSketch[time.Duration]
+}
+
+// NewDurationSketch creates a new empty DurationSketch with the given k and the default m.
+func NewDurationSketch(k uint16) (*DurationSketch, error) {
+	sketch, err := NewKllItemsSketch[time.Duration](k, _DEFAULT_M, durationComparator, durationSerDe{})
+	if err != nil {
+		return nil, err
+	}
+	return &DurationSketch{sketch: sketch}, nil
+}
+
+// NewDurationSketchFromSlice creates a DurationSketch from the bytes returned by ToSlice.
+func NewDurationSketchFromSlice(sl []byte) (*DurationSketch, error) {
+	sketch, err := NewKllItemsSketchFromSlice[time.Duration](sl, durationComparator, durationSerDe{})
+	if err != nil {
+		return nil, err
+	}
+	return &DurationSketch{sketch: sketch}, nil
+}
+
+// IsEmpty returns true if the sketch has not seen any duration.
+func (s *DurationSketch) IsEmpty() bool {
+	return s.sketch.IsEmpty()
+}
+
+// GetN returns the number of durations presented to the sketch.
+func (s *DurationSketch) GetN() uint64 {
+	return s.sketch.GetN()
+}
+
+// GetK returns the configured k of the sketch.
+func (s *DurationSketch) GetK() uint16 {
+	return s.sketch.GetK()
+}
+
+// Update presents a duration to the sketch.
+func (s *DurationSketch) Update(d time.Duration) {
+	s.sketch.Update(d)
+}
+
+// GetQuantile returns the approximate duration at the given normalized rank.
+func (s *DurationSketch) GetQuantile(rank float64) (time.Duration, error) {
+	return s.sketch.GetQuantile(rank, true)
+}
+
+// GetRank returns the approximate normalized rank of the given duration.
+func (s *DurationSketch) GetRank(d time.Duration) (float64, error) {
+	return s.sketch.GetRank(d, true)
+}
+
+// GetPMF returns the approximate fraction of the durations in each of the intervals delimited by splits.
+// See ItemsSketch.GetPMF.
+func (s *DurationSketch) GetPMF(splits []time.Duration) ([]float64, error) {
+	return s.sketch.GetPMF(splits, true)
+}
+
+// GetCDF returns the approximate fraction of the durations up to each of the splits.
+// See ItemsSketch.GetCDF.
+func (s *DurationSketch) GetCDF(splits []time.Duration) ([]float64, error) {
+	return s.sketch.GetCDF(splits, true)
+}
+
+// GetSketch returns the underlying ItemsSketch, for the queries not exposed by DurationSketch.
+func (s *DurationSketch) GetSketch() *ItemsSketch[time.Duration] {
+	return s.sketch
+}
+
+// Merge merges other into this sketch.
+func (s *DurationSketch) Merge(other *DurationSketch) error {
+	if other == nil {
+		return errors.New("no sketch provided")
+	}
+	s.sketch.Merge(other.sketch)
+	return nil
+}
+
+// MergeCtx merges other into this sketch unless ctx is done first. See ItemsSketch.MergeCtx.
+func (s *DurationSketch) MergeCtx(ctx context.Context, other *DurationSketch) error {
+	if other == nil {
+		return errors.New("no sketch provided")
+	}
+	return s.sketch.MergeCtx(ctx, other.sketch)
+}
+
+// Reset resets the sketch to its empty state.
+func (s *DurationSketch) Reset() {
+	s.sketch.Reset()
+}
+
+// ToSlice returns the serialized sketch, in the format of an ItemsSketch of int64 nanoseconds.
+// See ItemsSketch.ToSlice.
+func (s *DurationSketch) ToSlice() ([]byte, error) {
+	return s.sketch.ToSlice()
+}
+
+// GobEncode implements gob.GobEncoder with the bytes returned by ToSlice.
+func (s *DurationSketch) GobEncode() ([]byte, error) {
+	return s.ToSlice()
+}
+
+// GobDecode implements gob.GobDecoder, replacing the state of the sketch with the one serialized in data.
+// It can decode into a zero DurationSketch.
+func (s *DurationSketch) GobDecode(data []byte) error {
+	sketch, err := NewDurationSketchFromSlice(data)
+	if err != nil {
+		return err
+	}
+	s.sketch = sketch.sketch
+	return nil
+}
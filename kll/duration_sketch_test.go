@@ -0,0 +1,105 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurationSketch(t *testing.T) {
+	_, err := NewDurationSketch(1)
+	assert.Error(t, err)
+
+	sk, err := NewDurationSketch(200)
+	assert.NoError(t, err)
+	assert.True(t, sk.IsEmpty())
+	_, err = sk.GetQuantile(0.5)
+	assert.Error(t, err)
+
+	for i := 1; i <= 100; i++ {
+		sk.Update(time.Duration(i) * time.Millisecond)
+	}
+	assert.Equal(t, uint64(100), sk.GetN())
+	q, err := sk.GetQuantile(0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, q)
+	rank, err := sk.GetRank(25 * time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, rank)
+
+	pmf, err := sk.GetPMF([]time.Duration{10 * time.Millisecond, 90 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0.1, 0.8, 0.1}, pmf, 1e-12)
+	cdf, err := sk.GetCDF([]time.Duration{10 * time.Millisecond, 90 * time.Millisecond})
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0.1, 0.9, 1.0}, cdf, 1e-12)
+
+	other, err := NewDurationSketch(200)
+	assert.NoError(t, err)
+	for i := 101; i <= 200; i++ {
+		other.Update(time.Duration(i) * time.Millisecond)
+	}
+	assert.NoError(t, sk.Merge(other))
+	assert.Error(t, sk.Merge(nil))
+	assert.Equal(t, uint64(200), sk.GetN())
+	q, err = sk.GetQuantile(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 200*time.Millisecond, q)
+
+	sk.Reset()
+	assert.True(t, sk.IsEmpty())
+}
+
+func TestDurationSketchSerialization(t *testing.T) {
+	sk, err := NewDurationSketch(200)
+	assert.NoError(t, err)
+
+	// empty, single item and estimation mode
+	for _, n := range []int{0, 1, 100000} {
+		sk.Reset()
+		for i := 0; i < n; i++ {
+			sk.Update(time.Duration(i) * time.Microsecond)
+		}
+		sl, err := sk.ToSlice()
+		assert.NoError(t, err)
+		sk2, err := NewDurationSketchFromSlice(sl)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetN(), sk2.GetN())
+		sl2, err := sk2.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, sl, sl2)
+		if n > 0 {
+			q1, _ := sk.GetQuantile(0.5)
+			q2, _ := sk2.GetQuantile(0.5)
+			assert.Equal(t, q1, q2)
+		}
+
+		// the durations are stored as int64 nanoseconds
+		longs, err := NewKllItemsSketchFromSlice[int64](sl, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{})
+		assert.NoError(t, err)
+		if n > 0 {
+			q1, _ := sk.GetQuantile(0.5)
+			q2, _ := longs.GetQuantile(0.5, true)
+			assert.Equal(t, int64(q1), q2)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+// FuzzDeserialize checks that NewKllItemsSketchFromSlice never panics, with fixed and variable size items:
+// it must either return an error or a sketch that can be queried and serialized.
+func FuzzDeserialize(f *testing.F) {
+	for _, n := range []int{0, 1, 10, 1000} {
+		doubles, err := NewKllItemsSketch[float64](20, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+		if err != nil {
+			f.Fatal(err)
+		}
+		strs, err := NewKllItemsSketch[string](20, _DEFAULT_M, common.ItemSketchStringComparator(false), common.ItemSketchStringSerDe{})
+		if err != nil {
+			f.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			doubles.Update(float64(i))
+			strs.Update(strconv.Itoa(i))
+		}
+		for _, sk := range []interface{ ToSlice() ([]byte, error) }{doubles, strs} {
+			sl, err := sk.ToSlice()
+			if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(sl)
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = PeekPreamble(data)
+		if sk, err := NewKllItemsSketchFromSlice[float64](data, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{}); err == nil {
+			checkFuzzedSketch(sk)
+		}
+		if sk, err := NewKllItemsSketchFromSlice[string](data, common.ItemSketchStringComparator(false), common.ItemSketchStringSerDe{}); err == nil {
+			checkFuzzedSketch(sk)
+		}
+	})
+}
+
+func checkFuzzedSketch[C comparable](sk *ItemsSketch[C]) {
+	_, _ = sk.GetQuantile(0.5, true)
+	_, _ = sk.GetMinItem()
+	_, _ = sk.GetMaxItem()
+	_ = sk.GetTotalItemsArray()
+	_, _ = sk.ToSlice()
+}
@@ -0,0 +1,63 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import "math"
+
+// BucketCount is a bucket of a histogram, with the approximate number of items
+// greater than the UpperBound of the previous bucket and less than or equal to its own.
+type BucketCount[C comparable] struct {
+	UpperBound  C
+	ApproxCount uint64
+}
+
+// ToHistogram returns the approximate counts of the items in the buckets delimited by the given upper bounds,
+// which must be unique and monotonically increasing, as for GetCDF.
+// The counts are derived from the INCLUSIVE CDF, count[i] = (CDF[i] - CDF[i-1]) * N, rounded so that they sum to N.
+// The result has one more bucket than the given bounds, with the max item as its UpperBound,
+// for the items above the last bound.
+func (s *ItemsSketch[C]) ToHistogram(buckets []C) ([]BucketCount[C], error) {
+	cdf, err := s.GetCDF(buckets, true)
+	if err != nil {
+		return nil, err
+	}
+	maxItem, err := s.GetMaxItem()
+	if err != nil {
+		return nil, err
+	}
+	n := float64(s.GetN())
+	result := make([]BucketCount[C], len(cdf))
+	prev := uint64(0)
+	for i, rank := range cdf {
+		cumulative := uint64(math.Round(rank * n))
+		if i < len(buckets) {
+			result[i].UpperBound = buckets[i]
+		} else {
+			result[i].UpperBound = maxItem
+		}
+		result[i].ApproxCount = cumulative - prev
+		prev = cumulative
+	}
+	return result, nil
+}
+
+// ToHistogram returns the approximate counts of the items in the buckets delimited by the given upper bounds.
+// See ItemsSketch.ToHistogram.
+func (s *DoublesSketch) ToHistogram(buckets []float64) ([]BucketCount[float64], error) {
+	return s.sketch.ToHistogram(buckets)
+}
@@ -0,0 +1,77 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToHistogram(t *testing.T) {
+	sk, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	_, err = sk.ToHistogram([]float64{1})
+	assert.Error(t, err)
+
+	// exact mode
+	for i := 1; i <= 100; i++ {
+		sk.Update(float64(i))
+	}
+	hist, err := sk.ToHistogram([]float64{10, 50, 90})
+	assert.NoError(t, err)
+	assert.Equal(t, []BucketCount[float64]{
+		{UpperBound: 10, ApproxCount: 10},
+		{UpperBound: 50, ApproxCount: 40},
+		{UpperBound: 90, ApproxCount: 40},
+		{UpperBound: 100, ApproxCount: 10},
+	}, hist)
+
+	_, err = sk.ToHistogram([]float64{50, 10})
+	assert.Error(t, err)
+
+	// estimation mode
+	for i := 101; i <= 100000; i++ {
+		sk.Update(float64(i))
+	}
+	hist, err = sk.ToHistogram([]float64{25000, 50000, 75000})
+	assert.NoError(t, err)
+	assert.Len(t, hist, 4)
+	total := uint64(0)
+	for _, b := range hist {
+		assert.InDelta(t, 25000, b.ApproxCount, 100000*0.02)
+		total += b.ApproxCount
+	}
+	assert.Equal(t, uint64(100000), total)
+}
+
+func TestItemsSketchToHistogram(t *testing.T) {
+	sk, err := NewKllItemsSketch[string](200, _DEFAULT_M, common.ItemSketchStringComparator(false), common.ItemSketchStringSerDe{})
+	assert.NoError(t, err)
+	for _, item := range []string{"a", "b", "b", "c", "d", "d", "d"} {
+		sk.Update(item)
+	}
+	hist, err := sk.ToHistogram([]string{"b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, []BucketCount[string]{
+		{UpperBound: "b", ApproxCount: 3},
+		{UpperBound: "c", ApproxCount: 1},
+		{UpperBound: "d", ApproxCount: 3},
+	}, hist)
+}
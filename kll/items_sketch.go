@@ -27,10 +27,13 @@
 package kll
 
 import (
+	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"github.com/apache/datasketches-go/common"
 	"github.com/apache/datasketches-go/internal"
+	"io"
 	"math/rand"
 	"sort"
 )
@@ -52,9 +55,8 @@ type ItemsSketch[C comparable] struct {
 	sortedView        *ItemsSketchSortedView[C]
 	serde             common.ItemSketchSerde[C]
 	compareFn         common.CompareFn[C]
-
-	// Force deterministic offset for test, so that we can compare results across implementation.
-	deterministicOffsetForTest bool
+	options           itemsSketchOptions
+	numCompactions    uint64 // see KllDiagnostics, not serialized
 }
 
 const (
@@ -64,6 +66,10 @@ const (
 	_MAX_K     = (1 << 16) - 1
 	_MIN_M     = 2 //The minimum M
 	_MAX_M     = 8 //The maximum M
+
+	_MAX_NUM_LEVELS = 61 // beyond 61 levels the capacity of the levels cannot be computed, and n would overflow
+
+	_MERGE_CTX_CHECK_INTERVAL = 1024 // number of level 0 items merged between two checks of the context
 )
 
 var (
@@ -72,15 +78,13 @@ var (
 		3486784401, 10460353203, 31381059609, 94143178827, 282429536481,
 		847288609443, 2541865828329, 7625597484987, 22876792454961, 68630377364883,
 		205891132094649}
-
-	// Used for deterministic rand behavior in tests
-	nextOffsetForTest = 0
 )
 
 // NewKllItemsSketch create a new ItemsSketch with the given k and m.
 // The default k = 200 results in a normalized rank error of about 1.65%.
 // Larger K will have smaller error but the sketch will be larger (and slower).
-func NewKllItemsSketch[C comparable](k uint16, m uint8, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C]) (*ItemsSketch[C], error) {
+// Optional behaviour, such as the compaction strategy, is set with opts.
+func NewKllItemsSketch[C comparable](k uint16, m uint8, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C], opts ...ItemsSketchOption) (*ItemsSketch[C], error) {
 	if k < _MIN_K || k > _MAX_K {
 		return nil, fmt.Errorf("k must be >= %d and <= %d: %d", _MIN_K, _MAX_K, k)
 	}
@@ -96,17 +100,39 @@ func NewKllItemsSketch[C comparable](k uint16, m uint8, compareFn common.Compare
 		items:     make([]C, k),
 		serde:     serde,
 		compareFn: compareFn,
+		options:   newItemsSketchOptions(opts),
 	}, nil
 }
 
 // NewKllItemsSketchWithDefault create a new ItemsSketch with default k and m.
 // The default k = 200 results in a normalized rank error of about 1.65%.
-func NewKllItemsSketchWithDefault[C comparable](compareFn common.CompareFn[C], serde common.ItemSketchSerde[C]) (*ItemsSketch[C], error) {
-	return NewKllItemsSketch[C](_DEFAULT_K, _DEFAULT_M, compareFn, serde)
+func NewKllItemsSketchWithDefault[C comparable](compareFn common.CompareFn[C], serde common.ItemSketchSerde[C], opts ...ItemsSketchOption) (*ItemsSketch[C], error) {
+	return NewKllItemsSketch[C](_DEFAULT_K, _DEFAULT_M, compareFn, serde, opts...)
+}
+
+// Downsample returns a new sketch with the smaller newK, holding the content of src.
+// The retained items of src are compacted, with their weights, into the new sketch as by a merge,
+// so the result stays unbiased and its rank error is the one of newK.
+// The m, compare function, serde and options of src are retained, and src is not modified.
+func Downsample[C comparable](src *ItemsSketch[C], newK uint16) (*ItemsSketch[C], error) {
+	if src == nil {
+		return nil, fmt.Errorf("no sketch provided")
+	}
+	if newK < _MIN_K || newK >= src.k {
+		return nil, fmt.Errorf("newK must be >= %d and < %d: %d", _MIN_K, src.k, newK)
+	}
+	sketch, err := NewKllItemsSketch[C](newK, src.m, src.compareFn, src.serde)
+	if err != nil {
+		return nil, err
+	}
+	sketch.options = src.options
+	sketch.Merge(src)
+	return sketch, nil
 }
 
 // NewKllItemsSketchFromSlice create a new ItemsSketch from the given byte slice (serialized sketch).
-func NewKllItemsSketchFromSlice[C comparable](sl []byte, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C]) (*ItemsSketch[C], error) {
+// The options are not serialized, they are set again with opts.
+func NewKllItemsSketchFromSlice[C comparable](sl []byte, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C], opts ...ItemsSketchOption) (*ItemsSketch[C], error) {
 	if serde == nil {
 		return nil, fmt.Errorf("no SerDe provided")
 	}
@@ -184,9 +210,102 @@ func NewKllItemsSketchFromSlice[C comparable](sl []byte, compareFn common.Compar
 		maxItem:           maxItem,
 		serde:             serde,
 		compareFn:         compareFn,
+		options:           newItemsSketchOptions(opts),
 	}, nil
 }
 
+// NewItemsSketchFromReader create a new ItemsSketch from a serialized sketch read from r.
+// The byte layout is the one produced by ToSlice and WriteTo. Exactly the bytes of one sketch are read,
+// so several sketches written one after the other to the same stream can be read back in turn.
+// The size of variable length items is found from the serde, which may issue small reads, so r should be buffered.
+func NewItemsSketchFromReader[C comparable](r io.Reader, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C]) (*ItemsSketch[C], error) {
+	if serde == nil {
+		return nil, fmt.Errorf("no SerDe provided")
+	}
+	sl, err := readItemsSketchBytes[C](r, serde)
+	if err != nil {
+		return nil, err
+	}
+	return NewKllItemsSketchFromSlice[C](sl, compareFn, serde)
+}
+
+// readItemsSketchBytes reads the preamble and the levels array, which give the number of serialized items,
+// then reads the items one at a time until their size is known to the serde.
+func readItemsSketchBytes[C comparable](r io.Reader, serde common.ItemSketchSerde[C]) ([]byte, error) {
+	sl, err := readSketchBytes(r, nil, _DATA_START_ADR_SINGLE_ITEM)
+	if err != nil {
+		return nil, err
+	}
+	structure, err := getSketchStructure(getPreInts(sl), getSerVer(sl))
+	if err != nil {
+		return nil, err
+	}
+	numItems := 0
+	switch structure {
+	case _COMPACT_EMPTY:
+		return sl, nil
+	case _COMPACT_SINGLE:
+		numItems = 1
+	case _COMPACT_FULL:
+		k := getK(sl)
+		m := getM(sl)
+		if err := checkM(m); err != nil {
+			return nil, err
+		}
+		if err := checkK(k, m); err != nil {
+			return nil, err
+		}
+		if sl, err = readSketchBytes(r, sl, _DATA_START_ADR); err != nil {
+			return nil, err
+		}
+		numLevels := getNumLevels(sl)
+		if numLevels == 0 || numLevels > _MAX_NUM_LEVELS {
+			return nil, fmt.Errorf("Invalid number of levels: %d", numLevels)
+		}
+		if sl, err = readSketchBytes(r, sl, _DATA_START_ADR+int(numLevels)*4); err != nil {
+			return nil, err
+		}
+		capacityItems := computeTotalItemCapacity(k, m, numLevels)
+		levelZero := binary.LittleEndian.Uint32(sl[_DATA_START_ADR : _DATA_START_ADR+4])
+		if levelZero >= capacityItems {
+			return nil, fmt.Errorf("Invalid number of retained items: %d", int64(capacityItems)-int64(levelZero))
+		}
+		numItems = int(capacityItems-levelZero) + 2 // 2 for min & max
+	default:
+		return nil, fmt.Errorf("Invalid preamble ints and serial version combo")
+	}
+	for i := 0; i < numItems; i++ {
+		offset := len(sl)
+		for {
+			size, err := serde.SizeOfMany(sl, offset, 1)
+			if err == nil {
+				if sl, err = readSketchBytes(r, sl, offset+size); err != nil {
+					return nil, err
+				}
+				break
+			}
+			// the serde needs more bytes to tell the size of the item
+			if sl, err = readSketchBytes(r, sl, len(sl)+1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sl, nil
+}
+
+// readSketchBytes grows sl to n bytes with bytes read from r.
+func readSketchBytes(r io.Reader, sl []byte, n int) ([]byte, error) {
+	if n <= len(sl) {
+		return sl, nil
+	}
+	start := len(sl)
+	sl = append(sl, make([]byte, n-start)...)
+	if _, err := io.ReadFull(r, sl[start:]); err != nil {
+		return nil, fmt.Errorf("reading sketch: %w", err)
+	}
+	return sl, nil
+}
+
 // IsEmpty returns true if the sketch is empty, otherwise false.
 func (s *ItemsSketch[C]) IsEmpty() bool {
 	return s.n == 0
@@ -300,14 +419,7 @@ func (s *ItemsSketch[C]) GetQuantiles(ranks []float64, inclusive bool) ([]C, err
 	if err != nil {
 		return nil, err
 	}
-	quantiles := make([]C, len(ranks))
-	for i := range ranks {
-		quantiles[i], err = s.sortedView.GetQuantile(ranks[i], inclusive)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return quantiles, nil
+	return s.sortedView.GetQuantiles(ranks, inclusive)
 }
 
 // GetPMF returns an approximation to the Probability Mass Function (PMF) of the input stream
@@ -400,6 +512,66 @@ func (s *ItemsSketch[C]) GetNormalizedRankError(pmf bool) float64 {
 	return getNormalizedRankError(s.minK, pmf)
 }
 
+// GetRankLowerBound returns the lower bound of the normalized rank of the given item, using the
+// INCLUSIVE search criterion. The bound is the rank minus numStdDev times the single-sided
+// normalized rank error, clamped to 0.
+//
+//   - numStdDev, this must be an integer between 1 and 3, inclusive.
+func (s *ItemsSketch[C]) GetRankLowerBound(item C, numStdDev int) (float64, error) {
+	if err := checkNumStdDev(numStdDev); err != nil {
+		return 0, err
+	}
+	rank, err := s.GetRank(item, true)
+	if err != nil {
+		return 0, err
+	}
+	return max(0.0, rank-float64(numStdDev)*s.GetNormalizedRankError(false)), nil
+}
+
+// GetRankUpperBound returns the upper bound of the normalized rank of the given item, using the
+// INCLUSIVE search criterion. The bound is the rank plus numStdDev times the single-sided
+// normalized rank error, clamped to 1.
+//
+//   - numStdDev, this must be an integer between 1 and 3, inclusive.
+func (s *ItemsSketch[C]) GetRankUpperBound(item C, numStdDev int) (float64, error) {
+	if err := checkNumStdDev(numStdDev); err != nil {
+		return 0, err
+	}
+	rank, err := s.GetRank(item, true)
+	if err != nil {
+		return 0, err
+	}
+	return min(1.0, rank+float64(numStdDev)*s.GetNormalizedRankError(false)), nil
+}
+
+// GetQuantileLowerBound returns the quantile of the given normalized rank minus numStdDev times the
+// single-sided normalized rank error, using the INCLUSIVE search criterion.
+//
+//   - numStdDev, this must be an integer between 1 and 3, inclusive.
+func (s *ItemsSketch[C]) GetQuantileLowerBound(rank float64, numStdDev int) (C, error) {
+	if err := checkNumStdDev(numStdDev); err != nil {
+		return *new(C), err
+	}
+	if err := checkNormalizedRankBounds(rank); err != nil {
+		return *new(C), err
+	}
+	return s.GetQuantile(max(0.0, rank-float64(numStdDev)*s.GetNormalizedRankError(false)), true)
+}
+
+// GetQuantileUpperBound returns the quantile of the given normalized rank plus numStdDev times the
+// single-sided normalized rank error, using the INCLUSIVE search criterion.
+//
+//   - numStdDev, this must be an integer between 1 and 3, inclusive.
+func (s *ItemsSketch[C]) GetQuantileUpperBound(rank float64, numStdDev int) (C, error) {
+	if err := checkNumStdDev(numStdDev); err != nil {
+		return *new(C), err
+	}
+	if err := checkNormalizedRankBounds(rank); err != nil {
+		return *new(C), err
+	}
+	return s.GetQuantile(min(1.0, rank+float64(numStdDev)*s.GetNormalizedRankError(false)), true)
+}
+
 // GetPartitionBoundaries returns an instance of ItemsSketchPartitionBoundaries
 // which provides sufficient information for the user to create the given number of equally sized partitions,
 // where "equally sized" refers to an approximately equal number of items per partition.
@@ -452,13 +624,40 @@ func (s *ItemsSketch[C]) Merge(other *ItemsSketch[C]) {
 	if other.IsEmpty() {
 		return
 	}
-	s.mergeItemsSketch(other)
+	// the merge can only fail on a cancelled context
+	_ = s.mergeItemsSketch(context.Background(), other)
 	s.sortedView = nil
 }
 
+// MergeCtx merges the given sketch into this sketch, like Merge, unless ctx is done first.
+// The merge is done on a copy of this sketch, and ctx is checked while the items of other are
+// being merged. If ctx is done, ctx.Err() is returned and this sketch is left unchanged.
+func (s *ItemsSketch[C]) MergeCtx(ctx context.Context, other *ItemsSketch[C]) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if other == nil {
+		return fmt.Errorf("no sketch provided")
+	}
+	if other.IsEmpty() {
+		return nil
+	}
+	tmp := *s
+	tmp.levels = append([]uint32(nil), s.levels...)
+	tmp.items = append([]C(nil), s.items...)
+	if err := tmp.mergeItemsSketch(ctx, other); err != nil {
+		return err
+	}
+	tmp.sortedView = nil
+	*s = tmp
+	return nil
+}
+
 // Reset this sketch to the empty state.
+// The configured k, m and compare function are retained, so the sketch behaves as a freshly constructed one.
 func (s *ItemsSketch[C]) Reset() {
 	s.n = 0
+	s.minK = s.k
 	s.isLevelZeroSorted = false
 	s.numLevels = 1
 	s.levels = []uint32{uint32(s.k), uint32(s.k)}
@@ -466,6 +665,18 @@ func (s *ItemsSketch[C]) Reset() {
 	s.maxItem = nil
 	s.items = make([]C, s.k)
 	s.sortedView = nil
+	s.numCompactions = 0
+}
+
+// ToSliceWith returns the serialized byte array of this sketch with the items serialized by the given serde
+// instead of the one of the sketch, which is left unchanged. The result is deserialized with the same serde.
+func (s *ItemsSketch[C]) ToSliceWith(serde common.ItemSketchSerde[C]) ([]byte, error) {
+	if serde == nil {
+		return nil, fmt.Errorf("no SerDe provided")
+	}
+	tmp := *s
+	tmp.serde = serde
+	return tmp.ToSlice()
 }
 
 // ToSlice returns the serialized byte array of this sketch.
@@ -491,16 +702,7 @@ func (s *ItemsSketch[C]) ToSlice() ([]byte, error) {
 	preInts := byte(tgtStructure.getPreInts())
 	serVer := byte(tgtStructure.getSerVer())
 	famId := byte(internal.FamilyEnum.Kll.Id)
-	flags := byte(0)
-	if s.IsEmpty() {
-		flags |= _EMPTY_BIT_MASK
-	}
-	if s.isLevelZeroSorted {
-		flags |= _LEVEL_ZERO_SORTED_BIT_MASK
-	}
-	if s.n == 1 {
-		flags |= _SINGLE_ITEM_BIT_MASK
-	}
+	flags := s.getFlags()
 	k := uint16(s.k)
 	m := uint8(s.m)
 
@@ -546,7 +748,92 @@ func (s *ItemsSketch[C]) ToSlice() ([]byte, error) {
 	return bytesOut, nil
 }
 
+// WriteTo writes the serialized sketch to w, without first building the whole byte array in memory.
+// The bytes written are identical to the ones returned by ToSlice.
+func (s *ItemsSketch[C]) WriteTo(w io.Writer) (int64, error) {
+	if s.serde == nil {
+		return 0, fmt.Errorf("no SerDe provided")
+	}
+	var written int64
+	write := func(b []byte) error {
+		n, err := w.Write(b)
+		written += int64(n)
+		if err != nil {
+			return fmt.Errorf("writing sketch: %w", err)
+		}
+		return nil
+	}
+
+	var tgtStructure = _COMPACT_FULL
+	if s.n == 0 {
+		tgtStructure = _COMPACT_EMPTY
+	} else if s.n == 1 {
+		tgtStructure = _COMPACT_SINGLE
+	}
+
+	preamble := make([]byte, _DATA_START_ADR_SINGLE_ITEM, _DATA_START_ADR+int(s.numLevels)*4)
+	preamble[0] = byte(tgtStructure.getPreInts())
+	preamble[1] = byte(tgtStructure.getSerVer())
+	preamble[2] = byte(internal.FamilyEnum.Kll.Id)
+	preamble[3] = s.getFlags()
+	binary.LittleEndian.PutUint16(preamble[4:6], s.k)
+	preamble[6] = s.m
+
+	if tgtStructure == _COMPACT_EMPTY {
+		return written, write(preamble)
+	}
+
+	if tgtStructure == _COMPACT_SINGLE {
+		siByteArr, err := s.getSingleItemByteArr()
+		if err != nil {
+			return written, err
+		}
+		if err := write(preamble); err != nil {
+			return written, err
+		}
+		return written, write(siByteArr)
+	}
+
+	preamble = preamble[:_DATA_START_ADR+int(s.numLevels)*4]
+	binary.LittleEndian.PutUint64(preamble[8:16], s.n)
+	binary.LittleEndian.PutUint16(preamble[16:18], s.minK)
+	preamble[18] = s.numLevels
+	for i := uint8(0); i < s.numLevels; i++ {
+		binary.LittleEndian.PutUint32(preamble[_DATA_START_ADR+int(i)*4:], s.levels[i])
+	}
+	if err := write(preamble); err != nil {
+		return written, err
+	}
+	if err := write(s.getMinMaxByteArr()); err != nil {
+		return written, err
+	}
+	return written, write(s.getRetainedItemsByteArr())
+}
+
+// GobEncode implements gob.GobEncoder with the bytes returned by ToSlice.
+func (s *ItemsSketch[C]) GobEncode() ([]byte, error) {
+	return s.ToSlice()
+}
+
+// GobDecode implements gob.GobDecoder, replacing the state of the sketch with the one serialized in data.
+// The compare function and serde cannot be encoded, so the sketch must have been created with them,
+// for instance with NewKllItemsSketch, before decoding into it. Its options are retained.
+func (s *ItemsSketch[C]) GobDecode(data []byte) error {
+	if s.serde == nil {
+		return fmt.Errorf("no SerDe provided")
+	}
+	sketch, err := NewKllItemsSketchFromSlice[C](data, s.compareFn, s.serde)
+	if err != nil {
+		return err
+	}
+	sketch.options = s.options
+	*s = *sketch
+	return nil
+}
+
 // GetSerializedSizeBytes Returns the current number of bytes this Sketch would require if serialized in compact form.
+// The size is computed from the SizeOf of the retained items, without serializing them, so it is exact
+// for variable-length items too, at the cost of a pass over the retained items.
 func (s *ItemsSketch[C]) GetSerializedSizeBytes() (int, error) {
 	if s.serde == nil {
 		return 0, fmt.Errorf("no SerDe provided")
@@ -554,6 +841,42 @@ func (s *ItemsSketch[C]) GetSerializedSizeBytes() (int, error) {
 	return s.currentSerializedSizeBytes()
 }
 
+// String returns a JSON summary of the sketch for debugging, not a serialization.
+// The min and max items are JSON encoded when possible, formatted with fmt otherwise,
+// and null for an empty sketch.
+func (s *ItemsSketch[C]) String() string {
+	summary := struct {
+		Type             string `json:"type"`
+		K                uint16 `json:"k"`
+		M                uint8  `json:"m"`
+		NumLevels        int    `json:"num_levels"`
+		N                uint64 `json:"n"`
+		NumRetained      uint32 `json:"num_retained"`
+		MinItem          any    `json:"min_item"`
+		MaxItem          any    `json:"max_item"`
+		IsEmpty          bool   `json:"is_empty"`
+		IsEstimationMode bool   `json:"is_estimation_mode"`
+	}{
+		Type:             "kll",
+		K:                s.k,
+		M:                s.m,
+		NumLevels:        s.getNumLevels(),
+		N:                s.n,
+		NumRetained:      s.GetNumRetained(),
+		IsEmpty:          s.IsEmpty(),
+		IsEstimationMode: s.IsEstimationMode(),
+	}
+	if s.minItem != nil && s.maxItem != nil {
+		summary.MinItem = jsonItem(*s.minItem)
+		summary.MaxItem = jsonItem(*s.maxItem)
+	}
+	out, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Sprintf("%+v", summary)
+	}
+	return string(out)
+}
+
 // GetIterator returns the iterator for this sketch, which is not sorted.
 func (s *ItemsSketch[C]) GetIterator() *ItemsSketchIterator[C] {
 	return newItemsSketchIterator[C](
@@ -567,6 +890,14 @@ func (s *ItemsSketch[C]) GetIterator() *ItemsSketchIterator[C] {
 // Private methods
 //
 
+// jsonItem returns item if it can be encoded to JSON, its fmt formatting otherwise.
+func jsonItem[C comparable](item C) any {
+	if _, err := json.Marshal(item); err != nil {
+		return fmt.Sprint(item)
+	}
+	return item
+}
+
 func (s *ItemsSketch[C]) currentSerializedSizeBytes() (int, error) {
 	srcN := s.n
 	var tgtStructure = _COMPACT_FULL
@@ -594,6 +925,20 @@ func (s *ItemsSketch[C]) currentSerializedSizeBytes() (int, error) {
 	return totalBytes, nil
 }
 
+func (s *ItemsSketch[C]) getFlags() byte {
+	flags := byte(0)
+	if s.IsEmpty() {
+		flags |= _EMPTY_BIT_MASK
+	}
+	if s.isLevelZeroSorted {
+		flags |= _LEVEL_ZERO_SORTED_BIT_MASK
+	}
+	if s.n == 1 {
+		flags |= _SINGLE_ITEM_BIT_MASK
+	}
+	return flags
+}
+
 func (s *ItemsSketch[C]) getNumLevels() int {
 	return len(s.levels) - 1
 }
@@ -663,7 +1008,11 @@ func (s *ItemsSketch[C]) getRetainedItemsByteArr() []byte {
 }
 
 func (s *ItemsSketch[C]) getRetainedItemsSizeBytes() int {
-	return len(s.getRetainedItemsByteArr())
+	size := 0
+	for _, item := range s.items[s.levels[0]:] {
+		size += s.serde.SizeOf(item)
+	}
+	return size
 }
 
 func (s *ItemsSketch[C]) setupSortedView() error {
@@ -704,9 +1053,11 @@ func (s *ItemsSketch[C]) updateItem(item C, compareFn common.CompareFn[C]) {
 	s.items[nextPos] = item
 }
 
-func (s *ItemsSketch[C]) mergeItemsSketch(other *ItemsSketch[C]) {
+// mergeItemsSketch merges other into this sketch, checking ctx every _MERGE_CTX_CHECK_INTERVAL items.
+// When ctx is done the sketch is left partially merged, so callers that can be cancelled merge into a copy.
+func (s *ItemsSketch[C]) mergeItemsSketch(ctx context.Context, other *ItemsSketch[C]) error {
 	if other.IsEmpty() {
-		return
+		return nil
 	}
 	// capture my key mutable fields before doing any merging
 	myEmpty := s.IsEmpty()
@@ -733,6 +1084,11 @@ func (s *ItemsSketch[C]) mergeItemsSketch(other *ItemsSketch[C]) {
 	// MERGE: update this sketch with level0 items from the other sketch
 	otherItemsArr = other.GetTotalItemsArray()
 	for i := otherLevelsArr[0]; i < otherLevelsArr[1]; i++ {
+		if (i-otherLevelsArr[0])%_MERGE_CTX_CHECK_INTERVAL == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
 		s.updateItem(otherItemsArr[i], s.compareFn)
 	}
 
@@ -748,6 +1104,9 @@ func (s *ItemsSketch[C]) mergeItemsSketch(other *ItemsSketch[C]) {
 
 	//merge higher levels if they exist
 	if otherNumLevels > 1 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		tmpSpaceNeeded := s.GetNumRetained() + getNumRetainedAboveLevelZero(otherNumLevels, otherLevelsArr)
 		workbuf := make([]C, tmpSpaceNeeded)
 		ub := ubOnNumLevels(finalN)
@@ -761,7 +1120,7 @@ func (s *ItemsSketch[C]) mergeItemsSketch(other *ItemsSketch[C]) {
 			otherNumLevels, otherLevelsArr, otherItemsArr, s.compareFn)
 
 		// notice that workbuf is being used as both the input and output
-		result := generalItemsCompress(s.k, s.m, provisionalNumLevels, workbuf, worklevels, workbuf, outlevels, s.isLevelZeroSorted, s.compareFn, s.deterministicOffsetForTest)
+		result := generalItemsCompress(s.k, s.m, provisionalNumLevels, workbuf, worklevels, workbuf, outlevels, s.isLevelZeroSorted, s.compareFn, s.selectOffset)
 		targetItemCount := result[1] //was finalCapacity. Max size given k, m, numLevels
 		curItemCount := result[2]    //was finalPop
 
@@ -830,6 +1189,7 @@ func (s *ItemsSketch[C]) mergeItemsSketch(other *ItemsSketch[C]) {
 			s.maxItem = other.maxItem
 		}
 	}
+	return nil
 }
 
 func (s *ItemsSketch[C]) compressWhileUpdatingSketch() {
@@ -867,9 +1227,9 @@ func (s *ItemsSketch[C]) compressWhileUpdatingSketch() {
 		})
 	}
 	if popAbove == 0 {
-		randomlyHalveUpItems(myItemsArr, adjBeg, adjPop, s.deterministicOffsetForTest)
+		randomlyHalveUpItems(myItemsArr, adjBeg, adjPop, s.selectOffset(int(adjPop)))
 	} else {
-		randomlyHalveDownItems(myItemsArr, adjBeg, adjPop, s.deterministicOffsetForTest)
+		randomlyHalveDownItems(myItemsArr, adjBeg, adjPop, s.selectOffset(int(adjPop)))
 		mergeSortedItemsArrays(
 			myItemsArr, adjBeg, halfAdjPop,
 			myItemsArr, rawEnd, popAbove,
@@ -1003,12 +1363,8 @@ func intCapAuxAux(k uint16, depth uint8) uint32 {
 	return uint32(k)
 }
 
-func randomlyHalveUpItems[C comparable](buf []C, start uint32, length uint32, deterministicOffsetForTest bool) {
+func randomlyHalveUpItems[C comparable](buf []C, start uint32, length uint32, offset int) {
 	halfLength := length / 2
-	offset := rand.Intn(2)
-	if deterministicOffsetForTest {
-		offset = deterministicOffset()
-	}
 	j := (start + length) - 1 - uint32(offset)
 	for i := (start + length) - 1; i >= (start + halfLength); i-- {
 		buf[i] = buf[j]
@@ -1016,12 +1372,8 @@ func randomlyHalveUpItems[C comparable](buf []C, start uint32, length uint32, de
 	}
 }
 
-func randomlyHalveDownItems[C comparable](buf []C, start uint32, length uint32, deterministicOffsetForTest bool) {
+func randomlyHalveDownItems[C comparable](buf []C, start uint32, length uint32, offset int) {
 	halfLength := length / 2
-	offset := rand.Intn(2)
-	if deterministicOffsetForTest {
-		offset = deterministicOffset()
-	}
 	j := start + uint32(offset)
 	for i := start; i < (start + halfLength); i++ {
 		buf[i] = buf[j]
@@ -1102,7 +1454,7 @@ func generalItemsCompress[C comparable](
 	outLevels []uint32,
 	isLevelZeroSorted bool,
 	compareFn common.CompareFn[C],
-	deterministicOffsetForTest bool,
+	selectOffset func(levelSize int) int,
 ) []uint32 {
 	numLevels := numLevelsIn
 	currentItemCount := inLevels[numLevels] - inLevels[0]        // decreases with each compaction
@@ -1160,9 +1512,9 @@ func generalItemsCompress[C comparable](
 			}
 
 			if popAbove == 0 {
-				randomlyHalveUpItems(inBuf, adjBeg, adjPop, deterministicOffsetForTest)
+				randomlyHalveUpItems(inBuf, adjBeg, adjPop, selectOffset(int(adjPop)))
 			} else {
-				randomlyHalveDownItems(inBuf, adjBeg, adjPop, deterministicOffsetForTest)
+				randomlyHalveDownItems(inBuf, adjBeg, adjPop, selectOffset(int(adjPop)))
 				mergeSortedItemsArrays(
 					inBuf, adjBeg, halfAdjPop,
 					inBuf, rawLim, popAbove,
@@ -1192,8 +1544,12 @@ func generalItemsCompress[C comparable](
 	return []uint32{uint32(numLevels), targetItemCount, currentItemCount}
 }
 
-func deterministicOffset() int {
-	result := nextOffsetForTest
-	nextOffsetForTest = 1 - nextOffsetForTest
-	return result
+// selectOffset returns the offset of the compaction of a level of levelSize items.
+// It is called once for each compaction, so it also counts them.
+func (s *ItemsSketch[C]) selectOffset(levelSize int) int {
+	s.numCompactions++
+	if s.options.compaction == nil {
+		return rand.Intn(2)
+	}
+	return s.options.compaction.SelectOffset(levelSize, s.options.rng)
 }
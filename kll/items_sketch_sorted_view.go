@@ -21,6 +21,7 @@ import (
 	"errors"
 	"github.com/apache/datasketches-go/common"
 	"github.com/apache/datasketches-go/internal"
+	"iter"
 	"sort"
 )
 
@@ -101,6 +102,67 @@ func (s *ItemsSketchSortedView[C]) GetQuantile(rank float64, inclusive bool) (C,
 	return s.quantiles[index], nil
 }
 
+// GetRanks returns the normalized ranks of the given items, which must be sorted in ascending order,
+// in a single sweep over the sorted view.
+func (s *ItemsSketchSortedView[C]) GetRanks(items []C, inclusive bool) ([]float64, error) {
+	if s.totalN == 0 {
+		return nil, errors.New("empty sketch")
+	}
+	for i := 1; i < len(items); i++ {
+		if s.compareFn(items[i], items[i-1]) {
+			return nil, errors.New("items must be sorted in ascending order")
+		}
+	}
+	ranks := make([]float64, len(items))
+	// index is the number of quantiles that satisfy the criterion for the current item
+	index := 0
+	for i, item := range items {
+		for index < len(s.quantiles) {
+			q := s.quantiles[index]
+			if (inclusive && s.compareFn(item, q)) || (!inclusive && !s.compareFn(q, item)) {
+				break
+			}
+			index++
+		}
+		if index > 0 {
+			ranks[i] = float64(s.cumWeights[index-1]) / float64(s.totalN)
+		}
+	}
+	return ranks, nil
+}
+
+// GetQuantiles returns the quantiles at the given normalized ranks, in the order of the ranks.
+// The ranks are sorted internally, so the sorted view is swept only once.
+func (s *ItemsSketchSortedView[C]) GetQuantiles(ranks []float64, inclusive bool) ([]C, error) {
+	if s.totalN == 0 {
+		return nil, errors.New("empty sketch")
+	}
+	for _, rank := range ranks {
+		if err := checkNormalizedRankBounds(rank); err != nil {
+			return nil, err
+		}
+	}
+	order := make([]int, len(ranks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ranks[order[a]] < ranks[order[b]]
+	})
+	quantiles := make([]C, len(ranks))
+	length := len(s.cumWeights)
+	index := 0
+	for _, i := range order {
+		naturalRank := getNaturalRank(ranks[i], s.totalN, inclusive)
+		// the first cumulative weight >= naturalRank if inclusive, > naturalRank otherwise
+		for index < length && (s.cumWeights[index] < naturalRank || (!inclusive && s.cumWeights[index] == naturalRank)) {
+			index++
+		}
+		quantiles[i] = s.quantiles[min(index, length-1)]
+	}
+	return quantiles, nil
+}
+
 func (s *ItemsSketchSortedView[C]) GetPMF(splitPoints []C, inclusive bool) ([]float64, error) {
 	if s.totalN == 0 {
 		return nil, errors.New("empty sketch")
@@ -143,6 +205,38 @@ func (s *ItemsSketchSortedView[C]) Iterator() *ItemsSketchSortedViewIterator[C]
 	return newItemsSketchSortedViewIterator(s.quantiles, s.cumWeights)
 }
 
+// All returns an iterator over the retained items in ascending order, paired with their
+// INCLUSIVE normalized rank (the fraction of the stream <= the item).
+func (s *ItemsSketchSortedView[C]) All() iter.Seq2[C, float64] {
+	return s.between(0, len(s.quantiles))
+}
+
+// AllBetweenRanks returns an iterator like All, restricted to the items whose
+// INCLUSIVE normalized rank is in [lo, hi]. It yields nothing if lo > hi.
+func (s *ItemsSketchSortedView[C]) AllBetweenRanks(lo, hi float64) iter.Seq2[C, float64] {
+	if s.totalN == 0 || lo > hi {
+		return s.between(0, 0)
+	}
+	totalN := float64(s.totalN)
+	start := sort.Search(len(s.cumWeights), func(i int) bool {
+		return float64(s.cumWeights[i])/totalN >= lo
+	})
+	end := sort.Search(len(s.cumWeights), func(i int) bool {
+		return float64(s.cumWeights[i])/totalN > hi
+	})
+	return s.between(start, end)
+}
+
+func (s *ItemsSketchSortedView[C]) between(start, end int) iter.Seq2[C, float64] {
+	return func(yield func(C, float64) bool) {
+		for i := start; i < end; i++ {
+			if !yield(s.quantiles[i], float64(s.cumWeights[i])/float64(s.totalN)) {
+				return
+			}
+		}
+	}
+}
+
 func (s *ItemsSketchSortedView[C]) getQuantileIndex(rank float64, inclusive bool) int {
 	length := len(s.quantiles)
 	naturalRank := getNaturalRank(rank, s.totalN, inclusive)
@@ -159,6 +253,16 @@ func (s *ItemsSketchSortedView[C]) getQuantileIndex(rank float64, inclusive bool
 	return index
 }
 
+// Reset clears the content of this sorted view, leaving it empty.
+// All queries on a reset view return an error until it is rebuilt from a sketch.
+func (s *ItemsSketchSortedView[C]) Reset() {
+	s.quantiles = nil
+	s.cumWeights = nil
+	s.totalN = 0
+	s.minItem = *new(C)
+	s.maxItem = *new(C)
+}
+
 func (s *ItemsSketchSortedView[C]) GetNumRetained() int {
 	return len(s.quantiles)
 }
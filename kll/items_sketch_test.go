@@ -18,9 +18,13 @@
 package kll
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/apache/datasketches-go/common"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"math"
 	"math/rand"
 	"strconv"
@@ -336,6 +340,62 @@ func TestItemsSketch_Merge(t *testing.T) {
 	assert.True(t, lowerBound < median)
 }
 
+// cancelAfterCtx reports itself as cancelled once Err has been called more than a given number of times.
+type cancelAfterCtx struct {
+	context.Context
+	calls int
+}
+
+func (c *cancelAfterCtx) Err() error {
+	if c.calls <= 0 {
+		return context.Canceled
+	}
+	c.calls--
+	return nil
+}
+
+func TestItemsSketch_MergeCtx(t *testing.T) {
+	comparator := common.ItemSketchLongComparator(false)
+	sketch1, err := NewKllItemsSketchWithDefault[int64](comparator, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	sketch2, err := NewKllItemsSketchWithDefault[int64](comparator, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	n := 100000
+	for i := 0; i < n; i++ {
+		sketch1.Update(int64(i))
+		sketch2.Update(int64(n + i))
+	}
+	before, err := sketch1.ToSlice()
+	assert.NoError(t, err)
+
+	// cancelled before the merge
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sketch1.MergeCtx(ctx, sketch2), context.Canceled)
+	after, err := sketch1.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// cancelled in the middle of the merge
+	assert.ErrorIs(t, sketch1.MergeCtx(&cancelAfterCtx{Context: context.Background(), calls: 2}, sketch2), context.Canceled)
+	after, err = sketch1.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	assert.Error(t, sketch1.MergeCtx(context.Background(), nil))
+	assert.NoError(t, sketch1.MergeCtx(context.Background(), sketch2))
+	assert.Equal(t, uint64(2*n), sketch1.GetN())
+	minV, err := sketch1.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), minV)
+	maxV, err := sketch1.GetMaxItem()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2*n-1), maxV)
+	median, err := sketch1.GetQuantile(0.5, true)
+	assert.NoError(t, err)
+	assert.InDelta(t, n, median, 2*float64(n)*PMF_EPS_FOR_K_256)
+}
+
 func TestItemsSketch_MergeLowerK(t *testing.T) {
 	comparator := common.ItemSketchStringComparator(false)
 	sketch1, err := NewKllItemsSketchWithDefault[string](comparator, common.ItemSketchStringSerDe{})
@@ -761,6 +821,113 @@ func TestItemsSketch_SortedViewAfterReset(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestItemsSketch_ResetBehavesAsNew(t *testing.T) {
+	comparator := common.ItemSketchDoubleComparator(false)
+	sk, err := NewKllItemsSketch[float64](20, _DEFAULT_M, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	other, err := NewKllItemsSketch[float64](8, _DEFAULT_M, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		other.Update(float64(i))
+	}
+	sk.Merge(other)
+	assert.True(t, sk.IsEstimationMode())
+
+	sk.Reset()
+	fresh, err := NewKllItemsSketch[float64](20, _DEFAULT_M, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	assert.True(t, sk.IsEmpty())
+	assert.Equal(t, fresh.GetK(), sk.GetK())
+	assert.Equal(t, fresh.GetNormalizedRankError(false), sk.GetNormalizedRankError(false))
+	assert.Equal(t, fresh.GetNumRetained(), sk.GetNumRetained())
+	skBytes, err := sk.ToSlice()
+	assert.NoError(t, err)
+	freshBytes, err := fresh.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, freshBytes, skBytes)
+}
+
+func TestItemsSketchSortedView_Reset(t *testing.T) {
+	comparator := common.ItemSketchStringComparator(false)
+	sk, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, common.ItemSketchStringSerDe{})
+	assert.NoError(t, err)
+	sk.Update("1")
+	sv, err := sk.GetSortedView()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sv.GetNumRetained())
+	sv.Reset()
+	assert.Equal(t, 0, sv.GetNumRetained())
+	_, err = sv.GetRank("1", true)
+	assert.Error(t, err)
+	_, err = sv.GetQuantile(0.5, true)
+	assert.Error(t, err)
+	assert.False(t, sv.Iterator().Next())
+}
+
+func TestItemsSketchSortedView_All(t *testing.T) {
+	comparator := common.ItemSketchDoubleComparator(false)
+	sk, err := NewKllItemsSketch[float64](20, _DEFAULT_M, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	n := 1000
+	for i := 1; i <= n; i++ {
+		sk.Update(float64(i))
+	}
+	sv, err := sk.GetSortedView()
+	assert.NoError(t, err)
+
+	count := 0
+	prevItem := 0.0
+	prevRank := 0.0
+	for item, rank := range sv.All() {
+		assert.Greater(t, item, prevItem)
+		assert.Greater(t, rank, prevRank)
+		expected, err := sv.GetRank(item, true)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, rank)
+		prevItem, prevRank = item, rank
+		count++
+	}
+	assert.Equal(t, sv.GetNumRetained(), count)
+	assert.Equal(t, 1.0, prevRank)
+
+	// early exit
+	count = 0
+	for range sv.All() {
+		count++
+		if count == 3 {
+			break
+		}
+	}
+	assert.Equal(t, 3, count)
+
+	count = 0
+	for item, rank := range sv.AllBetweenRanks(0.25, 0.75) {
+		assert.GreaterOrEqual(t, rank, 0.25)
+		assert.LessOrEqual(t, rank, 0.75)
+		assert.InDelta(t, rank*float64(n), item, float64(n)*PMF_EPS_FOR_K_8)
+		count++
+	}
+	assert.Greater(t, count, 0)
+	assert.Less(t, count, sv.GetNumRetained())
+
+	count = 0
+	for range sv.AllBetweenRanks(0, 1) {
+		count++
+	}
+	assert.Equal(t, sv.GetNumRetained(), count)
+	for range sv.AllBetweenRanks(0.75, 0.25) {
+		assert.Fail(t, "no item expected")
+	}
+
+	sv.Reset()
+	for range sv.All() {
+		assert.Fail(t, "no item expected")
+	}
+	for range sv.AllBetweenRanks(0, 1) {
+		assert.Fail(t, "no item expected")
+	}
+}
+
 func TestItemsSketch_SerializeDeserializeEmpty(t *testing.T) {
 	comparator := common.ItemSketchStringComparator(false)
 	sk1, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, common.ItemSketchStringSerDe{})
@@ -791,6 +958,28 @@ func TestItemsSketch_SerializeDeserializeEmpty(t *testing.T) {
 	assert.Equal(t, mem, mem2)
 }
 
+func TestItemsSketch_SerializedSizeBytes(t *testing.T) {
+	comparator := common.ItemSketchStringComparator(false)
+	sk, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, common.ItemSketchStringSerDe{})
+	assert.NoError(t, err)
+	// strings of varying lengths, so the size depends on which items are retained
+	for i := 0; i < 1000; i++ {
+		if i%100 == 0 || i < 3 {
+			mem, err := sk.ToSlice()
+			assert.NoError(t, err)
+			size, err := sk.GetSerializedSizeBytes()
+			assert.NoError(t, err)
+			assert.Equal(t, len(mem), size, "n=%d", i)
+		}
+		sk.Update(strings.Repeat("x", i%17))
+	}
+
+	noSerde, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, nil)
+	assert.NoError(t, err)
+	_, err = noSerde.GetSerializedSizeBytes()
+	assert.Error(t, err)
+}
+
 func TestItemsSketch_SerializeDeserializeOneValue(t *testing.T) {
 	comparator := common.ItemSketchStringComparator(false)
 	sk1, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, common.ItemSketchStringSerDe{})
@@ -1014,3 +1203,257 @@ func TestL0SortDuringMerge(t *testing.T) {
 		}
 	}
 }
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
+
+func TestItemsSketch_WriteToReadFrom(t *testing.T) {
+	comparator := common.ItemSketchStringComparator(false)
+	serde := common.ItemSketchStringSerDe{}
+	for _, n := range []int{0, 1, 10, 1000} {
+		sk, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, serde)
+		assert.NoError(t, err)
+		digits := numDigits(n)
+		for i := 1; i <= n; i++ {
+			sk.Update(intToFixedLengthString(i, digits))
+		}
+		expected, err := sk.ToSlice()
+		assert.NoError(t, err)
+
+		var buf bytes.Buffer
+		written, err := sk.WriteTo(&buf)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(expected)), written)
+		assert.Equal(t, expected, buf.Bytes())
+
+		sk2, err := NewItemsSketchFromReader[string](&buf, comparator, serde)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetN(), sk2.GetN())
+		assert.Equal(t, sk.GetNumRetained(), sk2.GetNumRetained())
+		if n > 0 {
+			q1, err := sk.GetQuantile(0.5, true)
+			assert.NoError(t, err)
+			q2, err := sk2.GetQuantile(0.5, true)
+			assert.NoError(t, err)
+			assert.Equal(t, q1, q2)
+		}
+	}
+}
+
+func TestItemsSketch_ReadFromConsecutiveSketches(t *testing.T) {
+	comparator := common.ItemSketchStringComparator(false)
+	serde := common.ItemSketchStringSerDe{}
+	ns := []int{0, 1, 10, 1000}
+	var buf bytes.Buffer
+	var sketches []*ItemsSketch[string]
+	for _, n := range ns {
+		sk, err := NewKllItemsSketch[string](20, _DEFAULT_M, comparator, serde)
+		assert.NoError(t, err)
+		for i := 1; i <= n; i++ {
+			sk.Update(intToFixedLengthString(i, numDigits(n)))
+		}
+		_, err = sk.WriteTo(&buf)
+		assert.NoError(t, err)
+		sketches = append(sketches, sk)
+	}
+
+	for _, sk := range sketches {
+		sk2, err := NewItemsSketchFromReader[string](&buf, comparator, serde)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetN(), sk2.GetN())
+		assert.Equal(t, sk.GetNumRetained(), sk2.GetNumRetained())
+		expected, err := sk.ToSlice()
+		assert.NoError(t, err)
+		actual, err := sk2.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+	assert.Equal(t, 0, buf.Len())
+	_, err := NewItemsSketchFromReader[string](&buf, comparator, serde)
+	assert.Error(t, err)
+}
+
+func TestItemsSketch_WriteToError(t *testing.T) {
+	comparator := common.ItemSketchDoubleComparator(false)
+	sk, err := NewKllItemsSketch[float64](20, _DEFAULT_M, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	sk.Update(1)
+	_, err = sk.WriteTo(failingWriter{})
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+}
+
+func TestItemsSketch_RankAndQuantileBounds(t *testing.T) {
+	comparator := common.ItemSketchDoubleComparator(false)
+	sk, err := NewKllItemsSketch[float64](200, _DEFAULT_M, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	_, err = sk.GetRankLowerBound(1, 1)
+	assert.Error(t, err)
+	_, err = sk.GetQuantileUpperBound(0.5, 1)
+	assert.Error(t, err)
+
+	n := 10000
+	for i := 1; i <= n; i++ {
+		sk.Update(float64(i))
+	}
+	eps := sk.GetNormalizedRankError(false)
+	for numStdDev := 1; numStdDev <= 3; numStdDev++ {
+		rank, err := sk.GetRank(5000, true)
+		assert.NoError(t, err)
+		lb, err := sk.GetRankLowerBound(5000, numStdDev)
+		assert.NoError(t, err)
+		ub, err := sk.GetRankUpperBound(5000, numStdDev)
+		assert.NoError(t, err)
+		assert.InDelta(t, rank-float64(numStdDev)*eps, lb, NUMERIC_NOISE_TOLERANCE)
+		assert.InDelta(t, rank+float64(numStdDev)*eps, ub, NUMERIC_NOISE_TOLERANCE)
+		assert.LessOrEqual(t, lb, 0.5)
+		assert.GreaterOrEqual(t, ub, 0.5)
+
+		q, err := sk.GetQuantile(0.5, true)
+		assert.NoError(t, err)
+		qlb, err := sk.GetQuantileLowerBound(0.5, numStdDev)
+		assert.NoError(t, err)
+		qub, err := sk.GetQuantileUpperBound(0.5, numStdDev)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, qlb, q)
+		assert.GreaterOrEqual(t, qub, q)
+		assert.LessOrEqual(t, qlb, float64(n)/2)
+		assert.GreaterOrEqual(t, qub, float64(n)/2)
+	}
+
+	// bounds are clamped to [0, 1]
+	lb, err := sk.GetRankLowerBound(1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, lb)
+	ub, err := sk.GetRankUpperBound(float64(n), 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, ub)
+	qlb, err := sk.GetQuantileLowerBound(0, 3)
+	assert.NoError(t, err)
+	q0, err := sk.GetQuantile(0, true)
+	assert.NoError(t, err)
+	assert.Equal(t, q0, qlb)
+	qub, err := sk.GetQuantileUpperBound(1, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(n), qub)
+
+	_, err = sk.GetRankLowerBound(1, 0)
+	assert.Error(t, err)
+	_, err = sk.GetRankUpperBound(1, 4)
+	assert.Error(t, err)
+	_, err = sk.GetQuantileLowerBound(1.5, 1)
+	assert.Error(t, err)
+}
+
+func TestItemsSketch_Downsample(t *testing.T) {
+	comparator := common.ItemSketchDoubleComparator(false)
+	serde := common.ItemSketchDoubleSerDe{}
+	src, err := NewKllItemsSketch[float64](1024, _DEFAULT_M, comparator, serde)
+	assert.NoError(t, err)
+	_, err = Downsample(src, 200)
+	assert.NoError(t, err)
+
+	n := 100000
+	for i := 1; i <= n; i++ {
+		src.Update(float64(i))
+	}
+	srcRetained := src.GetNumRetained()
+	sk, err := Downsample(src, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(200), sk.GetK())
+	assert.Equal(t, src.GetN(), sk.GetN())
+	assert.Less(t, sk.GetNumRetained(), srcRetained)
+	assert.Equal(t, srcRetained, src.GetNumRetained())
+	assert.Equal(t, getNormalizedRankError(200, false), sk.GetNormalizedRankError(false))
+	minItem, err := sk.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, minItem)
+	maxItem, err := sk.GetMaxItem()
+	assert.NoError(t, err)
+	assert.Equal(t, float64(n), maxItem)
+
+	eps := sk.GetNormalizedRankError(false)
+	for _, rank := range []float64{0.1, 0.5, 0.9} {
+		q, err := sk.GetQuantile(rank, true)
+		assert.NoError(t, err)
+		assert.InDelta(t, rank*float64(n), q, eps*float64(n))
+	}
+
+	bytes, err := sk.ToSlice()
+	assert.NoError(t, err)
+	sk2, err := NewKllItemsSketchFromSlice[float64](bytes, comparator, serde)
+	assert.NoError(t, err)
+	assert.Equal(t, sk.GetNumRetained(), sk2.GetNumRetained())
+
+	_, err = Downsample(src, 1024)
+	assert.Error(t, err)
+	_, err = Downsample(src, _MIN_K-1)
+	assert.Error(t, err)
+	_, err = Downsample[float64](nil, 200)
+	assert.Error(t, err)
+}
+
+func TestItemsSketchSortedView_BatchQueries(t *testing.T) {
+	sk, err := NewKllItemsSketch[int64](20, _DEFAULT_M, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	for i := 0; i < 5000; i++ {
+		sk.Update(int64(i % 1000))
+	}
+	sv, err := sk.GetSortedView()
+	assert.NoError(t, err)
+
+	items := []int64{-5, 0, 0, 1, 250, 499, 500, 999, 1000, 2000}
+	ranks := []float64{1, 0, 0.5, 0.001, 0.25, 0.999, 0.5, 0.75}
+	for _, inclusive := range []bool{true, false} {
+		batchRanks, err := sv.GetRanks(items, inclusive)
+		assert.NoError(t, err)
+		for i, item := range items {
+			rank, err := sv.GetRank(item, inclusive)
+			assert.NoError(t, err)
+			assert.Equal(t, rank, batchRanks[i], "item %d", item)
+		}
+
+		batchQuantiles, err := sv.GetQuantiles(ranks, inclusive)
+		assert.NoError(t, err)
+		for i, rank := range ranks {
+			quantile, err := sv.GetQuantile(rank, inclusive)
+			assert.NoError(t, err)
+			assert.Equal(t, quantile, batchQuantiles[i], "rank %f", rank)
+		}
+	}
+
+	_, err = sv.GetRanks([]int64{2, 1}, true)
+	assert.Error(t, err)
+	_, err = sv.GetQuantiles([]float64{0.5, 1.5}, true)
+	assert.Error(t, err)
+	_, err = sv.GetQuantiles([]float64{-0.1}, true)
+	assert.Error(t, err)
+}
+
+func TestItemsSketch_String(t *testing.T) {
+	sk, err := NewKllItemsSketch[string](20, _DEFAULT_M, common.ItemSketchStringComparator(false), common.ItemSketchStringSerDe{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"kll","k":20,"m":8,"num_levels":1,"n":0,"num_retained":0,`+
+		`"min_item":null,"max_item":null,"is_empty":true,"is_estimation_mode":false}`, sk.String())
+
+	for i := 0; i < 100; i++ {
+		sk.Update(intToFixedLengthString(i, 3))
+	}
+	var summary map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(sk.String()), &summary))
+	assert.Equal(t, "  0", summary["min_item"])
+	assert.Equal(t, " 99", summary["max_item"])
+	assert.Equal(t, 100.0, summary["n"])
+	assert.Equal(t, float64(sk.GetNumRetained()), summary["num_retained"])
+	assert.Equal(t, true, summary["is_estimation_mode"])
+
+	doubles, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	doubles.Update(math.Inf(1))
+	doubles.Update(1.5)
+	assert.NoError(t, json.Unmarshal([]byte(doubles.String()), &summary))
+	assert.Equal(t, 1.5, summary["min_item"])
+	assert.Equal(t, "+Inf", summary["max_item"])
+}
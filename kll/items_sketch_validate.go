@@ -56,13 +56,16 @@ type itemsSketchMemoryValidate[C comparable] struct {
 }
 
 func newItemsSketchMemoryValidate[C comparable](srcMem []byte, serde common.ItemSketchSerde[C]) (*itemsSketchMemoryValidate[C], error) {
-	capa := cap(srcMem)
+	capa := len(srcMem)
 	if capa < 8 {
 		return nil, fmt.Errorf("Memory too small: %d", capa)
 	}
 	preInts := getPreInts(srcMem)
 	serVer := getSerVer(srcMem)
-	sketchStructure := getSketchStructure(preInts, serVer)
+	sketchStructure, err := getSketchStructure(preInts, serVer)
+	if err != nil {
+		return nil, err
+	}
 	familyID := getFamilyID(srcMem)
 	if familyID != internal.FamilyEnum.Kll.Id {
 		return nil, fmt.Errorf("Source not KLL: %d", familyID)
@@ -70,7 +73,7 @@ func newItemsSketchMemoryValidate[C comparable](srcMem []byte, serde common.Item
 	flags := getFlags(srcMem)
 	k := getK(srcMem)
 	m := getM(srcMem)
-	err := checkM(m)
+	err = checkM(m)
 	if err != nil {
 		return nil, err
 	}
@@ -106,16 +109,34 @@ func (vlid *itemsSketchMemoryValidate[C]) validate() error {
 		if vlid.emptyFlag {
 			return fmt.Errorf("Empty flag and compact full")
 		}
+		if len(vlid.srcMem) < _DATA_START_ADR {
+			return fmt.Errorf("Memory too small: %d", len(vlid.srcMem))
+		}
 		vlid.n = getN(vlid.srcMem)
 		vlid.minK = getMinK(vlid.srcMem)
+		if vlid.minK < uint16(vlid.m) || vlid.minK > vlid.k {
+			return fmt.Errorf("Invalid minK: %d", vlid.minK)
+		}
 		vlid.numLevels = getNumLevels(vlid.srcMem)
+		if vlid.numLevels == 0 || vlid.numLevels > _MAX_NUM_LEVELS || len(vlid.srcMem) < _DATA_START_ADR+int(vlid.numLevels)*4 {
+			return fmt.Errorf("Invalid number of levels: %d", vlid.numLevels)
+		}
 		// Get Levels Arr and add the last element
 		vlid.levelsArr = make([]uint32, vlid.numLevels+1)
-		for i := uint8(0); i < vlid.numLevels; i++ {
+		for i := uint32(0); i < uint32(vlid.numLevels); i++ {
 			vlid.levelsArr[i] = binary.LittleEndian.Uint32(vlid.srcMem[_DATA_START_ADR+i*4 : _DATA_START_ADR+i*4+4])
 		}
 		capacityItems := computeTotalItemCapacity(uint16(vlid.k), uint8(vlid.m), uint8(vlid.numLevels))
 		vlid.levelsArr[vlid.numLevels] = capacityItems //load the last one
+		for i := 1; i < len(vlid.levelsArr); i++ {
+			if vlid.levelsArr[i-1] > vlid.levelsArr[i] {
+				return fmt.Errorf("Invalid levels array: %v", vlid.levelsArr)
+			}
+		}
+		numRetained := capacityItems - vlid.levelsArr[0]
+		if numRetained == 0 || vlid.n < uint64(numRetained) {
+			return fmt.Errorf("Invalid number of retained items: %d, n: %d", numRetained, vlid.n)
+		}
 		sb, err := computeSketchBytes(vlid.srcMem, vlid.levelsArr, vlid.typeBytes, vlid.serde)
 		if err != nil {
 			return err
@@ -147,6 +168,9 @@ func (vlid *itemsSketchMemoryValidate[C]) validate() error {
 	default:
 		return fmt.Errorf("Invalid preamble ints and serial version combo")
 	}
+	if len(vlid.srcMem) < vlid.sketchBytes {
+		return fmt.Errorf("Memory too small: %d, %d", len(vlid.srcMem), vlid.sketchBytes)
+	}
 	return nil
 }
 
@@ -18,6 +18,8 @@
 package kll
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"github.com/apache/datasketches-go/common"
 	"github.com/apache/datasketches-go/internal"
@@ -34,11 +36,13 @@ func TestGenerateGoFiles(t *testing.T) {
 	os.Mkdir(internal.GoPath, 0755)
 
 	nArr := []int{0, 1, 10, 100, 1000, 10000, 100000, 1000000}
+	// one strategy shared by all the sketches, so that the offsets alternate across them
+	// as the package-level test offset did
+	compaction := WithCompactionStrategy(&AlternatingCompactionStrategy{})
 	comparatorString := common.ItemSketchStringComparator(false)
 	for _, n := range nArr {
 		digits := numDigits(n)
-		sk, err := NewKllItemsSketchWithDefault[string](comparatorString, common.ItemSketchStringSerDe{})
-		sk.deterministicOffsetForTest = true
+		sk, err := NewKllItemsSketchWithDefault[string](comparatorString, common.ItemSketchStringSerDe{}, compaction)
 		assert.NoError(t, err)
 		for i := 1; i <= n; i++ {
 			sk.Update(intToFixedLengthString(i, digits))
@@ -51,8 +55,7 @@ func TestGenerateGoFiles(t *testing.T) {
 
 	comparatorDouble := common.ItemSketchDoubleComparator(false)
 	for _, n := range nArr {
-		sk, err := NewKllItemsSketchWithDefault[float64](comparatorDouble, common.ItemSketchDoubleSerDe{})
-		sk.deterministicOffsetForTest = true
+		sk, err := NewKllItemsSketchWithDefault[float64](comparatorDouble, common.ItemSketchDoubleSerDe{}, compaction)
 		assert.NoError(t, err)
 		for i := 1; i <= n; i++ {
 			sk.Update(float64(i))
@@ -74,8 +77,8 @@ func TestJavaCompat(t *testing.T) {
 			bytes, err := os.ReadFile(fmt.Sprintf("%s/kll_string_n%d_java.sk", internal.JavaPath, n))
 			assert.NoError(t, err)
 			sketch, err := NewKllItemsSketchFromSlice[string](bytes, comparatorString, serde)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, sketch.GetK(), uint16(200))
@@ -122,8 +125,8 @@ func TestJavaCompat(t *testing.T) {
 			bytes, err := os.ReadFile(fmt.Sprintf("%s/kll_double_n%d_java.sk", internal.JavaPath, n))
 			assert.NoError(t, err)
 			sketch, err := NewKllItemsSketchFromSlice[float64](bytes, comparatorDouble, serde)
-			if err != nil {
-				return
+			if !assert.NoError(t, err, "n=%d", n) {
+				continue
 			}
 
 			assert.Equal(t, sketch.GetK(), uint16(200))
@@ -161,3 +164,89 @@ func TestJavaCompat(t *testing.T) {
 		}
 	})
 }
+
+func TestItemsSketchGob(t *testing.T) {
+	comparator := common.ItemSketchDoubleComparator(false)
+	sk, err := NewKllItemsSketch[float64](200, 8, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	for i := 1; i <= 10000; i++ {
+		sk.Update(float64(i))
+	}
+	doubles, err := NewDoublesSketch(200)
+	assert.NoError(t, err)
+	for i := 1; i <= 10000; i++ {
+		doubles.Update(float64(i))
+	}
+
+	var buf bytes.Buffer
+	enc := gob.NewEncoder(&buf)
+	assert.NoError(t, enc.Encode(sk))
+	assert.NoError(t, enc.Encode(doubles))
+
+	dec := gob.NewDecoder(&buf)
+	// the compare function and serde must be provided before decoding
+	decoded, err := NewKllItemsSketch[float64](200, 8, comparator, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	assert.NoError(t, dec.Decode(decoded))
+	expected, err := sk.ToSlice()
+	assert.NoError(t, err)
+	actual, err := decoded.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	decoded.Update(0)
+	minItem, err := decoded.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, minItem)
+
+	var decodedDoubles DoublesSketch
+	assert.NoError(t, dec.Decode(&decodedDoubles))
+	doublesBytes, err := doubles.ToSlice()
+	assert.NoError(t, err)
+	decodedDoublesBytes, err := decodedDoubles.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, doublesBytes, decodedDoublesBytes)
+
+	assert.Error(t, (&ItemsSketch[float64]{}).GobDecode(expected))
+}
+
+func TestToSliceWith(t *testing.T) {
+	comparator := common.ItemSketchLongComparator(false)
+	sk, err := NewKllItemsSketch[int64](20, _DEFAULT_M, comparator, nil)
+	assert.NoError(t, err)
+	for i := int64(0); i < 1000; i++ {
+		sk.Update(i)
+	}
+	_, err = sk.ToSlice()
+	assert.Error(t, err)
+	_, err = sk.ToSliceWith(nil)
+	assert.Error(t, err)
+
+	slc, err := sk.ToSliceWith(common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	// the serde of the sketch is unchanged
+	_, err = sk.ToSlice()
+	assert.Error(t, err)
+
+	sk2, err := NewKllItemsSketchFromSlice[int64](slc, comparator, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	assert.Equal(t, sk.GetN(), sk2.GetN())
+	assert.Equal(t, sk.GetTotalItemsArray()[sk.levels[0]:], sk2.GetTotalItemsArray()[sk2.levels[0]:])
+	slc2, err := sk2.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, slc, slc2)
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1000, 10000, 100000, 1000000} {
+		bytes, err := os.ReadFile(fmt.Sprintf("%s/kll_double_n%d_java.sk", internal.JavaPath, n))
+		assert.NoError(t, err)
+		// the items are 1 to n
+		median := float64(n+1) / 2
+		assert.NoError(t, CheckCompatibility(bytes, uint64(n), median, float64(n)*0.02+1), "n=%d", n)
+		assert.Error(t, CheckCompatibility(bytes, uint64(n+1), median, float64(n)*0.02+1))
+		if n > 100 {
+			assert.Error(t, CheckCompatibility(bytes, uint64(n), median*1.5, float64(n)*0.02+1))
+		}
+	}
+	assert.Error(t, CheckCompatibility([]byte{1, 2, 3}, 0, 0, 0))
+}
@@ -0,0 +1,70 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math"
+)
+
+// KolmogorovSmirnovTest performs an approximate two-sample Kolmogorov-Smirnov test on the
+// distributions summarized by the two sketches.
+//
+// The statistic is the largest difference between the INCLUSIVE empirical CDFs of the sketches,
+// evaluated at the union of their retained items. Because these CDFs are only known within the
+// normalized rank error of each sketch, the p-value is computed from the statistic reduced by the
+// sum of both errors, with the asymptotic Kolmogorov distribution and the effective sample size
+// n_a * n_b / (n_a + n_b). This makes the test conservative: a small p-value is strong evidence that
+// the distributions differ, but differences smaller than the sketch error cannot be detected.
+// toFloat64 must be increasing with respect to the compare function of the sketches.
+func KolmogorovSmirnovTest[C comparable](a, b *ItemsSketch[C], toFloat64 func(C) float64) (statistic, pValue float64, err error) {
+	_, cdfA, cdfB, err := mergedCDFs(a, b, toFloat64)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i := range cdfA {
+		statistic = math.Max(statistic, math.Abs(cdfA[i]-cdfB[i]))
+	}
+
+	adjusted := statistic - a.GetNormalizedRankError(false) - b.GetNormalizedRankError(false)
+	if adjusted <= 0 {
+		return statistic, 1.0, nil
+	}
+	nA, nB := float64(a.GetN()), float64(b.GetN())
+	en := math.Sqrt(nA * nB / (nA + nB))
+	return statistic, kolmogorovQ((en + 0.12 + 0.11/en) * adjusted), nil
+}
+
+// kolmogorovQ returns the complementary CDF of the Kolmogorov distribution,
+// Q(lambda) = 2 * sum_{j>=1} (-1)^(j-1) exp(-2 j^2 lambda^2).
+func kolmogorovQ(lambda float64) float64 {
+	if lambda < 0.2 {
+		// the series converges slowly and its sum is 1 within double precision
+		return 1.0
+	}
+	sum := 0.0
+	sign := 1.0
+	for j := 1; j <= 100; j++ {
+		term := sign * 2 * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) <= 1e-12*sum {
+			break
+		}
+		sign = -sign
+	}
+	return math.Min(1.0, math.Max(0.0, sum))
+}
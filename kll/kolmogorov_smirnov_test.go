@@ -0,0 +1,53 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKolmogorovSmirnovTest(t *testing.T) {
+	n := 100000
+	rnd := rand.New(rand.NewSource(1))
+	uniform1 := newDistanceTestSketch(t, func(int) float64 { return rnd.Float64() }, n)
+	uniform2 := newDistanceTestSketch(t, func(int) float64 { return rnd.Float64() }, n)
+	normal := newDistanceTestSketch(t, func(int) float64 { return 0.5 + 0.15*rnd.NormFloat64() }, n)
+
+	statistic, pValue, err := KolmogorovSmirnovTest(uniform1, normal, identity)
+	assert.NoError(t, err)
+	assert.Greater(t, statistic, 0.05)
+	assert.Less(t, pValue, 0.001)
+
+	statistic, pValue, err = KolmogorovSmirnovTest(uniform1, uniform2, identity)
+	assert.NoError(t, err)
+	assert.Less(t, statistic, uniform1.GetNormalizedRankError(false)+uniform2.GetNormalizedRankError(false))
+	assert.Equal(t, 1.0, pValue)
+
+	_, _, err = KolmogorovSmirnovTest(uniform1, nil, identity)
+	assert.Error(t, err)
+}
+
+func TestKolmogorovQ(t *testing.T) {
+	assert.Equal(t, 1.0, kolmogorovQ(0))
+	// critical values of the Kolmogorov distribution
+	assert.InDelta(t, 0.05, kolmogorovQ(1.3581), 1e-4)
+	assert.InDelta(t, 0.01, kolmogorovQ(1.6276), 1e-4)
+}
@@ -0,0 +1,181 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/apache/datasketches-go/internal"
+)
+
+// PreambleInfo holds the fields of the preamble of a serialized KLL sketch.
+type PreambleInfo struct {
+	K           uint16
+	M           uint8
+	MinK        uint16
+	N           uint64
+	NumLevels   uint8
+	NumRetained uint32
+	IsEmpty     bool
+}
+
+// PeekPreamble reads the preamble of a serialized KLL sketch, of any item type, without deserializing the items.
+func PeekPreamble(data []byte) (PreambleInfo, error) {
+	if len(data) < _DATA_START_ADR_SINGLE_ITEM {
+		return PreambleInfo{}, fmt.Errorf("possible corruption: insufficient bytes in array: %d", len(data))
+	}
+	if familyID := getFamilyID(data); familyID != internal.FamilyEnum.Kll.Id {
+		return PreambleInfo{}, fmt.Errorf("possible corruption: source not KLL: %d", familyID)
+	}
+	k := getK(data)
+	m := getM(data)
+	if err := checkM(m); err != nil {
+		return PreambleInfo{}, err
+	}
+	if err := checkK(k, m); err != nil {
+		return PreambleInfo{}, err
+	}
+	preInts := getPreInts(data)
+	serVer := getSerVer(data)
+	empty := getEmptyFlag(data)
+	switch {
+	case preInts == _PREAMBLE_INTS_EMPTY_SINGLE && serVer == _SERIAL_VERSION_EMPTY_FULL:
+		if !empty {
+			return PreambleInfo{}, fmt.Errorf("possible corruption: empty sketch without empty flag")
+		}
+		return PreambleInfo{K: k, M: m, MinK: k, NumLevels: 1, IsEmpty: true}, nil
+	case preInts == _PREAMBLE_INTS_EMPTY_SINGLE && serVer == _SERIAL_VERSION_SINGLE:
+		if empty {
+			return PreambleInfo{}, fmt.Errorf("possible corruption: single item sketch with empty flag")
+		}
+		return PreambleInfo{K: k, M: m, MinK: k, N: 1, NumLevels: 1, NumRetained: 1}, nil
+	case preInts == _PREAMBLE_INTS_FULL && serVer == _SERIAL_VERSION_EMPTY_FULL:
+		if empty {
+			return PreambleInfo{}, fmt.Errorf("possible corruption: full sketch with empty flag")
+		}
+	default:
+		return PreambleInfo{}, fmt.Errorf("possible corruption: invalid preamble ints and serial version: %d, %d", preInts, serVer)
+	}
+	if len(data) < _DATA_START_ADR {
+		return PreambleInfo{}, fmt.Errorf("possible corruption: insufficient bytes in array: %d", len(data))
+	}
+	numLevels := getNumLevels(data)
+	if numLevels == 0 || numLevels > _MAX_NUM_LEVELS || len(data) < _DATA_START_ADR+int(numLevels)*4 {
+		return PreambleInfo{}, fmt.Errorf("possible corruption: invalid number of levels: %d", numLevels)
+	}
+	capacity := computeTotalItemCapacity(k, m, numLevels)
+	level0 := binary.LittleEndian.Uint32(data[_DATA_START_ADR:])
+	if level0 > capacity {
+		return PreambleInfo{}, fmt.Errorf("possible corruption: invalid level 0 start: %d", level0)
+	}
+	return PreambleInfo{
+		K:           k,
+		M:           m,
+		MinK:        getMinK(data),
+		N:           getN(data),
+		NumLevels:   numLevels,
+		NumRetained: capacity - level0,
+	}, nil
+}
+
+// LazyItemsSketch is a read-only view of a serialized ItemsSketch that only reads the preamble up front.
+// The items are deserialized on the first query that needs them.
+// The serialized bytes are referenced, not copied, so they must not be modified while the view is in use.
+type LazyItemsSketch[C comparable] struct {
+	data      []byte
+	info      PreambleInfo
+	compareFn common.CompareFn[C]
+	serde     common.ItemSketchSerde[C]
+	opts      []ItemsSketchOption
+	sketch    *ItemsSketch[C]
+}
+
+// NewItemsSketchLazy creates a LazyItemsSketch from the given serialized sketch.
+// Only the preamble is validated, the items are validated when they are deserialized.
+func NewItemsSketchLazy[C comparable](data []byte, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C], opts ...ItemsSketchOption) (*LazyItemsSketch[C], error) {
+	if serde == nil {
+		return nil, fmt.Errorf("no SerDe provided")
+	}
+	if compareFn == nil {
+		return nil, fmt.Errorf("no compare function provided")
+	}
+	info, err := PeekPreamble(data)
+	if err != nil {
+		return nil, err
+	}
+	return &LazyItemsSketch[C]{
+		data:      data,
+		info:      info,
+		compareFn: compareFn,
+		serde:     serde,
+		opts:      opts,
+	}, nil
+}
+
+// GetPreamble returns the preamble of the sketch.
+func (s *LazyItemsSketch[C]) GetPreamble() PreambleInfo {
+	return s.info
+}
+
+// IsEmpty returns true if the sketch is empty.
+func (s *LazyItemsSketch[C]) IsEmpty() bool {
+	return s.info.IsEmpty
+}
+
+// GetN returns the length of the input stream of the sketch.
+func (s *LazyItemsSketch[C]) GetN() uint64 {
+	return s.info.N
+}
+
+// GetK returns the configured k of the sketch.
+func (s *LazyItemsSketch[C]) GetK() uint16 {
+	return s.info.K
+}
+
+// GetSketch deserializes the sketch on the first call and returns it.
+// The returned sketch is owned by the view, updating it changes the results of the view.
+func (s *LazyItemsSketch[C]) GetSketch() (*ItemsSketch[C], error) {
+	if s.sketch == nil {
+		sketch, err := NewKllItemsSketchFromSlice[C](s.data, s.compareFn, s.serde, s.opts...)
+		if err != nil {
+			return nil, err
+		}
+		s.sketch = sketch
+	}
+	return s.sketch, nil
+}
+
+// GetQuantile returns the approximate quantile of the given normalized rank. See ItemsSketch.GetQuantile.
+func (s *LazyItemsSketch[C]) GetQuantile(rank float64, inclusive bool) (C, error) {
+	sketch, err := s.GetSketch()
+	if err != nil {
+		return *new(C), err
+	}
+	return sketch.GetQuantile(rank, inclusive)
+}
+
+// GetRank returns the normalized rank of the given item. See ItemsSketch.GetRank.
+func (s *LazyItemsSketch[C]) GetRank(item C, inclusive bool) (float64, error) {
+	sketch, err := s.GetSketch()
+	if err != nil {
+		return 0, err
+	}
+	return sketch.GetRank(item, inclusive)
+}
@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeekPreamble(t *testing.T) {
+	comparator := common.ItemSketchLongComparator(false)
+	sk, err := NewKllItemsSketch[int64](1000, _DEFAULT_M, comparator, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+
+	for _, n := range []int{0, 1, 10, 100000} {
+		sk.Reset()
+		for i := 0; i < n; i++ {
+			sk.Update(int64(i))
+		}
+		bytes, err := sk.ToSlice()
+		assert.NoError(t, err)
+		info, err := PeekPreamble(bytes)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetK(), info.K)
+		assert.Equal(t, _DEFAULT_M, info.M)
+		assert.Equal(t, sk.GetN(), info.N)
+		assert.Equal(t, uint8(sk.getNumLevels()), info.NumLevels)
+		assert.Equal(t, sk.GetNumRetained(), info.NumRetained)
+		assert.Equal(t, sk.IsEmpty(), info.IsEmpty)
+
+		// a k above 255 survives a round trip
+		sk2, err := NewKllItemsSketchFromSlice[int64](bytes, comparator, common.ItemSketchLongSerDe{})
+		assert.NoError(t, err)
+		assert.Equal(t, uint16(1000), sk2.GetK())
+	}
+
+	bytes, err := sk.ToSlice()
+	assert.NoError(t, err)
+	_, err = PeekPreamble(bytes[:4])
+	assert.Error(t, err)
+	_, err = PeekPreamble(bytes[:16])
+	assert.Error(t, err)
+	corrupt := append([]byte{}, bytes...)
+	corrupt[_FAMILY_BYTE_ADR] = 7
+	_, err = PeekPreamble(corrupt)
+	assert.Error(t, err)
+	corrupt = append([]byte{}, bytes...)
+	corrupt[_SER_VER_BYTE_ADR] = 9
+	_, err = PeekPreamble(corrupt)
+	assert.Error(t, err)
+}
+
+func TestItemsSketchLazy(t *testing.T) {
+	comparator := common.ItemSketchLongComparator(false)
+	sk, err := NewKllItemsSketchWithDefault[int64](comparator, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		sk.Update(int64(i))
+	}
+	bytes, err := sk.ToSlice()
+	assert.NoError(t, err)
+
+	lazy, err := NewItemsSketchLazy[int64](bytes, comparator, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10000), lazy.GetN())
+	assert.Equal(t, sk.GetK(), lazy.GetK())
+	assert.False(t, lazy.IsEmpty())
+	assert.Nil(t, lazy.sketch)
+
+	q1, err := sk.GetQuantile(0.5, true)
+	assert.NoError(t, err)
+	q2, err := lazy.GetQuantile(0.5, true)
+	assert.NoError(t, err)
+	assert.Equal(t, q1, q2)
+	assert.NotNil(t, lazy.sketch)
+	r1, err := sk.GetRank(5000, true)
+	assert.NoError(t, err)
+	r2, err := lazy.GetRank(5000, true)
+	assert.NoError(t, err)
+	assert.Equal(t, r1, r2)
+
+	_, err = NewItemsSketchLazy[int64](bytes, nil, common.ItemSketchLongSerDe{})
+	assert.Error(t, err)
+	_, err = NewItemsSketchLazy[int64](bytes[:4], comparator, common.ItemSketchLongSerDe{})
+	assert.Error(t, err)
+}
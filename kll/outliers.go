@@ -0,0 +1,50 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"errors"
+)
+
+const (
+	_IQR_OUTLIER_FACTOR = 1.5
+)
+
+// IQROutlierBounds returns the bounds outside of which items are considered outliers with Tukey's fences:
+// Q1 - 1.5 * IQR and Q3 + 1.5 * IQR, where Q1 and Q3 are the INCLUSIVE quantiles at ranks 0.25 and 0.75
+// and IQR = Q3 - Q1.
+// toFloat64 and fromFloat64 convert between items and numbers, and must be increasing with respect to
+// the compare function of the sketch.
+func IQROutlierBounds[C comparable](s *ItemsSketch[C], toFloat64 func(C) float64, fromFloat64 func(float64) C) (lower, upper C, err error) {
+	if s == nil {
+		return lower, upper, errors.New("no sketch provided")
+	}
+	if toFloat64 == nil || fromFloat64 == nil {
+		return lower, upper, errors.New("no conversion functions provided")
+	}
+	if s.IsEmpty() {
+		return lower, upper, errors.New("operation is undefined for an empty sketch")
+	}
+	quartiles, err := s.GetQuantiles([]float64{0.25, 0.75}, true)
+	if err != nil {
+		return lower, upper, err
+	}
+	q1, q3 := toFloat64(quartiles[0]), toFloat64(quartiles[1])
+	iqr := q3 - q1
+	return fromFloat64(q1 - _IQR_OUTLIER_FACTOR*iqr), fromFloat64(q3 + _IQR_OUTLIER_FACTOR*iqr), nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIQROutlierBounds(t *testing.T) {
+	sk, err := NewKllItemsSketch[int64](200, _DEFAULT_M, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	toFloat64 := func(v int64) float64 { return float64(v) }
+	fromFloat64 := func(v float64) int64 { return int64(v) }
+
+	_, _, err = IQROutlierBounds(sk, toFloat64, fromFloat64)
+	assert.Error(t, err)
+
+	// exact mode: Q1 = 25, Q3 = 75, IQR = 50
+	for i := int64(1); i <= 100; i++ {
+		sk.Update(i)
+	}
+	lower, upper, err := IQROutlierBounds(sk, toFloat64, fromFloat64)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-50), lower)
+	assert.Equal(t, int64(150), upper)
+
+	for i := int64(101); i <= 100000; i++ {
+		sk.Update(i % 1000)
+	}
+	lower, upper, err = IQROutlierBounds(sk, toFloat64, fromFloat64)
+	assert.NoError(t, err)
+	assert.InDelta(t, -500, lower, 1000*4*PMF_EPS_FOR_K_256)
+	assert.InDelta(t, 1500, upper, 1000*4*PMF_EPS_FOR_K_256)
+
+	_, _, err = IQROutlierBounds(sk, nil, fromFloat64)
+	assert.Error(t, err)
+	_, _, err = IQROutlierBounds[int64](nil, toFloat64, fromFloat64)
+	assert.Error(t, err)
+}
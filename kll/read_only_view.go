@@ -0,0 +1,201 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import "sync"
+
+// ReadOnlyView guards an ItemsSketch with a sync.RWMutex, so that queries can run concurrently
+// with each other and with writes made through WithWriteLock.
+//
+// The queries of an ItemsSketch build and cache its sorted view, so they are not safe for concurrent
+// use on their own. The view builds the sorted view under the write lock when it is missing, then runs
+// the queries under the read lock. The queries behave as the ItemsSketch methods of the same name.
+type ReadOnlyView[C comparable] struct {
+	mu     sync.RWMutex
+	sketch *ItemsSketch[C]
+}
+
+// NewReadOnlyView returns a view of the given sketch. Once the view is created,
+// the sketch must only be modified within WithWriteLock.
+func NewReadOnlyView[C comparable](sketch *ItemsSketch[C]) *ReadOnlyView[C] {
+	return &ReadOnlyView[C]{sketch: sketch}
+}
+
+// WithWriteLock calls fn while holding the write lock, fn may update, merge or reset the sketch.
+func (v *ReadOnlyView[C]) WithWriteLock(fn func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	fn()
+}
+
+// Snapshot returns a copy of the sketch taken under the read lock, independent of later writes.
+// The snapshot can be updated concurrently with the sketch: it gets a clone of a stateful compaction
+// strategy implementing Cloner, and a new random source if one was set with WithRng,
+// so its random compactions are not the ones the sketch would have made.
+func (v *ReadOnlyView[C]) Snapshot() *ItemsSketch[C] {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	snapshot := *v.sketch
+	snapshot.levels = append([]uint32(nil), v.sketch.levels...)
+	snapshot.items = append([]C(nil), v.sketch.items...)
+	snapshot.sortedView = nil
+	snapshot.options = v.sketch.options.clone()
+	return &snapshot
+}
+
+// rLockSorted acquires the read lock with the sorted view of a non-empty sketch already built.
+func (v *ReadOnlyView[C]) rLockSorted() {
+	for {
+		v.mu.RLock()
+		if v.sketch.IsEmpty() || v.sketch.sortedView != nil {
+			return
+		}
+		v.mu.RUnlock()
+		v.mu.Lock()
+		var err error
+		if !v.sketch.IsEmpty() {
+			err = v.sketch.setupSortedView()
+		}
+		v.mu.Unlock()
+		if err != nil {
+			// the query returns the error again
+			v.mu.RLock()
+			return
+		}
+	}
+}
+
+func (v *ReadOnlyView[C]) IsEmpty() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.IsEmpty()
+}
+
+func (v *ReadOnlyView[C]) GetN() uint64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.GetN()
+}
+
+func (v *ReadOnlyView[C]) GetK() uint16 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.GetK()
+}
+
+func (v *ReadOnlyView[C]) GetNumRetained() uint32 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.GetNumRetained()
+}
+
+func (v *ReadOnlyView[C]) IsEstimationMode() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.IsEstimationMode()
+}
+
+func (v *ReadOnlyView[C]) GetMinItem() (C, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.GetMinItem()
+}
+
+func (v *ReadOnlyView[C]) GetMaxItem() (C, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.GetMaxItem()
+}
+
+func (v *ReadOnlyView[C]) GetNormalizedRankError(pmf bool) float64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.GetNormalizedRankError(pmf)
+}
+
+func (v *ReadOnlyView[C]) GetRank(item C, inclusive bool) (float64, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetRank(item, inclusive)
+}
+
+func (v *ReadOnlyView[C]) GetRanks(items []C, inclusive bool) ([]float64, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetRanks(items, inclusive)
+}
+
+func (v *ReadOnlyView[C]) GetQuantile(rank float64, inclusive bool) (C, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetQuantile(rank, inclusive)
+}
+
+func (v *ReadOnlyView[C]) GetQuantiles(ranks []float64, inclusive bool) ([]C, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetQuantiles(ranks, inclusive)
+}
+
+func (v *ReadOnlyView[C]) GetPMF(splitPoints []C, inclusive bool) ([]float64, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetPMF(splitPoints, inclusive)
+}
+
+func (v *ReadOnlyView[C]) GetCDF(splitPoints []C, inclusive bool) ([]float64, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetCDF(splitPoints, inclusive)
+}
+
+func (v *ReadOnlyView[C]) GetRankLowerBound(item C, numStdDev int) (float64, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetRankLowerBound(item, numStdDev)
+}
+
+func (v *ReadOnlyView[C]) GetRankUpperBound(item C, numStdDev int) (float64, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetRankUpperBound(item, numStdDev)
+}
+
+func (v *ReadOnlyView[C]) GetQuantileLowerBound(rank float64, numStdDev int) (C, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetQuantileLowerBound(rank, numStdDev)
+}
+
+func (v *ReadOnlyView[C]) GetQuantileUpperBound(rank float64, numStdDev int) (C, error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetQuantileUpperBound(rank, numStdDev)
+}
+
+func (v *ReadOnlyView[C]) GetPartitionBoundaries(numEquallySized int, inclusive bool) (*ItemsSketchPartitionBoundaries[C], error) {
+	v.rLockSorted()
+	defer v.mu.RUnlock()
+	return v.sketch.GetPartitionBoundaries(numEquallySized, inclusive)
+}
+
+func (v *ReadOnlyView[C]) ToSlice() ([]byte, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.sketch.ToSlice()
+}
@@ -0,0 +1,116 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyView(t *testing.T) {
+	sk, err := NewKllItemsSketch[float64](200, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	view := NewReadOnlyView(sk)
+	_, err = view.GetQuantile(0.5, true)
+	assert.Error(t, err)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20000; i++ {
+			view.WithWriteLock(func() { sk.Update(float64(i)) })
+		}
+	}()
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if view.IsEmpty() {
+					continue
+				}
+				q, err := view.GetQuantile(0.5, true)
+				assert.NoError(t, err)
+				assert.GreaterOrEqual(t, q, 0.0)
+				_, err = view.GetPMF([]float64{5000, 10000}, true)
+				assert.NoError(t, err)
+				_ = view.Snapshot().GetN()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(20000), view.GetN())
+	snapshot := view.Snapshot()
+	view.WithWriteLock(func() { sk.Update(-1) })
+	assert.Equal(t, uint64(20000), snapshot.GetN())
+	minItem, err := snapshot.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, minItem)
+	minItem, err = view.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, -1.0, minItem)
+
+	q1, err := view.GetQuantile(0.5, true)
+	assert.NoError(t, err)
+	q2, err := sk.GetQuantile(0.5, true)
+	assert.NoError(t, err)
+	assert.Equal(t, q2, q1)
+}
+
+func TestReadOnlyView_SnapshotUpdatedConcurrently(t *testing.T) {
+	// run with -race: the snapshot must not share the state of the compactions with the sketch
+	for _, opts := range [][]ItemsSketchOption{
+		{WithCompactionStrategy(&AlternatingCompactionStrategy{})},
+		{WithRng(rand.New(rand.NewSource(1)))},
+	} {
+		sk, err := NewKllItemsSketch[float64](20, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{}, opts...)
+		assert.NoError(t, err)
+		view := NewReadOnlyView(sk)
+		view.WithWriteLock(func() {
+			for i := 0; i < 1000; i++ {
+				sk.Update(float64(i))
+			}
+		})
+		snapshot := view.Snapshot()
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				view.WithWriteLock(func() { sk.Update(float64(i)) })
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 10000; i++ {
+				snapshot.Update(float64(i))
+			}
+		}()
+		wg.Wait()
+
+		assert.Equal(t, uint64(11000), view.GetN())
+		assert.Equal(t, uint64(11000), snapshot.GetN())
+	}
+}
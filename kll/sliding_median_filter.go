@@ -0,0 +1,141 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+const (
+	_SLIDING_MEDIAN_MAX_SEGMENTS = 8
+)
+
+// SlidingMedianFilter is a running approximate median of the last windowSize items of a stream.
+//
+// The window is partitioned into at most 8 segments of segmentSize = ceil(windowSize / 8) items, each
+// summarized by its own ItemsSketch in a circular buffer. When the current segment is full, the oldest
+// segment is reset and reused as the new current segment. The queries merge the current segment with as
+// many of the most recent completed segments as fit in the window.
+//
+// Since the items expire a segment at a time, the window holds between windowSize - segmentSize + 1 and
+// windowSize items once the stream is long enough. A windowSize of at most 8 gives segments of one item,
+// and an exact window.
+type SlidingMedianFilter[C comparable] struct {
+	segments    []*ItemsSketch[C]
+	current     int
+	segmentSize uint64
+	windowSize  int
+	scratch     *ItemsSketch[C] // the merge of the segments in the window, for the queries
+}
+
+// NewSlidingMedianFilter creates a new SlidingMedianFilter over the last windowSize items,
+// with segments sketched with the given k and the default m.
+func NewSlidingMedianFilter[C comparable](windowSize int, k uint16, compareFn common.CompareFn[C], serde common.ItemSketchSerde[C]) (*SlidingMedianFilter[C], error) {
+	if windowSize < 1 {
+		return nil, fmt.Errorf("windowSize must be >= 1: %d", windowSize)
+	}
+	segmentSize := (windowSize + _SLIDING_MEDIAN_MAX_SEGMENTS - 1) / _SLIDING_MEDIAN_MAX_SEGMENTS
+	// enough completed segments to fill the window next to a current segment of one item
+	numSegments := (windowSize-1)/segmentSize + 1
+	segments := make([]*ItemsSketch[C], numSegments)
+	for i := range segments {
+		sk, err := NewKllItemsSketch[C](k, _DEFAULT_M, compareFn, serde)
+		if err != nil {
+			return nil, err
+		}
+		segments[i] = sk
+	}
+	scratch, err := NewKllItemsSketch[C](k, _DEFAULT_M, compareFn, serde)
+	if err != nil {
+		return nil, err
+	}
+	return &SlidingMedianFilter[C]{
+		segments:    segments,
+		segmentSize: uint64(segmentSize),
+		windowSize:  windowSize,
+		scratch:     scratch,
+	}, nil
+}
+
+// Update presents an item to the filter, expiring the oldest segment of the window if needed.
+func (f *SlidingMedianFilter[C]) Update(item C) {
+	if f.segments[f.current].GetN() == f.segmentSize {
+		// the oldest segment becomes the current one
+		f.current = (f.current + 1) % len(f.segments)
+		f.segments[f.current].Reset()
+	}
+	f.segments[f.current].Update(item)
+}
+
+// GetMedian returns the approximate median of the items in the current window,
+// with the INCLUSIVE search criterion.
+func (f *SlidingMedianFilter[C]) GetMedian() (C, error) {
+	return f.GetQuantile(0.5)
+}
+
+// GetQuantile returns the approximate item at the given normalized rank in the current window,
+// with the INCLUSIVE search criterion.
+func (f *SlidingMedianFilter[C]) GetQuantile(rank float64) (C, error) {
+	if f.segments[f.current].IsEmpty() {
+		var zero C
+		return zero, errors.New("operation is undefined for an empty sketch")
+	}
+	f.scratch.Reset()
+	for _, sk := range f.windowSegments() {
+		f.scratch.Merge(sk)
+	}
+	return f.scratch.GetQuantile(rank, true)
+}
+
+// GetN returns the number of items in the current window.
+func (f *SlidingMedianFilter[C]) GetN() uint64 {
+	n := uint64(0)
+	for _, sk := range f.windowSegments() {
+		n += sk.GetN()
+	}
+	return n
+}
+
+// GetWindowSize returns the configured size of the window.
+func (f *SlidingMedianFilter[C]) GetWindowSize() int {
+	return f.windowSize
+}
+
+// Reset empties the window.
+func (f *SlidingMedianFilter[C]) Reset() {
+	for _, sk := range f.segments {
+		sk.Reset()
+	}
+	f.scratch.Reset()
+	f.current = 0
+}
+
+// windowSegments returns the current segment followed by the most recent completed segments
+// that fit in the window.
+func (f *SlidingMedianFilter[C]) windowSegments() []*ItemsSketch[C] {
+	cur := f.segments[f.current]
+	numCompleted := (uint64(f.windowSize) - cur.GetN()) / f.segmentSize
+	result := []*ItemsSketch[C]{cur}
+	for i := 1; i <= int(numCompleted) && i < len(f.segments); i++ {
+		result = append(result, f.segments[(f.current-i+len(f.segments))%len(f.segments)])
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingMedianFilterExactWindow(t *testing.T) {
+	_, err := NewSlidingMedianFilter[int64](0, 200, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{})
+	assert.Error(t, err)
+	_, err = NewSlidingMedianFilter[int64](10, 1, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{})
+	assert.Error(t, err)
+
+	f, err := NewSlidingMedianFilter[int64](5, 200, common.ItemSketchLongComparator(false), common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	_, err = f.GetMedian()
+	assert.Error(t, err)
+
+	// with segments of one item the window is exact
+	stream := []int64{9, 1, 8, 2, 7, 3, 6, 4, 5, 100, 100, 100, -1}
+	for i, v := range stream {
+		f.Update(v)
+		window := slices.Clone(stream[max(0, i-4) : i+1])
+		slices.Sort(window)
+		assert.Equal(t, uint64(len(window)), f.GetN())
+		median, err := f.GetMedian()
+		assert.NoError(t, err)
+		assert.Equal(t, window[(len(window)-1)/2], median, "item %d", i)
+	}
+
+	f.Reset()
+	assert.Equal(t, uint64(0), f.GetN())
+	_, err = f.GetMedian()
+	assert.Error(t, err)
+}
+
+func TestSlidingMedianFilterSegmentedWindow(t *testing.T) {
+	const windowSize = 1003
+	f, err := NewSlidingMedianFilter[float64](windowSize, 200, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	assert.Equal(t, windowSize, f.GetWindowSize())
+	segmentSize := uint64((windowSize + 7) / 8)
+
+	// a step from a noisy level around 0 to a noisy level around 100
+	for i := 0; i < 10*windowSize; i++ {
+		level := 0.0
+		if i >= 5*windowSize {
+			level = 100
+		}
+		f.Update(level + float64(i%21-10))
+		n := f.GetN()
+		assert.LessOrEqual(t, n, uint64(windowSize))
+		if i >= windowSize {
+			assert.Greater(t, n, windowSize-segmentSize)
+		}
+		if i == 5*windowSize-1 || i == 10*windowSize-1 {
+			median, err := f.GetMedian()
+			assert.NoError(t, err)
+			assert.InDelta(t, level, median, 1)
+		}
+	}
+}
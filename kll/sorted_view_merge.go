@@ -0,0 +1,126 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"container/heap"
+	"errors"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+// MergeSortedViews merges sorted views, for example of the partitions of a stream, into one sorted view
+// of all their items, without going back to the sketches.
+// The merge is an N-way merge of the retained items with a min-heap, in O(numRetained * log(len(views))).
+// The weights of the items are kept, so the cumulative weights of the result sum to the total N of
+// the views. Nil and empty views are skipped, and at least one view must be non-empty.
+func MergeSortedViews[C comparable](views []*ItemsSketchSortedView[C], compareFn common.CompareFn[C]) (*ItemsSketchSortedView[C], error) {
+	if compareFn == nil {
+		return nil, errors.New("no compare function provided")
+	}
+	h := &sortedViewHeap[C]{compareFn: compareFn}
+	numRetained := 0
+	totalN := uint64(0)
+	var minItem, maxItem C
+	for _, v := range views {
+		if v == nil || v.totalN == 0 {
+			continue
+		}
+		if totalN == 0 || compareFn(v.minItem, minItem) {
+			minItem = v.minItem
+		}
+		if totalN == 0 || compareFn(maxItem, v.maxItem) {
+			maxItem = v.maxItem
+		}
+		totalN += v.totalN
+		numRetained += len(v.quantiles)
+		h.cursors = append(h.cursors, sortedViewCursor[C]{view: v, order: len(h.cursors)})
+	}
+	if totalN == 0 {
+		return nil, errors.New("empty sketch")
+	}
+	heap.Init(h)
+
+	quantiles := make([]C, 0, numRetained)
+	cumWeights := make([]int64, 0, numRetained)
+	cumWeight := int64(0)
+	for h.Len() > 0 {
+		c := &h.cursors[0]
+		cumWeight += c.view.cumWeights[c.index]
+		if c.index > 0 {
+			cumWeight -= c.view.cumWeights[c.index-1]
+		}
+		quantiles = append(quantiles, c.view.quantiles[c.index])
+		cumWeights = append(cumWeights, cumWeight)
+		c.index++
+		if c.index == len(c.view.quantiles) {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return &ItemsSketchSortedView[C]{
+		quantiles:  quantiles,
+		cumWeights: cumWeights,
+		totalN:     totalN,
+		maxItem:    maxItem,
+		minItem:    minItem,
+		compareFn:  compareFn,
+	}, nil
+}
+
+type sortedViewCursor[C comparable] struct {
+	view  *ItemsSketchSortedView[C]
+	index int
+	order int // the position of the view in the input, to keep equal items in a stable order
+}
+
+type sortedViewHeap[C comparable] struct {
+	cursors   []sortedViewCursor[C]
+	compareFn common.CompareFn[C]
+}
+
+func (h *sortedViewHeap[C]) Len() int {
+	return len(h.cursors)
+}
+
+func (h *sortedViewHeap[C]) Less(i, j int) bool {
+	a := h.cursors[i].view.quantiles[h.cursors[i].index]
+	b := h.cursors[j].view.quantiles[h.cursors[j].index]
+	if h.compareFn(a, b) {
+		return true
+	}
+	if h.compareFn(b, a) {
+		return false
+	}
+	return h.cursors[i].order < h.cursors[j].order
+}
+
+func (h *sortedViewHeap[C]) Swap(i, j int) {
+	h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i]
+}
+
+func (h *sortedViewHeap[C]) Push(x any) {
+	h.cursors = append(h.cursors, x.(sortedViewCursor[C]))
+}
+
+func (h *sortedViewHeap[C]) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
@@ -0,0 +1,79 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeSortedViews(t *testing.T) {
+	compareFn := common.ItemSketchDoubleComparator(false)
+	// three partitions of different sizes and overlapping ranges, one in exact mode
+	var views []*ItemsSketchSortedView[float64]
+	numRetained := 0
+	for p, n := range []int{100, 10000, 50000} {
+		sk, err := NewKllItemsSketch[float64](200, _DEFAULT_M, compareFn, common.ItemSketchDoubleSerDe{})
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			sk.Update(float64(p*1000 + i))
+		}
+		view, err := sk.GetSortedView()
+		assert.NoError(t, err)
+		views = append(views, view)
+		numRetained += view.GetNumRetained()
+	}
+	empty, err := NewKllItemsSketch[float64](200, _DEFAULT_M, compareFn, common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	emptyView := &ItemsSketchSortedView[float64]{}
+	_, err = empty.GetSortedView()
+	assert.Error(t, err)
+
+	merged, err := MergeSortedViews(append(views, nil, emptyView), compareFn)
+	assert.NoError(t, err)
+	assert.Equal(t, numRetained, merged.GetNumRetained())
+	assert.Equal(t, uint64(60100), merged.totalN)
+	assert.Equal(t, int64(60100), merged.cumWeights[len(merged.cumWeights)-1])
+	assert.Equal(t, 0.0, merged.minItem)
+	assert.Equal(t, 51999.0, merged.maxItem)
+
+	// the ranks in the merged view are the weighted ranks in the views
+	for _, item := range []float64{-1, 0, 50, 99, 1000, 5000, 10999, 20000, 51999} {
+		for _, inclusive := range []bool{true, false} {
+			expected := 0.0
+			for _, view := range views {
+				r, err := view.GetRank(item, inclusive)
+				assert.NoError(t, err)
+				expected += r * float64(view.totalN)
+			}
+			rank, err := merged.GetRank(item, inclusive)
+			assert.NoError(t, err)
+			assert.InDelta(t, expected/60100, rank, 1e-12, "item %f inclusive %t", item, inclusive)
+		}
+	}
+	median, err := merged.GetQuantile(0.5, true)
+	assert.NoError(t, err)
+	assert.InDelta(t, 21950, median, 60100*0.02)
+
+	_, err = MergeSortedViews([]*ItemsSketchSortedView[float64]{nil, emptyView}, compareFn)
+	assert.Error(t, err)
+	_, err = MergeSortedViews(views, nil)
+	assert.Error(t, err)
+}
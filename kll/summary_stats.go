@@ -0,0 +1,81 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"errors"
+	"math"
+)
+
+// SummaryStats holds descriptive statistics of the stream presented to a sketch.
+type SummaryStats struct {
+	N        uint64
+	Min      float64
+	Max      float64
+	Mean     float64
+	Variance float64 // population variance
+	Skewness float64 // population skewness, NaN if the variance is zero
+}
+
+// GetSummaryStats returns descriptive statistics of the stream, with the items converted by toFloat64.
+// N, Min and Max are exact. Mean, Variance and Skewness are computed in one pass over the retained items,
+// each one weighted by its level, with Welford's online algorithm extended to weights and to the third moment.
+// They are exact in exact mode and approximate in estimation mode.
+func (s *ItemsSketch[C]) GetSummaryStats(toFloat64 func(C) float64) (SummaryStats, error) {
+	if toFloat64 == nil {
+		return SummaryStats{}, errors.New("no conversion function provided")
+	}
+	if s.IsEmpty() {
+		return SummaryStats{}, errors.New("operation is undefined for an empty sketch")
+	}
+	// weights are powers of 2 up to n, so they are summed exactly in a float64
+	var totalWeight, mean, m2, m3 float64
+	it := s.GetIterator()
+	for it.Next() {
+		x := toFloat64(it.GetQuantile())
+		w := float64(it.GetWeight())
+		n := totalWeight + w
+		delta := x - mean
+		deltaN := delta * w / n
+		m3 += delta*deltaN*deltaN*totalWeight*(totalWeight-w)/w - 3*deltaN*m2
+		m2 += delta * deltaN * totalWeight
+		mean += deltaN
+		totalWeight = n
+	}
+	minItem, err := s.GetMinItem()
+	if err != nil {
+		return SummaryStats{}, err
+	}
+	maxItem, err := s.GetMaxItem()
+	if err != nil {
+		return SummaryStats{}, err
+	}
+	variance := m2 / totalWeight
+	skewness := math.NaN()
+	if m2 > 0 {
+		skewness = math.Sqrt(totalWeight) * m3 / math.Pow(m2, 1.5)
+	}
+	return SummaryStats{
+		N:        s.GetN(),
+		Min:      toFloat64(minItem),
+		Max:      toFloat64(maxItem),
+		Mean:     mean,
+		Variance: variance,
+		Skewness: skewness,
+	}, nil
+}
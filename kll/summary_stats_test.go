@@ -0,0 +1,67 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSummaryStats(t *testing.T) {
+	sk, err := NewKllItemsSketch[float64](200, _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{})
+	assert.NoError(t, err)
+	identity := func(v float64) float64 { return v }
+
+	_, err = sk.GetSummaryStats(identity)
+	assert.Error(t, err)
+
+	// exact mode
+	for _, v := range []float64{1, 2, 3, 4, 10} {
+		sk.Update(v)
+	}
+	stats, err := sk.GetSummaryStats(identity)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), stats.N)
+	assert.Equal(t, 1.0, stats.Min)
+	assert.Equal(t, 10.0, stats.Max)
+	assert.InDelta(t, 4.0, stats.Mean, 1e-12)
+	assert.InDelta(t, 10.0, stats.Variance, 1e-12)
+	// third central moment 180 / 5, over 10^1.5
+	assert.InDelta(t, 36.0/math.Pow(10, 1.5), stats.Skewness, 1e-12)
+
+	// estimation mode, uniform on [0, 1)
+	sk.Reset()
+	n := 1000000
+	for i := 0; i < n; i++ {
+		sk.Update(rand.Float64())
+	}
+	stats, err = sk.GetSummaryStats(identity)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(n), stats.N)
+	eps := sk.GetNormalizedRankError(false)
+	assert.InDelta(t, 0.5, stats.Mean, eps)
+	assert.InDelta(t, 1.0/12, stats.Variance, eps)
+	assert.InDelta(t, 0.0, stats.Skewness, 10*eps)
+
+	_, err = sk.GetSummaryStats(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/apache/datasketches-go/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestVectors replays the KLL test vectors written by cmd/genvectors.
+func TestVectors(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(internal.VectorsPath, "kll_doubles_*.json"))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, files)
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			checkTestVectors(t, file)
+		})
+	}
+}
+
+// checkTestVectors replays the updates of each vector of the file, with the deterministic
+// compaction strategy of the generator, and fails if the serialization of the resulting sketch
+// differs from the expected one.
+func checkTestVectors(t *testing.T, vectorFile string) {
+	file, err := internal.ReadTestVectorFile(vectorFile)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "kll_doubles", file.Family)
+	for _, v := range file.Vectors {
+		sk, err := NewKllItemsSketch[float64](uint16(v.Params["k"]), _DEFAULT_M, common.ItemSketchDoubleComparator(false), common.ItemSketchDoubleSerDe{},
+			WithCompactionStrategy(DeterministicCompactionStrategy{}))
+		if !assert.NoError(t, err, v.Case) {
+			continue
+		}
+		for _, u := range v.Updates {
+			for i := u.From; i < u.From+u.Count; i++ {
+				sk.Update(float64(i))
+			}
+		}
+		bytes, err := sk.ToSlice()
+		assert.NoError(t, err, v.Case)
+		assert.Equal(t, v.Serialized, bytes, "%s: serialized bytes", v.Case)
+
+		expected, err := NewDoublesSketchFromSlice(v.Serialized)
+		if !assert.NoError(t, err, v.Case) {
+			continue
+		}
+		assert.Equal(t, sk.GetN(), expected.GetN(), "%s: n", v.Case)
+		if !sk.IsEmpty() {
+			median, err := sk.GetQuantile(0.5, true)
+			assert.NoError(t, err)
+			assert.Equal(t, median, expected.GetQuantile(0.5), "%s: median", v.Case)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+// timeSerDe serializes each time as its int64 UnixNano with common.ItemSketchLongSerDe.
+// Deserialized times are in UTC.
+type timeSerDe struct {
+	longs common.ItemSketchLongSerDe
+}
+
+func timeComparator(a, b time.Time) bool {
+	return a.UnixNano() < b.UnixNano()
+}
+
+func (f timeSerDe) SizeOf(item time.Time) int {
+	return f.longs.SizeOf(item.UnixNano())
+}
+
+func (f timeSerDe) SizeOfMany(mem []byte, offsetBytes int, numItems int) (int, error) {
+	return f.longs.SizeOfMany(mem, offsetBytes, numItems)
+}
+
+func (f timeSerDe) SerializeOneToSlice(item time.Time) []byte {
+	return f.longs.SerializeOneToSlice(item.UnixNano())
+}
+
+func (f timeSerDe) SerializeManyToSlice(items []time.Time) []byte {
+	nanos := make([]int64, len(items))
+	for i, item := range items {
+		nanos[i] = item.UnixNano()
+	}
+	return f.longs.SerializeManyToSlice(nanos)
+}
+
+func (f timeSerDe) DeserializeManyFromSlice(mem []byte, offsetBytes int, numItems int) ([]time.Time, error) {
+	// ItemSketchLongSerDe does not check the bounds of mem
+	if offsetBytes < 0 || len(mem) < offsetBytes+numItems*8 {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d, %d", len(mem), offsetBytes+numItems*8)
+	}
+	nanos, err := f.longs.DeserializeManyFromSlice(mem, offsetBytes, numItems)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]time.Time, numItems)
+	for i, n := range nanos {
+		items[i] = time.Unix(0, n).UTC()
+	}
+	return items, nil
+}
+
+// TimeSketch is a KLL sketch of time.Time items.
+// It wraps an ItemsSketch[time.Time] that orders the times by their UnixNano, so times with
+// different locations or offsets compare as instants, and serializes every time as an int64 UnixNano.
+//
+// The times are normalized to UTC without monotonic clock reading when presented to the sketch.
+// Only the times representable as an int64 UnixNano, from year 1677 to year 2262, are accepted.
+type TimeSketch struct {
+	sketch *ItemsSketch[time.Time]
+}
+
+// NewTimeSketch creates a new empty TimeSketch with the given k and the default m.
+func NewTimeSketch(k uint16) (*TimeSketch, error) {
+	sketch, err := NewKllItemsSketch[time.Time](k, _DEFAULT_M, timeComparator, timeSerDe{})
+	if err != nil {
+		return nil, err
+	}
+	return &TimeSketch{sketch: sketch}, nil
+}
+
+// NewTimeSketchFromSlice creates a TimeSketch from the bytes returned by ToSlice.
+func NewTimeSketchFromSlice(sl []byte) (*TimeSketch, error) {
+	sketch, err := NewKllItemsSketchFromSlice[time.Time](sl, timeComparator, timeSerDe{})
+	if err != nil {
+		return nil, err
+	}
+	return &TimeSketch{sketch: sketch}, nil
+}
+
+// IsEmpty returns true if the sketch has not seen any time.
+func (s *TimeSketch) IsEmpty() bool {
+	return s.sketch.IsEmpty()
+}
+
+// GetN returns the number of times presented to the sketch.
+func (s *TimeSketch) GetN() uint64 {
+	return s.sketch.GetN()
+}
+
+// GetK returns the configured k of the sketch.
+func (s *TimeSketch) GetK() uint16 {
+	return s.sketch.GetK()
+}
+
+// Update presents a time to the sketch.
+// It returns an error if t is not representable as an int64 UnixNano.
+func (s *TimeSketch) Update(t time.Time) error {
+	nanos := t.UnixNano()
+	normalized := time.Unix(0, nanos).UTC()
+	if !normalized.Equal(t) {
+		return fmt.Errorf("time out of the range of UnixNano: %v", t)
+	}
+	s.sketch.Update(normalized)
+	return nil
+}
+
+// GetMinItem returns the earliest time presented to the sketch.
+func (s *TimeSketch) GetMinItem() (time.Time, error) {
+	return s.sketch.GetMinItem()
+}
+
+// GetMaxItem returns the latest time presented to the sketch.
+func (s *TimeSketch) GetMaxItem() (time.Time, error) {
+	return s.sketch.GetMaxItem()
+}
+
+// GetRank returns the approximate normalized rank of the given time. See ItemsSketch.GetRank.
+func (s *TimeSketch) GetRank(t time.Time, inclusive bool) (float64, error) {
+	return s.sketch.GetRank(t, inclusive)
+}
+
+// GetQuantile returns the approximate time at the given normalized rank. See ItemsSketch.GetQuantile.
+func (s *TimeSketch) GetQuantile(rank float64, inclusive bool) (time.Time, error) {
+	return s.sketch.GetQuantile(rank, inclusive)
+}
+
+// GetQuantileTime returns the approximate time at the given normalized rank, with the INCLUSIVE criterion.
+func (s *TimeSketch) GetQuantileTime(rank float64) (time.Time, error) {
+	return s.sketch.GetQuantile(rank, true)
+}
+
+// GetQuantiles returns the approximate times at the given normalized ranks. See ItemsSketch.GetQuantiles.
+func (s *TimeSketch) GetQuantiles(ranks []float64, inclusive bool) ([]time.Time, error) {
+	return s.sketch.GetQuantiles(ranks, inclusive)
+}
+
+// GetPMF returns the approximate fraction of the times in each of the intervals delimited by splitPoints.
+// See ItemsSketch.GetPMF.
+func (s *TimeSketch) GetPMF(splitPoints []time.Time, inclusive bool) ([]float64, error) {
+	return s.sketch.GetPMF(splitPoints, inclusive)
+}
+
+// GetCDF returns the approximate fraction of the times up to each of the splitPoints.
+// See ItemsSketch.GetCDF.
+func (s *TimeSketch) GetCDF(splitPoints []time.Time, inclusive bool) ([]float64, error) {
+	return s.sketch.GetCDF(splitPoints, inclusive)
+}
+
+// GetNormalizedRankError returns the normalized rank error of the sketch. See ItemsSketch.GetNormalizedRankError.
+func (s *TimeSketch) GetNormalizedRankError(pmf bool) float64 {
+	return s.sketch.GetNormalizedRankError(pmf)
+}
+
+// GetSketch returns the underlying ItemsSketch, for the queries not exposed by TimeSketch.
+func (s *TimeSketch) GetSketch() *ItemsSketch[time.Time] {
+	return s.sketch
+}
+
+// Merge merges other into this sketch.
+func (s *TimeSketch) Merge(other *TimeSketch) error {
+	if other == nil {
+		return errors.New("no sketch provided")
+	}
+	s.sketch.Merge(other.sketch)
+	return nil
+}
+
+// MergeCtx merges other into this sketch unless ctx is done first. See ItemsSketch.MergeCtx.
+func (s *TimeSketch) MergeCtx(ctx context.Context, other *TimeSketch) error {
+	if other == nil {
+		return errors.New("no sketch provided")
+	}
+	return s.sketch.MergeCtx(ctx, other.sketch)
+}
+
+// Reset resets the sketch to its empty state.
+func (s *TimeSketch) Reset() {
+	s.sketch.Reset()
+}
+
+// ToSlice returns the serialized sketch, in the format of an ItemsSketch of int64 UnixNano.
+// See ItemsSketch.ToSlice.
+func (s *TimeSketch) ToSlice() ([]byte, error) {
+	return s.sketch.ToSlice()
+}
+
+// GobEncode implements gob.GobEncoder with the bytes returned by ToSlice.
+func (s *TimeSketch) GobEncode() ([]byte, error) {
+	return s.ToSlice()
+}
+
+// GobDecode implements gob.GobDecoder, replacing the state of the sketch with the one serialized in data.
+// It can decode into a zero TimeSketch.
+func (s *TimeSketch) GobDecode(data []byte) error {
+	sketch, err := NewTimeSketchFromSlice(data)
+	if err != nil {
+		return err
+	}
+	s.sketch = sketch.sketch
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kll
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSketch(t *testing.T) {
+	sk, err := NewTimeSketch(200)
+	assert.NoError(t, err)
+	assert.True(t, sk.IsEmpty())
+	_, err = sk.GetQuantileTime(0.5)
+	assert.Error(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, sk.Update(start.Add(time.Duration(i)*time.Hour)))
+	}
+	assert.Equal(t, uint64(100), sk.GetN())
+	q, err := sk.GetQuantileTime(0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, start.Add(49*time.Hour), q)
+	rank, err := sk.GetRank(start.Add(24*time.Hour), false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.24, rank)
+	cdf, err := sk.GetCDF([]time.Time{start.Add(9 * time.Hour)}, true)
+	assert.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0.1, 1.0}, cdf, 1e-12)
+
+	// the same instants expressed with another offset are not new items
+	tz := time.FixedZone("UTC+5", 5*3600)
+	other, err := NewTimeSketch(200)
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, other.Update(start.Add(time.Duration(i)*time.Hour).In(tz)))
+	}
+	assert.NoError(t, sk.Merge(other))
+	assert.Error(t, sk.Merge(nil))
+	minItem, err := sk.GetMinItem()
+	assert.NoError(t, err)
+	assert.Equal(t, start, minItem)
+	maxItem, err := sk.GetMaxItem()
+	assert.NoError(t, err)
+	assert.True(t, start.Add(99*time.Hour).Equal(maxItem))
+	q, err = sk.GetQuantileTime(0.5)
+	assert.NoError(t, err)
+	assert.True(t, start.Add(49*time.Hour).Equal(q))
+
+	// years beyond the range of UnixNano are rejected
+	assert.Error(t, sk.Update(time.Date(9999, 12, 31, 23, 59, 59, 999999999, time.UTC)))
+	assert.Error(t, sk.Update(time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, uint64(200), sk.GetN())
+}
+
+func TestTimeSketchSerialization(t *testing.T) {
+	times := []time.Time{
+		time.Unix(0, 0),
+		time.Unix(0, -1),
+		time.Unix(-86400*365, 123),
+		time.Date(2038, 1, 19, 3, 14, 7, 0, time.UTC),
+		time.Date(2038, 1, 19, 3, 14, 8, 1, time.FixedZone("UTC-8", -8*3600)),
+		time.Unix(0, math.MinInt64),
+		time.Unix(0, math.MaxInt64),
+		time.Now(),
+	}
+	sk, err := NewTimeSketch(200)
+	assert.NoError(t, err)
+	for _, tm := range times {
+		assert.NoError(t, sk.Update(tm))
+	}
+	sl, err := sk.ToSlice()
+	assert.NoError(t, err)
+	sk2, err := NewTimeSketchFromSlice(sl)
+	assert.NoError(t, err)
+	sl2, err := sk2.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, sl, sl2)
+	for _, tm := range times {
+		r1, err := sk.GetRank(tm, true)
+		assert.NoError(t, err)
+		r2, err := sk2.GetRank(tm, true)
+		assert.NoError(t, err)
+		assert.Equal(t, r1, r2)
+	}
+	items := sk2.GetSketch().GetTotalItemsArray()
+	for _, tm := range times {
+		assert.Contains(t, items, time.Unix(0, tm.UnixNano()).UTC())
+	}
+
+	// estimation mode
+	sk.Reset()
+	for i := 0; i < 100000; i++ {
+		assert.NoError(t, sk.Update(time.Unix(int64(i), 0)))
+	}
+	sl, err = sk.ToSlice()
+	assert.NoError(t, err)
+	sk2, err = NewTimeSketchFromSlice(sl)
+	assert.NoError(t, err)
+	assert.Equal(t, sk.GetN(), sk2.GetN())
+	q1, _ := sk.GetQuantileTime(0.5)
+	q2, _ := sk2.GetQuantileTime(0.5)
+	assert.Equal(t, q1, q2)
+}
@@ -76,6 +76,13 @@ func checkNormalizedRankBounds(rank float64) error {
 	return nil
 }
 
+func checkNumStdDev(numStdDev int) error {
+	if numStdDev < 1 || numStdDev > 3 {
+		return errors.New("numStdDev must be 1, 2 or 3: " + strconv.Itoa(numStdDev))
+	}
+	return nil
+}
+
 func checkItems[C comparable](items []C, compareFn common.CompareFn[C]) error {
 	if len(items) == 1 && internal.IsNil(items[0]) {
 		return errors.New("items must be unique, monotonically increasing and not nil")
@@ -0,0 +1,153 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+type expiringSlot[T any] struct {
+	item     T
+	inserted time.Time
+}
+
+// ExpiringReservoirSketch is a reservoir of at most k items in which the samples expire ttl after their insertion.
+//
+// An item presented to a full reservoir replaces the oldest sample if that sample has expired.
+// Otherwise it replaces a random sample with probability k / n, as in a standard reservoir,
+// where n is the number of items presented since the sketch was created or reset.
+// The samples are kept in a min-heap by insertion time, so an update costs O(log(k)).
+// The sketch is not serializable, so the items can be of any type.
+type ExpiringReservoirSketch[T any] struct {
+	k     int
+	ttl   time.Duration
+	clock func() time.Time
+	n     int64
+	slots expiringHeap[T] // the oldest sample is at index 0
+}
+
+// NewExpiringReservoirSketch constructs a new empty sketch.
+//
+//   - k, the maximum number of samples, at least 2.
+//   - ttl, the time after which a sample expires, it must be positive.
+//   - clock, the source of the current time, time.Now if nil.
+func NewExpiringReservoirSketch[T any](k int, ttl time.Duration, clock func() time.Time) (*ExpiringReservoirSketch[T], error) {
+	if k < _RESERVOIR_MIN_K || k > _RESERVOIR_MAX_K {
+		return nil, fmt.Errorf("k must be at least %d and at most %d: %d", _RESERVOIR_MIN_K, _RESERVOIR_MAX_K, k)
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("ttl must be positive: %v", ttl)
+	}
+	if clock == nil {
+		clock = time.Now
+	}
+	return &ExpiringReservoirSketch[T]{
+		k:     k,
+		ttl:   ttl,
+		clock: clock,
+		slots: expiringHeap[T]{slots: make([]expiringSlot[T], 0, min(k, 128))},
+	}, nil
+}
+
+// GetK returns the maximum number of samples retained by the sketch.
+func (s *ExpiringReservoirSketch[T]) GetK() int {
+	return s.k
+}
+
+// GetN returns the number of items presented to the sketch, including the expired ones.
+func (s *ExpiringReservoirSketch[T]) GetN() int64 {
+	return s.n
+}
+
+// IsEmpty returns true if the sketch has not seen any item.
+func (s *ExpiringReservoirSketch[T]) IsEmpty() bool {
+	return s.n == 0
+}
+
+// Update presents an item to the sketch.
+func (s *ExpiringReservoirSketch[T]) Update(item T) {
+	now := s.clock()
+	s.n++
+	slot := expiringSlot[T]{item: item, inserted: now}
+	if s.slots.Len() < s.k {
+		heap.Push(&s.slots, slot)
+		return
+	}
+	if s.isExpired(s.slots.slots[0], now) {
+		s.slots.slots[0] = slot
+		heap.Fix(&s.slots, 0)
+		return
+	}
+	if rand.Int63n(s.n) < int64(s.k) {
+		// the heap order is a permutation of the samples, so a random index is a random sample
+		i := rand.Intn(s.k)
+		s.slots.slots[i] = slot
+		heap.Fix(&s.slots, i)
+	}
+}
+
+// GetSamples returns the samples that have not expired.
+func (s *ExpiringReservoirSketch[T]) GetSamples() []T {
+	now := s.clock()
+	samples := make([]T, 0, s.slots.Len())
+	for _, slot := range s.slots.slots {
+		if !s.isExpired(slot, now) {
+			samples = append(samples, slot.item)
+		}
+	}
+	return samples
+}
+
+// Reset resets the sketch to its empty state, keeping k, ttl and the clock.
+func (s *ExpiringReservoirSketch[T]) Reset() {
+	s.n = 0
+	s.slots.slots = s.slots.slots[:0]
+}
+
+func (s *ExpiringReservoirSketch[T]) isExpired(slot expiringSlot[T], now time.Time) bool {
+	return now.Sub(slot.inserted) >= s.ttl
+}
+
+type expiringHeap[T any] struct {
+	slots []expiringSlot[T]
+}
+
+func (h *expiringHeap[T]) Len() int {
+	return len(h.slots)
+}
+
+func (h *expiringHeap[T]) Less(i, j int) bool {
+	return h.slots[i].inserted.Before(h.slots[j].inserted)
+}
+
+func (h *expiringHeap[T]) Swap(i, j int) {
+	h.slots[i], h.slots[j] = h.slots[j], h.slots[i]
+}
+
+func (h *expiringHeap[T]) Push(x any) {
+	h.slots = append(h.slots, x.(expiringSlot[T]))
+}
+
+func (h *expiringHeap[T]) Pop() any {
+	last := h.slots[len(h.slots)-1]
+	h.slots = h.slots[:len(h.slots)-1]
+	return last
+}
@@ -0,0 +1,116 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpiringReservoir_InvalidArgs(t *testing.T) {
+	_, err := NewExpiringReservoirSketch[int](1, time.Second, nil)
+	assert.Error(t, err)
+	_, err = NewExpiringReservoirSketch[int](10, 0, nil)
+	assert.Error(t, err)
+}
+
+func TestExpiringReservoir(t *testing.T) {
+	now := time.Unix(1000, 0)
+	clock := func() time.Time { return now }
+	sk, err := NewExpiringReservoirSketch[string](4, time.Minute, clock)
+	assert.NoError(t, err)
+	assert.True(t, sk.IsEmpty())
+
+	for _, item := range []string{"a", "b", "c", "d"} {
+		sk.Update(item)
+		now = now.Add(time.Second)
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "c", "d"}, sk.GetSamples())
+
+	// "a" expires first, then "b"
+	now = time.Unix(1060, 0)
+	assert.ElementsMatch(t, []string{"b", "c", "d"}, sk.GetSamples())
+	now = time.Unix(1061, 0)
+	assert.ElementsMatch(t, []string{"c", "d"}, sk.GetSamples())
+
+	// new items replace the expired samples, oldest first
+	sk.Update("e")
+	assert.ElementsMatch(t, []string{"c", "d", "e"}, sk.GetSamples())
+	sk.Update("f")
+	assert.ElementsMatch(t, []string{"c", "d", "e", "f"}, sk.GetSamples())
+	assert.Equal(t, int64(6), sk.GetN())
+
+	// everything expires
+	now = now.Add(time.Hour)
+	assert.Empty(t, sk.GetSamples())
+	assert.Equal(t, int64(6), sk.GetN())
+
+	sk.Reset()
+	assert.True(t, sk.IsEmpty())
+	assert.Empty(t, sk.GetSamples())
+}
+
+func TestExpiringReservoir_OldestFirst(t *testing.T) {
+	// the insertion times are not in the order of the updates
+	now := time.Unix(1005, 0)
+	clock := func() time.Time { return now }
+	sk, err := NewExpiringReservoirSketch[string](3, 10*time.Second, clock)
+	assert.NoError(t, err)
+	sk.Update("a")
+	now = time.Unix(1000, 0)
+	sk.Update("b")
+	now = time.Unix(1003, 0)
+	sk.Update("c")
+
+	now = time.Unix(1010, 0)
+	assert.ElementsMatch(t, []string{"a", "c"}, sk.GetSamples())
+	sk.Update("d")
+	assert.ElementsMatch(t, []string{"a", "c", "d"}, sk.GetSamples())
+	now = time.Unix(1013, 0)
+	sk.Update("e")
+	assert.ElementsMatch(t, []string{"a", "d", "e"}, sk.GetSamples())
+	now = time.Unix(1015, 0)
+	sk.Update("f")
+	assert.ElementsMatch(t, []string{"d", "e", "f"}, sk.GetSamples())
+}
+
+func TestExpiringReservoir_NoExpiration(t *testing.T) {
+	// without expirations the sketch is a standard reservoir
+	k := 10
+	n := 100
+	numTrials := 5000
+	counts := make([]int, n)
+	for trial := 0; trial < numTrials; trial++ {
+		sk, err := NewExpiringReservoirSketch[int](k, time.Hour, nil)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			sk.Update(i)
+		}
+		samples := sk.GetSamples()
+		assert.Equal(t, k, len(samples))
+		for _, item := range samples {
+			counts[item]++
+		}
+	}
+	expected := float64(numTrials*k) / float64(n)
+	for i, count := range counts {
+		assert.InDelta(t, expected, count, expected*0.25, "item %d", i)
+	}
+}
@@ -0,0 +1,103 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const (
+	// Preamble byte addresses
+	_PREAMBLE_LONGS_BYTE   = 0 // low 6 bits
+	_LG_RESIZE_FACTOR_BIT  = 6 // upper 2 bits of byte 0
+	_SER_VER_BYTE          = 1
+	_FAMILY_BYTE           = 2
+	_FLAGS_BYTE            = 3
+	_K_INT                 = 4  // to 7
+	_N_LONG                = 8  // to 15
+	_H_COUNT_INT           = 16 // to 19, VarOpt only
+	_R_COUNT_INT           = 20 // to 23, VarOpt only
+	_TOTAL_WEIGHT_R_DOUBLE = 24 // to 31, VarOpt only
+
+	// Flag bit masks
+	_EMPTY_FLAG_MASK  = 4
+	_GADGET_FLAG_MASK = 128
+
+	_RESERVOIR_SER_VER        = 2
+	_RESERVOIR_PRELONGS_EMPTY = 1
+	_RESERVOIR_PRELONGS_FULL  = 2
+
+	_VAROPT_SER_VER           = 2
+	_VAROPT_PRELONGS_EMPTY    = 1
+	_VAROPT_PRELONGS_WARMUP   = 3
+	_VAROPT_PRELONGS_FULL     = 4
+	_DEFAULT_LG_RESIZE_FACTOR = 3 // ResizeFactor.X8 in Java, only recorded for compatibility
+)
+
+func checkPreambleSize(preamble []byte) error {
+	if len(preamble) < 8 {
+		return errors.New("preamble is too small")
+	}
+	preLongs := extractPreLongs(preamble)
+	if len(preamble) < preLongs<<3 {
+		return errors.New("preamble is too small")
+	}
+	return nil
+}
+
+func extractPreLongs(preamble []byte) int {
+	return int(preamble[_PREAMBLE_LONGS_BYTE] & 0x3F)
+}
+
+func extractSerVer(preamble []byte) int {
+	return int(preamble[_SER_VER_BYTE])
+}
+
+func extractFamilyID(preamble []byte) int {
+	return int(preamble[_FAMILY_BYTE])
+}
+
+func extractFlags(preamble []byte) int {
+	return int(preamble[_FLAGS_BYTE])
+}
+
+func extractK(preamble []byte) int {
+	return int(int32(binary.LittleEndian.Uint32(preamble[_K_INT:])))
+}
+
+func extractN(preamble []byte) int64 {
+	return int64(binary.LittleEndian.Uint64(preamble[_N_LONG:]))
+}
+
+func extractHRegionItemCount(preamble []byte) int {
+	return int(int32(binary.LittleEndian.Uint32(preamble[_H_COUNT_INT:])))
+}
+
+func extractRRegionItemCount(preamble []byte) int {
+	return int(int32(binary.LittleEndian.Uint32(preamble[_R_COUNT_INT:])))
+}
+
+func extractTotalRWeight(preamble []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(preamble[_TOTAL_WEIGHT_R_DOUBLE:]))
+}
+
+func insertPreLongsAndResizeFactor(preamble []byte, preLongs int) {
+	preamble[_PREAMBLE_LONGS_BYTE] = byte(preLongs&0x3F) | byte(_DEFAULT_LG_RESIZE_FACTOR<<_LG_RESIZE_FACTOR_BIT)
+}
@@ -0,0 +1,487 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/apache/datasketches-go/internal"
+)
+
+const (
+	_RESERVOIR_MIN_K = 2
+	_RESERVOIR_MAX_K = (1 << 31) - 1
+)
+
+// ReservoirItemsSketch is a sketch that maintains a uniform random sample of at most k items of a stream.
+//
+// The first k items fill the reservoir. After that, the number of items to skip before the next
+// item replaces a random slot of the reservoir is drawn with Vitter's Algorithm Z, so the cost of
+// an update is amortized constant rather than one random number per item.
+//
+// Once an item is presented with UpdateWeighted, the sketch samples with Chao's algorithm for all
+// the following items, and it can no longer be serialized nor merged.
+type ReservoirItemsSketch[T any] struct {
+	k     int
+	n     int64
+	data  []T
+	serde common.ItemSketchSerde[T]
+	skip  int64   // number of items still to skip before the next replacement
+	w     float64 // state of Algorithm Z
+
+	// weighted sampling, only used after the first call to UpdateWeighted
+	weighted      bool
+	weights       []float64
+	numRegular    int     // number of regular samples, the others are overweight, see updateWeighted
+	regularWeight float64 // total weight of the stream minus the weight of the overweight samples
+}
+
+// WeightedSample is a sample of a weighted reservoir with its weight and its inclusion probability.
+type WeightedSample[T any] struct {
+	Item        T
+	Weight      float64
+	Probability float64
+}
+
+// NewReservoirItemsSketch constructs a new empty sketch with a maximum sample size of k,
+// using serde to serialize the items.
+func NewReservoirItemsSketch[T any](k int, serde common.ItemSketchSerde[T]) (*ReservoirItemsSketch[T], error) {
+	if k < _RESERVOIR_MIN_K || k > _RESERVOIR_MAX_K {
+		return nil, fmt.Errorf("k must be at least %d and at most %d: %d", _RESERVOIR_MIN_K, _RESERVOIR_MAX_K, k)
+	}
+	if serde == nil {
+		return nil, errors.New("no SerDe provided")
+	}
+	return &ReservoirItemsSketch[T]{
+		k:     k,
+		data:  make([]T, 0, min(k, 128)),
+		serde: serde,
+	}, nil
+}
+
+// NewReservoirItemsSketchFromSlice constructs a sketch from its serialized form, using serde to deserialize the items.
+func NewReservoirItemsSketchFromSlice[T any](sl []byte, serde common.ItemSketchSerde[T]) (*ReservoirItemsSketch[T], error) {
+	if serde == nil {
+		return nil, errors.New("no SerDe provided")
+	}
+	if err := checkPreambleSize(sl); err != nil {
+		return nil, err
+	}
+	preLongs := extractPreLongs(sl)
+	serVer := extractSerVer(sl)
+	familyID := extractFamilyID(sl)
+	flags := extractFlags(sl)
+	k := extractK(sl)
+	empty := (flags & _EMPTY_FLAG_MASK) != 0
+
+	if serVer != _RESERVOIR_SER_VER {
+		return nil, fmt.Errorf("possible corruption: ser ver must be %d: %d", _RESERVOIR_SER_VER, serVer)
+	}
+	if familyID != internal.FamilyEnum.Reservoir.Id {
+		return nil, fmt.Errorf("possible corruption: familyID must be %d: %d", internal.FamilyEnum.Reservoir.Id, familyID)
+	}
+	sketch, err := NewReservoirItemsSketch[T](k, serde)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		if preLongs != _RESERVOIR_PRELONGS_EMPTY {
+			return nil, fmt.Errorf("possible corruption: empty sketch must have %d preLongs: %d", _RESERVOIR_PRELONGS_EMPTY, preLongs)
+		}
+		return sketch, nil
+	}
+	if preLongs != _RESERVOIR_PRELONGS_FULL {
+		return nil, fmt.Errorf("possible corruption: preLongs must be %d: %d", _RESERVOIR_PRELONGS_FULL, preLongs)
+	}
+
+	n := extractN(sl)
+	if n <= 0 {
+		return nil, fmt.Errorf("possible corruption: non-empty sketch must have a positive n: %d", n)
+	}
+	numItems := int(min(n, int64(k)))
+	offset := preLongs << 3
+	itemsBytes, err := serde.SizeOfMany(sl, offset, numItems)
+	if err != nil {
+		return nil, err
+	}
+	if len(sl) < offset+itemsBytes {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d, %d", len(sl), offset+itemsBytes)
+	}
+	items, err := serde.DeserializeManyFromSlice(sl, offset, numItems)
+	if err != nil {
+		return nil, err
+	}
+	sketch.data = append(make([]T, 0, k), items...)
+	sketch.n = n
+	if n >= int64(k) {
+		// the skip count only depends on k and n, so it is drawn afresh instead of being serialized
+		sketch.skip = reservoirSkip(int64(k), n, &sketch.w)
+	}
+	return sketch, nil
+}
+
+// GetK returns the maximum number of samples retained by the sketch.
+func (s *ReservoirItemsSketch[T]) GetK() int {
+	return s.k
+}
+
+// GetN returns the number of items presented to the sketch.
+func (s *ReservoirItemsSketch[T]) GetN() int64 {
+	return s.n
+}
+
+// GetNumSamples returns the number of samples currently in the sketch.
+func (s *ReservoirItemsSketch[T]) GetNumSamples() int {
+	return len(s.data)
+}
+
+// IsEmpty returns true if the sketch has not seen any item.
+func (s *ReservoirItemsSketch[T]) IsEmpty() bool {
+	return s.n == 0
+}
+
+// Update presents an item to the sketch.
+func (s *ReservoirItemsSketch[T]) Update(item T) {
+	if s.weighted {
+		s.updateWeighted(item, 1)
+		return
+	}
+	if s.n < int64(s.k) {
+		s.data = append(s.data, item)
+		s.n++
+		if s.n == int64(s.k) {
+			s.skip = reservoirSkip(int64(s.k), s.n, &s.w)
+		}
+		return
+	}
+
+	s.n++
+	if s.skip > 0 {
+		s.skip--
+		return
+	}
+	s.data[rand.Intn(s.k)] = item
+	s.skip = reservoirSkip(int64(s.k), s.n, &s.w)
+}
+
+// UpdateWeighted presents an item with the given positive weight to the sketch, with Chao's algorithm:
+// once the reservoir is full, the item replaces a sample with probability k * weight / W,
+// where W is the total weight presented so far, including the weight of the item.
+// An item heavier than W / k is overweight: it is always accepted and kept while it stays overweight,
+// and k and W are reduced by the count and the weight of the overweight items for the other ones.
+// The items presented before the first call to UpdateWeighted count with a weight of 1.
+func (s *ReservoirItemsSketch[T]) UpdateWeighted(item T, weight float64) error {
+	if !(weight > 0) || math.IsInf(weight, 0) {
+		return fmt.Errorf("weight must be positive and finite: %f", weight)
+	}
+	if !s.weighted {
+		s.weighted = true
+		s.weights = make([]float64, len(s.data), cap(s.data))
+		for i := range s.weights {
+			s.weights[i] = 1
+		}
+		if len(s.data) == s.k {
+			// the uniform samples are regular, with the inclusion probability k / n
+			s.numRegular = s.k
+			s.regularWeight = float64(s.n)
+		}
+	}
+	s.updateWeighted(item, weight)
+	return nil
+}
+
+// updateWeighted is one step of Chao's algorithm.
+//
+// Once the reservoir is full, the samples are split in two regions: the regular items in [0, numRegular),
+// and the overweight items in [numRegular, k), kept in a min-heap by weight whose root is at k - 1.
+// The overweight items are included with probability 1. The regular items have the inclusion probability
+// k' * weight / W', where k' is numRegular and W' is regularWeight, the total weight minus the weight of
+// the overweight items. An item is overweight while k' * weight >= W', k' and W' excluding it.
+//
+// The new item is first taken as overweight, then the lightest overweight items which no longer are
+// are demoted to the regular region. The new item is accepted with its inclusion probability p_n, and
+// replaces the sample j with probability (1 - p_j(n) / p_j(n-1)) / p_n, which brings the inclusion probability
+// of every sample from p_j(n-1) to p_j(n). It is the same for all the samples regular before the update,
+// and 1 - p_j(n) for the samples demoted by the update. An update costs O(log(k)) amortized.
+func (s *ReservoirItemsSketch[T]) updateWeighted(item T, weight float64) {
+	s.n++
+	if len(s.data) < s.k {
+		s.data = append(s.data, item)
+		s.weights = append(s.weights, weight)
+		if len(s.data) == s.k {
+			// all the samples are included with probability 1 until the reservoir is full
+			s.numRegular = 0
+			s.regularWeight = 0
+			for i := s.k/2 - 1; i >= 0; i-- {
+				s.siftDownOverweight(i)
+			}
+		}
+		return
+	}
+
+	prevNumRegular := s.numRegular
+	prevRegularWeight := s.regularWeight
+	newOverweight := true
+	regularK := float64(s.numRegular - 1) // k minus the count of overweight items, the new one included
+	for {
+		// the lightest overweight item leaves first, the root of the heap is at k - 1
+		fromHeap := s.numRegular < s.k && (!newOverweight || s.weights[s.k-1] < weight)
+		if !fromHeap && !newOverweight {
+			break
+		}
+		lightest := weight
+		if fromHeap {
+			lightest = s.weights[s.k-1]
+		}
+		if regularK*lightest >= s.regularWeight {
+			break
+		}
+		regularK++
+		s.regularWeight += lightest
+		if fromHeap {
+			s.popOverweight()
+		} else {
+			newOverweight = false
+		}
+	}
+
+	probability := 1.0
+	if !newOverweight {
+		probability = min(1, regularK*weight/s.regularWeight)
+	}
+	if rand.Float64() >= probability {
+		return
+	}
+
+	// the samples demoted by this update are in [prevNumRegular, numRegular)
+	ratio := regularK / s.regularWeight
+	demotedMass := 0.0
+	for i := prevNumRegular; i < s.numRegular; i++ {
+		demotedMass += max(0, 1-ratio*s.weights[i])
+	}
+	regularMass := max(0, float64(prevNumRegular)-ratio*prevRegularWeight)
+	slot := s.numRegular - 1
+	r := rand.Float64() * (demotedMass + regularMass)
+	if r >= demotedMass && prevNumRegular > 0 {
+		slot = rand.Intn(prevNumRegular)
+	} else {
+		for i := prevNumRegular; i < s.numRegular; i++ {
+			r -= max(0, 1-ratio*s.weights[i])
+			if r < 0 {
+				slot = i
+				break
+			}
+		}
+	}
+
+	if !newOverweight {
+		s.data[slot] = item
+		s.weights[slot] = weight
+		return
+	}
+	// the last regular slot becomes the last slot of the heap
+	last := s.numRegular - 1
+	s.data[slot] = s.data[last]
+	s.weights[slot] = s.weights[last]
+	s.numRegular--
+	s.data[last] = item
+	s.weights[last] = weight
+	s.siftUpOverweight(s.k - 1 - last)
+}
+
+// overweightSlot returns the slot of the i-th node of the heap of the overweight items.
+func (s *ReservoirItemsSketch[T]) overweightSlot(i int) int {
+	return s.k - 1 - i
+}
+
+func (s *ReservoirItemsSketch[T]) swapSlots(a, b int) {
+	s.data[a], s.data[b] = s.data[b], s.data[a]
+	s.weights[a], s.weights[b] = s.weights[b], s.weights[a]
+}
+
+// popOverweight moves the lightest overweight item to the end of the regular region.
+func (s *ReservoirItemsSketch[T]) popOverweight() {
+	last := s.k - s.numRegular - 1
+	s.swapSlots(s.overweightSlot(0), s.overweightSlot(last))
+	s.numRegular++
+	s.siftDownOverweight(0)
+}
+
+func (s *ReservoirItemsSketch[T]) siftUpOverweight(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if s.weights[s.overweightSlot(i)] >= s.weights[s.overweightSlot(parent)] {
+			return
+		}
+		s.swapSlots(s.overweightSlot(i), s.overweightSlot(parent))
+		i = parent
+	}
+}
+
+func (s *ReservoirItemsSketch[T]) siftDownOverweight(i int) {
+	size := s.k - s.numRegular
+	for {
+		child := 2*i + 1
+		if child >= size {
+			return
+		}
+		if child+1 < size && s.weights[s.overweightSlot(child+1)] < s.weights[s.overweightSlot(child)] {
+			child++
+		}
+		if s.weights[s.overweightSlot(i)] <= s.weights[s.overweightSlot(child)] {
+			return
+		}
+		s.swapSlots(s.overweightSlot(i), s.overweightSlot(child))
+		i = child
+	}
+}
+
+// IsWeighted returns true if an item was presented to the sketch with UpdateWeighted.
+func (s *ReservoirItemsSketch[T]) IsWeighted() bool {
+	return s.weighted
+}
+
+// GetWeightedSamples returns the items in the reservoir with their weights and their inclusion probabilities.
+// The probabilities are the ones of Chao's algorithm after the last update: 1 for the overweight items
+// and k' * weight / W' for the others, see UpdateWeighted, so that weight / probability is an unbiased
+// Horvitz-Thompson estimate of the total weight.
+// Without weighted updates, the weights are 1 and the probabilities are min(1, k / n).
+func (s *ReservoirItemsSketch[T]) GetWeightedSamples() []WeightedSample[T] {
+	samples := make([]WeightedSample[T], len(s.data))
+	for i, item := range s.data {
+		weight := 1.0
+		probability := min(1, float64(s.k)/float64(s.n))
+		if s.weighted {
+			weight = s.weights[i]
+			probability = 1
+			if len(s.data) == s.k && i < s.numRegular {
+				probability = min(1, float64(s.numRegular)*weight/s.regularWeight)
+			}
+		}
+		samples[i] = WeightedSample[T]{
+			Item:        item,
+			Weight:      weight,
+			Probability: probability,
+		}
+	}
+	return samples
+}
+
+// GetSamples returns a copy of the items in the reservoir.
+func (s *ReservoirItemsSketch[T]) GetSamples() []T {
+	samples := make([]T, len(s.data))
+	copy(samples, s.data)
+	return samples
+}
+
+// Reset resets the sketch to its empty state, keeping k and the serde.
+func (s *ReservoirItemsSketch[T]) Reset() {
+	s.n = 0
+	s.data = s.data[:0]
+	s.skip = 0
+	s.w = 0
+	s.weighted = false
+	s.weights = nil
+	s.numRegular = 0
+	s.regularWeight = 0
+}
+
+// ToSlice serializes the sketch in the format of the Java ReservoirItemsSketch.
+// A sketch with weighted updates cannot be serialized, as the format does not hold the weights.
+func (s *ReservoirItemsSketch[T]) ToSlice() ([]byte, error) {
+	if s.weighted {
+		return nil, errors.New("a weighted reservoir cannot be serialized")
+	}
+	empty := s.IsEmpty()
+	preLongs := _RESERVOIR_PRELONGS_FULL
+	flags := 0
+	var itemBytes []byte
+	if empty {
+		preLongs = _RESERVOIR_PRELONGS_EMPTY
+		flags |= _EMPTY_FLAG_MASK
+	} else {
+		itemBytes = s.serde.SerializeManyToSlice(s.data)
+	}
+	out := make([]byte, (preLongs<<3)+len(itemBytes))
+
+	insertPreLongsAndResizeFactor(out, preLongs)
+	out[_SER_VER_BYTE] = _RESERVOIR_SER_VER
+	out[_FAMILY_BYTE] = byte(internal.FamilyEnum.Reservoir.Id)
+	out[_FLAGS_BYTE] = byte(flags)
+	binary.LittleEndian.PutUint32(out[_K_INT:], uint32(s.k))
+	if empty {
+		return out, nil
+	}
+	binary.LittleEndian.PutUint64(out[_N_LONG:], uint64(s.n))
+	copy(out[preLongs<<3:], itemBytes)
+	return out, nil
+}
+
+// GetImplicitSampleWeight returns the number of stream items represented by each sample, n / k
+// in sampling mode and 1 while the reservoir is filling.
+func (s *ReservoirItemsSketch[T]) GetImplicitSampleWeight() float64 {
+	if s.n < int64(s.k) {
+		return 1.0
+	}
+	return float64(s.n) / float64(s.k)
+}
+
+func (s *ReservoirItemsSketch[T]) copy() *ReservoirItemsSketch[T] {
+	c := *s
+	c.data = append(make([]T, 0, cap(s.data)), s.data...)
+	if s.weighted {
+		c.weights = append(make([]float64, 0, cap(s.weights)), s.weights...)
+	}
+	return &c
+}
+
+// downsampledCopy returns a copy of the sketch with the smaller maxK.
+// The samples all have the same implicit weight, so they are fed to the copy as items of weight 1,
+// and n is adjusted at the end to restore the implicit weight.
+func (s *ReservoirItemsSketch[T]) downsampledCopy(maxK int) (*ReservoirItemsSketch[T], error) {
+	c, err := NewReservoirItemsSketch[T](maxK, s.serde)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range s.data {
+		c.Update(item)
+	}
+	if c.n < s.n {
+		c.forceIncrementN(s.n - c.n)
+	}
+	return c, nil
+}
+
+// insertAt replaces the sample at pos with item, without changing n.
+func (s *ReservoirItemsSketch[T]) insertAt(item T, pos int) {
+	s.data[pos] = item
+}
+
+// forceIncrementN adds inc to the number of items seen, without changing the samples.
+// The reservoir must be full.
+func (s *ReservoirItemsSketch[T]) forceIncrementN(inc int64) {
+	s.n += inc
+	// the pending skip was drawn for the old n
+	s.w = 0
+	s.skip = reservoirSkip(int64(s.k), s.n, &s.w)
+}
@@ -0,0 +1,354 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReservoir_InvalidArgs(t *testing.T) {
+	_, err := NewReservoirItemsSketch[int64](1, common.ItemSketchLongSerDe{})
+	assert.Error(t, err)
+	_, err = NewReservoirItemsSketch[int64](10, nil)
+	assert.Error(t, err)
+}
+
+func TestReservoir_Warmup(t *testing.T) {
+	k := 100
+	sk, err := NewReservoirItemsSketch[int64](k, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	assert.True(t, sk.IsEmpty())
+	for i := 0; i < k; i++ {
+		sk.Update(int64(i))
+	}
+	assert.Equal(t, int64(k), sk.GetN())
+	samples := sk.GetSamples()
+	assert.Equal(t, k, len(samples))
+	for i, item := range samples {
+		assert.Equal(t, int64(i), item)
+	}
+
+	sk.Reset()
+	assert.True(t, sk.IsEmpty())
+	assert.Equal(t, 0, sk.GetNumSamples())
+}
+
+func TestReservoir_Uniform(t *testing.T) {
+	// n is well above 22 * k, so both Algorithm X and Algorithm Z are exercised
+	k := 10
+	n := 1000
+	numTrials := 5000
+	counts := make([]int, n)
+	for trial := 0; trial < numTrials; trial++ {
+		sk, err := NewReservoirItemsSketch[int64](k, common.ItemSketchLongSerDe{})
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			sk.Update(int64(i))
+		}
+		assert.Equal(t, k, sk.GetNumSamples())
+		for _, item := range sk.GetSamples() {
+			counts[item]++
+		}
+	}
+
+	// each item is retained with probability k / n
+	numBuckets := 10
+	bucketSize := n / numBuckets
+	expected := float64(numTrials*k) / float64(numBuckets)
+	for b := 0; b < numBuckets; b++ {
+		sum := 0
+		for i := b * bucketSize; i < (b+1)*bucketSize; i++ {
+			sum += counts[i]
+		}
+		assert.InDelta(t, expected, float64(sum), expected*0.05, "bucket %d", b)
+	}
+}
+
+func TestReservoir_SerializeDeserialize(t *testing.T) {
+	k := 32
+	serde := common.ItemSketchStringSerDe{}
+	for _, n := range []int{0, 1, 10, k, k + 1, 1000} {
+		sk, err := NewReservoirItemsSketch[string](k, serde)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			sk.Update(strconv.Itoa(i))
+		}
+		bytes, err := sk.ToSlice()
+		assert.NoError(t, err)
+		sk2, err := NewReservoirItemsSketchFromSlice[string](bytes, serde)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetK(), sk2.GetK())
+		assert.Equal(t, sk.GetN(), sk2.GetN())
+		assert.Equal(t, sk.GetSamples(), sk2.GetSamples())
+		bytes2, err := sk2.ToSlice()
+		assert.NoError(t, err)
+		assert.Equal(t, bytes, bytes2)
+
+		// a deserialized sketch can keep being updated
+		sk2.Update("x")
+		assert.Equal(t, sk.GetN()+1, sk2.GetN())
+	}
+}
+
+func TestReservoir_DeserializeCorrupt(t *testing.T) {
+	serde := common.ItemSketchLongSerDe{}
+	sk, err := NewReservoirItemsSketch[int64](8, serde)
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		sk.Update(int64(i))
+	}
+	bytes, err := sk.ToSlice()
+	assert.NoError(t, err)
+
+	_, err = NewReservoirItemsSketchFromSlice[int64](bytes[:4], serde)
+	assert.Error(t, err)
+	_, err = NewReservoirItemsSketchFromSlice[int64](bytes, nil)
+	assert.Error(t, err)
+	_, err = NewReservoirItemsSketchFromSlice[int64](bytes[:20], serde)
+	assert.Error(t, err)
+
+	badFamily := append([]byte{}, bytes...)
+	badFamily[_FAMILY_BYTE] = byte(13)
+	_, err = NewReservoirItemsSketchFromSlice[int64](badFamily, serde)
+	assert.Error(t, err)
+
+	badSerVer := append([]byte{}, bytes...)
+	badSerVer[_SER_VER_BYTE] = 1
+	_, err = NewReservoirItemsSketchFromSlice[int64](badSerVer, serde)
+	assert.Error(t, err)
+}
+
+func TestReservoir_UpdateWeighted(t *testing.T) {
+	sk, err := NewReservoirItemsSketch[int64](10, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	assert.Error(t, sk.UpdateWeighted(1, 0))
+	assert.Error(t, sk.UpdateWeighted(1, -1))
+	assert.False(t, sk.IsWeighted())
+
+	// the items seen before the first weighted update count with a weight of 1
+	for i := int64(0); i < 5; i++ {
+		sk.Update(i)
+	}
+	assert.NoError(t, sk.UpdateWeighted(5, 3))
+	assert.True(t, sk.IsWeighted())
+	samples := sk.GetWeightedSamples()
+	assert.Equal(t, 6, len(samples))
+	for _, sample := range samples {
+		assert.Equal(t, 1.0, sample.Probability)
+	}
+	assert.Equal(t, 3.0, samples[5].Weight)
+
+	for i := int64(6); i < 100; i++ {
+		sk.Update(i)
+	}
+	assert.Equal(t, int64(100), sk.GetN())
+	for _, sample := range sk.GetWeightedSamples() {
+		assert.InDelta(t, 10*sample.Weight/102, sample.Probability, 1e-12)
+	}
+
+	_, err = sk.ToSlice()
+	assert.Error(t, err)
+	union, err := NewReservoirItemsUnion[int64](10, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	assert.Error(t, union.UpdateSketch(sk))
+
+	sk.Reset()
+	assert.False(t, sk.IsWeighted())
+}
+
+func TestReservoir_WeightedOverweightItem(t *testing.T) {
+	// one item weighs 10 times more than all the others together
+	k := 10
+	n := 100
+	heavy := int64(n / 2)
+	numTrials := 5000
+	counts := make([]int, n+1)
+	totalEstimate := 0.0
+	for trial := 0; trial < numTrials; trial++ {
+		sk, err := NewReservoirItemsSketch[int64](k, common.ItemSketchLongSerDe{})
+		assert.NoError(t, err)
+		for i := int64(0); i <= int64(n); i++ {
+			weight := 1.0
+			if i == heavy {
+				weight = 1000
+			}
+			assert.NoError(t, sk.UpdateWeighted(i, weight))
+		}
+		samples := sk.GetWeightedSamples()
+		assert.Equal(t, k, len(samples))
+		estimate := 0.0
+		for _, sample := range samples {
+			counts[sample.Item]++
+			estimate += sample.Weight / sample.Probability
+			if sample.Item == heavy {
+				assert.Equal(t, 1.0, sample.Probability)
+				assert.Equal(t, 1000.0, sample.Weight)
+			} else {
+				// the other items share the k - 1 remaining slots
+				assert.InDelta(t, float64(k-1)/float64(n), sample.Probability, 1e-12)
+			}
+		}
+		totalEstimate += estimate
+	}
+	assert.Equal(t, numTrials, counts[heavy])
+	expected := float64(numTrials*(k-1)) / float64(n)
+	for i, count := range counts {
+		if int64(i) != heavy {
+			assert.InDelta(t, expected, count, expected*0.3, "item %d", i)
+		}
+	}
+	assert.InDelta(t, 1000+float64(n), totalEstimate/float64(numTrials), 1e-9)
+}
+
+func TestReservoir_WeightedFrequencies(t *testing.T) {
+	// odd items weigh 4 times more than even items
+	k := 10
+	n := 100
+	numTrials := 5000
+	counts := make([]int, 2)
+	for trial := 0; trial < numTrials; trial++ {
+		sk, err := NewReservoirItemsSketch[int64](k, common.ItemSketchLongSerDe{})
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			weight := 1.0
+			if i%2 == 1 {
+				weight = 4.0
+			}
+			assert.NoError(t, sk.UpdateWeighted(int64(i), weight))
+		}
+		for _, item := range sk.GetSamples() {
+			counts[item%2]++
+		}
+	}
+	ratio := float64(counts[1]) / float64(counts[0])
+	assert.Greater(t, ratio, 3.0)
+	assert.Less(t, ratio, 5.0)
+}
+
+func TestReservoir_WeightedDemotion(t *testing.T) {
+	// the heavy item starts overweight and becomes regular once the stream outweighs it
+	k := 4
+	sk, err := NewReservoirItemsSketch[int64](k, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	for i := int64(0); i < int64(k); i++ {
+		assert.NoError(t, sk.UpdateWeighted(i, 1))
+	}
+	assert.NoError(t, sk.UpdateWeighted(100, 50))
+	for _, sample := range sk.GetWeightedSamples() {
+		if sample.Item == 100 {
+			assert.Equal(t, 1.0, sample.Probability)
+		}
+	}
+	for i := int64(k); i < 1000; i++ {
+		assert.NoError(t, sk.UpdateWeighted(i, 1))
+	}
+	// the total weight is 1050, 4 * 50 < 1050 so every item is regular again
+	for _, sample := range sk.GetWeightedSamples() {
+		assert.InDelta(t, float64(k)*sample.Weight/1050, sample.Probability, 1e-12)
+	}
+
+	// updates do not allocate once the reservoir is full
+	weight := 1.0
+	allocs := testing.AllocsPerRun(1000, func() {
+		weight = 1 + math.Mod(weight*7, 100)
+		_ = sk.UpdateWeighted(1, weight)
+	})
+	assert.Equal(t, 0.0, allocs)
+}
+
+// int64SliceSerDe serializes each []int64 as its length followed by its items, all as longs.
+type int64SliceSerDe struct{}
+
+func (int64SliceSerDe) SizeOf(item []int64) int {
+	return 8 * (1 + len(item))
+}
+
+func (int64SliceSerDe) SizeOfMany(mem []byte, offsetBytes int, numItems int) (int, error) {
+	offset := offsetBytes
+	for i := 0; i < numItems; i++ {
+		if offset+8 > len(mem) {
+			return 0, errors.New("offset out of bounds")
+		}
+		offset += 8 * (1 + int(binary.LittleEndian.Uint64(mem[offset:])))
+	}
+	return offset - offsetBytes, nil
+}
+
+func (s int64SliceSerDe) SerializeOneToSlice(item []int64) []byte {
+	return s.SerializeManyToSlice([][]int64{item})
+}
+
+func (int64SliceSerDe) SerializeManyToSlice(items [][]int64) []byte {
+	var out []byte
+	for _, item := range items {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(item)))
+		for _, v := range item {
+			out = binary.LittleEndian.AppendUint64(out, uint64(v))
+		}
+	}
+	return out
+}
+
+func (s int64SliceSerDe) DeserializeManyFromSlice(mem []byte, offsetBytes int, numItems int) ([][]int64, error) {
+	if _, err := s.SizeOfMany(mem, offsetBytes, numItems); err != nil {
+		return nil, err
+	}
+	items := make([][]int64, numItems)
+	offset := offsetBytes
+	for i := range items {
+		items[i] = make([]int64, binary.LittleEndian.Uint64(mem[offset:]))
+		offset += 8
+		for j := range items[i] {
+			items[i][j] = int64(binary.LittleEndian.Uint64(mem[offset:]))
+			offset += 8
+		}
+	}
+	return items, nil
+}
+
+func TestReservoir_NonComparableItems(t *testing.T) {
+	sk, err := NewReservoirItemsSketch[[]int64](4, int64SliceSerDe{})
+	assert.NoError(t, err)
+	for i := int64(0); i < 100; i++ {
+		sk.Update([]int64{i, i * i})
+	}
+	assert.Equal(t, 4, sk.GetNumSamples())
+	for _, sample := range sk.GetSamples() {
+		assert.Equal(t, sample[0]*sample[0], sample[1])
+	}
+
+	sl, err := sk.ToSlice()
+	assert.NoError(t, err)
+	sk2, err := NewReservoirItemsSketchFromSlice[[]int64](sl, int64SliceSerDe{})
+	assert.NoError(t, err)
+	assert.Equal(t, sk.GetSamples(), sk2.GetSamples())
+
+	union, err := NewReservoirItemsUnion[[]int64](4, int64SliceSerDe{})
+	assert.NoError(t, err)
+	assert.NoError(t, union.UpdateSketch(sk))
+	result, err := union.GetResult()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), result.GetN())
+}
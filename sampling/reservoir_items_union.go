@@ -0,0 +1,168 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"errors"
+	"math/rand"
+
+	"github.com/apache/datasketches-go/common"
+)
+
+// ReservoirItemsUnion merges reservoir sketches, and single items, into a reservoir of at most maxK samples
+// that is a uniform sample of the union of their streams.
+//
+// This is the union of the Java ReservoirItemsUnion: a sketch in exact mode is merged item by item.
+// Two sketches in sampling mode are merged by feeding the samples of the sketch with the lighter implicit
+// weight (n / k) to the other one as weighted items, which keeps the result uniform as long as the
+// weights are strictly lighter than the weight of a sample of the target.
+type ReservoirItemsUnion[T any] struct {
+	maxK   int
+	serde  common.ItemSketchSerde[T]
+	gadget *ReservoirItemsSketch[T]
+}
+
+// NewReservoirItemsUnion constructs an empty union with a maximum sample size of maxK, using serde for the result.
+func NewReservoirItemsUnion[T any](maxK int, serde common.ItemSketchSerde[T]) (*ReservoirItemsUnion[T], error) {
+	// validates the arguments
+	if _, err := NewReservoirItemsSketch[T](maxK, serde); err != nil {
+		return nil, err
+	}
+	return &ReservoirItemsUnion[T]{maxK: maxK, serde: serde}, nil
+}
+
+// GetMaxK returns the maximum number of samples of the result.
+func (u *ReservoirItemsUnion[T]) GetMaxK() int {
+	return u.maxK
+}
+
+// Update presents a single item to the union.
+func (u *ReservoirItemsUnion[T]) Update(item T) error {
+	if u.gadget == nil {
+		gadget, err := NewReservoirItemsSketch[T](u.maxK, u.serde)
+		if err != nil {
+			return err
+		}
+		u.gadget = gadget
+	}
+	u.gadget.Update(item)
+	return nil
+}
+
+// UpdateSketch merges the given sketch into the union. The sketch is not modified.
+// A sketch with a k larger than maxK is downsampled to maxK first.
+func (u *ReservoirItemsUnion[T]) UpdateSketch(sketch *ReservoirItemsSketch[T]) error {
+	if sketch == nil {
+		return errors.New("no sketch provided")
+	}
+	if sketch.IsWeighted() {
+		return errors.New("a weighted reservoir cannot be merged")
+	}
+	if sketch.IsEmpty() {
+		return nil
+	}
+	source := sketch
+	modifiable := false
+	if sketch.k > u.maxK {
+		var err error
+		if source, err = sketch.downsampledCopy(u.maxK); err != nil {
+			return err
+		}
+		modifiable = true
+	}
+
+	if u.gadget == nil {
+		if source.k < u.maxK && source.n <= int64(source.k) {
+			// exact mode, a reservoir of maxK keeps all of the items
+			gadget, err := NewReservoirItemsSketch[T](u.maxK, u.serde)
+			if err != nil {
+				return err
+			}
+			u.gadget = gadget
+			u.mergeStandard(source)
+		} else if modifiable {
+			u.gadget = source
+		} else {
+			u.gadget = source.copy()
+		}
+		return nil
+	}
+
+	switch {
+	case source.n <= int64(source.k):
+		u.mergeStandard(source)
+	case u.gadget.n < int64(u.gadget.k):
+		// the gadget is in exact mode, it is merged into a copy of the source
+		tmp := u.gadget
+		if modifiable {
+			u.gadget = source
+		} else {
+			u.gadget = source.copy()
+		}
+		u.mergeStandard(tmp)
+	case source.GetImplicitSampleWeight() < float64(u.gadget.n)/float64(u.gadget.k-1):
+		// the samples of the source are light enough to be merged into the gadget
+		u.mergeWeighted(source)
+	default:
+		// the samples of the gadget are light enough to be merged into a copy of the source
+		tmp := u.gadget
+		if modifiable {
+			u.gadget = source
+		} else {
+			u.gadget = source.copy()
+		}
+		u.mergeWeighted(tmp)
+	}
+	return nil
+}
+
+// GetResult returns a copy of the sketch of the union, or an empty sketch of maxK if nothing was presented.
+func (u *ReservoirItemsUnion[T]) GetResult() (*ReservoirItemsSketch[T], error) {
+	if u.gadget == nil {
+		return NewReservoirItemsSketch[T](u.maxK, u.serde)
+	}
+	return u.gadget.copy(), nil
+}
+
+// Reset resets the union to its empty state.
+func (u *ReservoirItemsUnion[T]) Reset() {
+	u.gadget = nil
+}
+
+// mergeStandard presents the samples of an exact mode source to the gadget as regular items.
+func (u *ReservoirItemsUnion[T]) mergeStandard(source *ReservoirItemsSketch[T]) {
+	for _, item := range source.data {
+		u.gadget.Update(item)
+	}
+}
+
+// mergeWeighted presents each sample of a sampling mode source to the gadget as an item of weight n / k.
+// An item of weight w is accepted with probability k * w / total, where total includes the fractional
+// weights presented so far, which requires k * w < total for every item.
+func (u *ReservoirItemsUnion[T]) mergeWeighted(source *ReservoirItemsSketch[T]) {
+	sourceItemWeight := source.GetImplicitSampleWeight()
+	rescaledProb := float64(u.gadget.k) * sourceItemWeight
+	targetTotal := float64(u.gadget.n)
+	for _, item := range source.data {
+		targetTotal += sourceItemWeight
+		if rand.Float64()*targetTotal < rescaledProb {
+			u.gadget.insertAt(item, rand.Intn(u.gadget.k))
+		}
+	}
+	u.gadget.forceIncrementN(source.n)
+}
@@ -0,0 +1,104 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"math"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newReservoirOfRange(t *testing.T, k int, from int64, to int64) *ReservoirItemsSketch[int64] {
+	sk, err := NewReservoirItemsSketch[int64](k, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	for i := from; i < to; i++ {
+		sk.Update(i)
+	}
+	return sk
+}
+
+func TestReservoirUnion_Exact(t *testing.T) {
+	_, err := NewReservoirItemsUnion[int64](1, common.ItemSketchLongSerDe{})
+	assert.Error(t, err)
+
+	union, err := NewReservoirItemsUnion[int64](20, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	result, err := union.GetResult()
+	assert.NoError(t, err)
+	assert.True(t, result.IsEmpty())
+
+	// exact mode sketches with a smaller k end up in a reservoir of maxK
+	assert.NoError(t, union.UpdateSketch(newReservoirOfRange(t, 10, 0, 5)))
+	assert.NoError(t, union.UpdateSketch(newReservoirOfRange(t, 10, 5, 10)))
+	assert.NoError(t, union.Update(10))
+	assert.Error(t, union.UpdateSketch(nil))
+	result, err = union.GetResult()
+	assert.NoError(t, err)
+	assert.Equal(t, 20, result.GetK())
+	assert.Equal(t, int64(11), result.GetN())
+	assert.ElementsMatch(t, []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, result.GetSamples())
+
+	union.Reset()
+	result, err = union.GetResult()
+	assert.NoError(t, err)
+	assert.True(t, result.IsEmpty())
+}
+
+func TestReservoirUnion_Downsample(t *testing.T) {
+	union, err := NewReservoirItemsUnion[int64](10, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	source := newReservoirOfRange(t, 100, 0, 1000)
+	assert.NoError(t, union.UpdateSketch(source))
+	result, err := union.GetResult()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, result.GetK())
+	assert.Equal(t, int64(1000), result.GetN())
+	assert.Equal(t, 10, result.GetNumSamples())
+	// the source is not modified
+	assert.Equal(t, 100, source.GetK())
+	assert.Equal(t, 100, source.GetNumSamples())
+}
+
+func TestReservoirUnion_Uniform(t *testing.T) {
+	// two reservoirs in sampling mode, each fed with half of the stream
+	k := 10
+	n := 100
+	numTrials := 10000
+	counts := make([]int, n)
+	for trial := 0; trial < numTrials; trial++ {
+		union, err := NewReservoirItemsUnion[int64](k, common.ItemSketchLongSerDe{})
+		assert.NoError(t, err)
+		assert.NoError(t, union.UpdateSketch(newReservoirOfRange(t, k, 0, int64(n/2))))
+		assert.NoError(t, union.UpdateSketch(newReservoirOfRange(t, k, int64(n/2), int64(n))))
+		result, err := union.GetResult()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(n), result.GetN())
+		assert.Equal(t, k, result.GetNumSamples())
+		for _, item := range result.GetSamples() {
+			counts[item]++
+		}
+	}
+	// each item is sampled with probability k / n
+	expected := float64(numTrials*k) / float64(n)
+	stdDev := math.Sqrt(expected * (1 - float64(k)/float64(n)))
+	for i, count := range counts {
+		assert.InDelta(t, expected, count, 5*stdDev, "item %d", i)
+	}
+}
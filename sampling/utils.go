@@ -0,0 +1,101 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"math"
+	"math/rand"
+)
+
+const (
+	// _ALGORITHM_Z_THRESHOLD is the number of items seen, as a multiple of the reservoir size,
+	// above which Algorithm Z is cheaper than Algorithm X to compute the number of items to skip.
+	_ALGORITHM_Z_THRESHOLD = 22
+)
+
+// nextDoubleExcludeZero returns a uniform random number in (0, 1).
+func nextDoubleExcludeZero() float64 {
+	r := rand.Float64()
+	for r == 0.0 {
+		r = rand.Float64()
+	}
+	return r
+}
+
+// reservoirSkip computes the number of items to skip before the next item is
+// accepted into a reservoir of size k, having already seen t items (t >= k).
+//
+// It implements Algorithm X and Algorithm Z from J. S. Vitter, "Random Sampling with a Reservoir",
+// ACM Transactions on Mathematical Software, 1985. w is the state of Algorithm Z across calls,
+// it must be zero on the first call.
+func reservoirSkip(k int64, t int64, w *float64) int64 {
+	if t <= _ALGORITHM_Z_THRESHOLD*k {
+		// Algorithm X
+		v := nextDoubleExcludeZero()
+		skip := int64(0)
+		t++
+		quot := float64(t-k) / float64(t)
+		for quot > v {
+			skip++
+			t++
+			quot *= float64(t-k) / float64(t)
+		}
+		return skip
+	}
+
+	// Algorithm Z
+	n := float64(k)
+	tf := float64(t)
+	if *w == 0 {
+		*w = math.Exp(-math.Log(nextDoubleExcludeZero()) / n)
+	}
+	term := tf - n + 1
+	for {
+		u := nextDoubleExcludeZero()
+		x := tf * (*w - 1.0)
+		skip := math.Floor(x)
+
+		// test if u <= h(skip)/cg(x) in the manner of (6.3)
+		tmp := (tf + 1) / term
+		lhs := math.Exp(math.Log(((u*tmp*tmp)*(term+skip))/(tf+x)) / n)
+		rhs := (((tf + x) / (term + skip)) * term) / tf
+		if lhs <= rhs {
+			*w = rhs / lhs
+			return int64(skip)
+		}
+
+		// test if u <= f(skip)/cg(x)
+		y := (((u * (tf + 1)) / term) * (tf + skip + 1)) / (tf + x)
+		var denom, numerLim float64
+		if n < skip {
+			denom = tf
+			numerLim = term + skip
+		} else {
+			denom = tf - n + skip
+			numerLim = tf + 1
+		}
+		for numer := tf + skip; numer >= numerLim; numer-- {
+			y = (y * numer) / denom
+			denom--
+		}
+		*w = math.Exp(-math.Log(nextDoubleExcludeZero()) / n)
+		if math.Exp(math.Log(y)/n) <= (tf+x)/tf {
+			return int64(skip)
+		}
+	}
+}
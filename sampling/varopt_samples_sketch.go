@@ -0,0 +1,565 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sampling contains sketches that maintain a bounded random sample of a stream.
+//
+// VarOptSamplesSketch implements the VarOpt_k algorithm of Cohen, Duffield, Kaplan, Lund and Thorup,
+// "Stream sampling for variance-optimal estimation of subset sums", which produces a
+// probability-proportional-to-size sample of k weighted items.
+package sampling
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/apache/datasketches-go/internal"
+)
+
+const (
+	_VAROPT_MAX_K = (1 << 31) - 2
+)
+
+// WeightedItem is a sampled item with its adjusted weight.
+type WeightedItem[T any] struct {
+	Item   T
+	Weight float64
+}
+
+// VarOptSamplesSketch is a sketch that maintains a sample of k items drawn with probability
+// proportional to their weight, such that the adjusted weights of the sample are an unbiased
+// estimate of the weight of any subset of the stream, with optimal variance.
+//
+// The items are stored in two regions: H holds the heavy items with their exact weight, as a min-heap,
+// and R holds the light items which all share the adjusted weight totalWtR / r.
+// When R is not empty a gap slot is kept at index h, between the two regions.
+type VarOptSamplesSketch[T any] struct {
+	k        int
+	n        int64
+	h        int // number of items in the H region
+	m        int // number of items in the M (candidate) region, only non zero during an update
+	r        int // number of items in the R region
+	totalWtR float64
+	data     []T
+	weights  []float64
+}
+
+// NewVarOptSamplesSketch constructs a new empty sketch with a maximum sample size of k.
+func NewVarOptSamplesSketch[T any](k int) (*VarOptSamplesSketch[T], error) {
+	if k < 1 || k > _VAROPT_MAX_K {
+		return nil, fmt.Errorf("k must be at least 1 and less than %d: %d", _VAROPT_MAX_K+1, k)
+	}
+	return &VarOptSamplesSketch[T]{
+		k:       k,
+		data:    make([]T, k+1),
+		weights: make([]float64, k+1),
+	}, nil
+}
+
+// NewVarOptFromSlice constructs a sketch from its serialized form, using serde to deserialize the items.
+func NewVarOptFromSlice[T any](sl []byte, serde common.ItemSketchSerde[T]) (*VarOptSamplesSketch[T], error) {
+	if serde == nil {
+		return nil, errors.New("no SerDe provided")
+	}
+	if err := checkPreambleSize(sl); err != nil {
+		return nil, err
+	}
+	preLongs := extractPreLongs(sl)
+	serVer := extractSerVer(sl)
+	familyID := extractFamilyID(sl)
+	flags := extractFlags(sl)
+	k := extractK(sl)
+	empty := (flags & _EMPTY_FLAG_MASK) != 0
+
+	if serVer != _VAROPT_SER_VER {
+		return nil, fmt.Errorf("possible corruption: ser ver must be %d: %d", _VAROPT_SER_VER, serVer)
+	}
+	if familyID != internal.FamilyEnum.VarOpt.Id {
+		return nil, fmt.Errorf("possible corruption: familyID must be %d: %d", internal.FamilyEnum.VarOpt.Id, familyID)
+	}
+	if (flags & _GADGET_FLAG_MASK) != 0 {
+		return nil, errors.New("union gadgets are not supported")
+	}
+	if empty {
+		if preLongs != _VAROPT_PRELONGS_EMPTY {
+			return nil, fmt.Errorf("possible corruption: empty sketch must have %d preLongs: %d", _VAROPT_PRELONGS_EMPTY, preLongs)
+		}
+		return NewVarOptSamplesSketch[T](k)
+	}
+	if preLongs != _VAROPT_PRELONGS_WARMUP && preLongs != _VAROPT_PRELONGS_FULL {
+		return nil, fmt.Errorf("possible corruption: preLongs must be %d or %d: %d", _VAROPT_PRELONGS_WARMUP, _VAROPT_PRELONGS_FULL, preLongs)
+	}
+
+	sketch, err := NewVarOptSamplesSketch[T](k)
+	if err != nil {
+		return nil, err
+	}
+	n := extractN(sl)
+	h := extractHRegionItemCount(sl)
+	r := extractRRegionItemCount(sl)
+	if n < 0 || h < 0 || r < 0 {
+		return nil, fmt.Errorf("possible corruption: negative count, n: %d, h: %d, r: %d", n, h, r)
+	}
+	if preLongs == _VAROPT_PRELONGS_WARMUP {
+		if r != 0 || h > k || int64(h) != n {
+			return nil, fmt.Errorf("possible corruption: invalid warmup sketch, k: %d, n: %d, h: %d, r: %d", k, n, h, r)
+		}
+	} else if r == 0 || h+r != k || int64(k) > n {
+		return nil, fmt.Errorf("possible corruption: invalid full sketch, k: %d, n: %d, h: %d, r: %d", k, n, h, r)
+	}
+
+	offset := preLongs << 3
+	if len(sl) < offset+h*8 {
+		return nil, fmt.Errorf("possible corruption: insufficient bytes in array: %d, %d", len(sl), offset+h*8)
+	}
+	for i := 0; i < h; i++ {
+		w := math.Float64frombits(binary.LittleEndian.Uint64(sl[offset:]))
+		if !(w > 0) || math.IsInf(w, 0) {
+			return nil, fmt.Errorf("possible corruption: non-positive weight in H region: %f", w)
+		}
+		sketch.weights[i] = w
+		offset += 8
+	}
+	items, err := serde.DeserializeManyFromSlice(sl, offset, h+r)
+	if err != nil {
+		return nil, err
+	}
+	copy(sketch.data, items[:h])
+	if r > 0 {
+		totalWtR := extractTotalRWeight(sl)
+		if !(totalWtR > 0) || math.IsInf(totalWtR, 0) {
+			return nil, fmt.Errorf("possible corruption: non-positive total weight in R region: %f", totalWtR)
+		}
+		sketch.totalWtR = totalWtR
+		copy(sketch.data[h+1:], items[h:])
+		for i := h; i <= k; i++ {
+			sketch.weights[i] = -1.0
+		}
+	}
+	sketch.n = n
+	sketch.h = h
+	sketch.r = r
+	return sketch, nil
+}
+
+// GetK returns the maximum number of samples retained by the sketch.
+func (s *VarOptSamplesSketch[T]) GetK() int {
+	return s.k
+}
+
+// GetN returns the number of items presented to the sketch.
+func (s *VarOptSamplesSketch[T]) GetN() int64 {
+	return s.n
+}
+
+// GetNumSamples returns the number of samples currently in the sketch.
+func (s *VarOptSamplesSketch[T]) GetNumSamples() int {
+	return s.h + s.r
+}
+
+// IsEmpty returns true if the sketch has not seen any item.
+func (s *VarOptSamplesSketch[T]) IsEmpty() bool {
+	return s.h == 0 && s.r == 0
+}
+
+// GetTotalWeight returns the estimated total weight of the stream, which is exact for VarOpt.
+func (s *VarOptSamplesSketch[T]) GetTotalWeight() float64 {
+	total := s.totalWtR
+	for i := 0; i < s.h; i++ {
+		total += s.weights[i]
+	}
+	return total
+}
+
+// Update presents an item with the given weight to the sketch.
+// The weight must be a positive finite number; items with a zero weight are ignored.
+func (s *VarOptSamplesSketch[T]) Update(item T, weight float64) error {
+	if weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		return fmt.Errorf("item weights must be non-negative and finite: %f", weight)
+	}
+	if weight == 0 {
+		return nil
+	}
+	s.n++
+
+	if s.r == 0 {
+		s.updateWarmupPhase(item, weight)
+		return nil
+	}
+
+	// what tau would be if deletion candidates turn out to be R plus the new item
+	// note: (r + 1) - 1 is intentional
+	hypotheticalTau := (weight + s.totalWtR) / float64((s.r+1)-1)
+
+	// is new item's turn to be considered for reservoir?
+	condition1 := s.h == 0 || weight <= s.peekMin()
+	// is new item light enough for reservoir?
+	condition2 := weight < hypotheticalTau
+
+	if condition1 && condition2 {
+		s.updateLight(item, weight)
+	} else if s.r == 1 {
+		s.updateHeavyREq1(item, weight)
+	} else {
+		s.updateHeavyGeneral(item, weight)
+	}
+	return nil
+}
+
+// GetSamples returns the sampled items with their adjusted weights.
+// Heavy items keep their original weight, light items share the weight totalWtR / r.
+func (s *VarOptSamplesSketch[T]) GetSamples() []WeightedItem[T] {
+	samples := make([]WeightedItem[T], 0, s.h+s.r)
+	for i := 0; i < s.h; i++ {
+		samples = append(samples, WeightedItem[T]{Item: s.data[i], Weight: s.weights[i]})
+	}
+	if s.r > 0 {
+		tau := s.getTau()
+		for i := s.h + 1; i <= s.h+s.r; i++ {
+			samples = append(samples, WeightedItem[T]{Item: s.data[i], Weight: tau})
+		}
+	}
+	return samples
+}
+
+// MergeApproximate presents the samples of other to this sketch as new items, the heavy items of
+// the H region with their exact weight and the light items of the R region with the adjusted weight tau.
+//
+// This is an approximation of a VarOpt union: since the adjusted weights of other are unbiased estimates
+// of the weights of its input stream, the merged sketch remains an unbiased estimator of subset sums over
+// both streams, and its total weight is the exact sum of the total weights, but the light items of other
+// are treated as exact weights, so the variance is higher than the one of the union of the Java
+// VarOptItemsUnion, which keeps track of them. Nothing is changed if an error is returned.
+func (s *VarOptSamplesSketch[T]) MergeApproximate(other *VarOptSamplesSketch[T]) error {
+	if other == nil {
+		return errors.New("no sketch provided")
+	}
+	if other.IsEmpty() {
+		return nil
+	}
+	n := s.n + other.n
+	if n < s.n {
+		return fmt.Errorf("n overflows: %d + %d", s.n, other.n)
+	}
+	samples := other.GetSamples()
+	for _, sample := range samples {
+		if !(sample.Weight > 0) || math.IsInf(sample.Weight, 0) {
+			return fmt.Errorf("possible corruption: non-positive weight in sketch: %f", sample.Weight)
+		}
+	}
+	for _, sample := range samples {
+		// the weights are checked above, so the update cannot fail
+		_ = s.Update(sample.Item, sample.Weight)
+	}
+	s.n = n
+	return nil
+}
+
+// Reset clears the sketch, keeping its configured k.
+func (s *VarOptSamplesSketch[T]) Reset() {
+	s.n = 0
+	s.h = 0
+	s.m = 0
+	s.r = 0
+	s.totalWtR = 0
+	s.data = make([]T, s.k+1)
+	s.weights = make([]float64, s.k+1)
+}
+
+// ToSlice serializes the sketch using serde for the items.
+// The binary format is the one of the Java VarOptItemsSketch.
+func (s *VarOptSamplesSketch[T]) ToSlice(serde common.ItemSketchSerde[T]) ([]byte, error) {
+	if serde == nil {
+		return nil, errors.New("no SerDe provided")
+	}
+	empty := s.IsEmpty()
+	preLongs := _VAROPT_PRELONGS_FULL
+	flags := 0
+	var itemBytes []byte
+	if empty {
+		preLongs = _VAROPT_PRELONGS_EMPTY
+		flags |= _EMPTY_FLAG_MASK
+	} else {
+		if s.r == 0 {
+			preLongs = _VAROPT_PRELONGS_WARMUP
+		}
+		itemBytes = serde.SerializeManyToSlice(s.getDataSamples())
+	}
+	outBytes := (preLongs << 3) + s.h*8 + len(itemBytes)
+	out := make([]byte, outBytes)
+
+	insertPreLongsAndResizeFactor(out, preLongs)
+	out[_SER_VER_BYTE] = _VAROPT_SER_VER
+	out[_FAMILY_BYTE] = byte(internal.FamilyEnum.VarOpt.Id)
+	out[_FLAGS_BYTE] = byte(flags)
+	binary.LittleEndian.PutUint32(out[_K_INT:], uint32(s.k))
+	if empty {
+		return out, nil
+	}
+
+	binary.LittleEndian.PutUint64(out[_N_LONG:], uint64(s.n))
+	binary.LittleEndian.PutUint32(out[_H_COUNT_INT:], uint32(s.h))
+	binary.LittleEndian.PutUint32(out[_R_COUNT_INT:], uint32(s.r))
+	if s.r > 0 {
+		binary.LittleEndian.PutUint64(out[_TOTAL_WEIGHT_R_DOUBLE:], math.Float64bits(s.totalWtR))
+	}
+	offset := preLongs << 3
+	for i := 0; i < s.h; i++ {
+		binary.LittleEndian.PutUint64(out[offset:], math.Float64bits(s.weights[i]))
+		offset += 8
+	}
+	copy(out[offset:], itemBytes)
+	return out, nil
+}
+
+//
+// Private methods
+//
+
+// getDataSamples returns the items of the H and R regions, skipping the gap.
+func (s *VarOptSamplesSketch[T]) getDataSamples() []T {
+	items := make([]T, 0, s.h+s.r)
+	items = append(items, s.data[:s.h]...)
+	if s.r > 0 {
+		items = append(items, s.data[s.h+1:s.h+1+s.r]...)
+	}
+	return items
+}
+
+func (s *VarOptSamplesSketch[T]) getTau() float64 {
+	if s.r == 0 {
+		return math.NaN()
+	}
+	return s.totalWtR / float64(s.r)
+}
+
+func (s *VarOptSamplesSketch[T]) updateWarmupPhase(item T, weight float64) {
+	// store items as they come in, until full
+	s.data[s.h] = item
+	s.weights[s.h] = weight
+	s.h++
+
+	if s.h > s.k {
+		s.transitionFromWarmup()
+	}
+}
+
+func (s *VarOptSamplesSketch[T]) transitionFromWarmup() {
+	// Move 2 lightest items from H to M
+	// But the lighter really belongs in R, so update counts to reflect that
+	s.convertToHeap()
+	s.popMinToMRegion()
+	s.popMinToMRegion()
+	s.m--
+	s.r++
+
+	// Update total weight in R and then, having grabbed the value, overwrite in
+	// weights to help make bugs more obvious
+	s.totalWtR = s.weights[s.k]
+	s.weights[s.k] = -1.0
+
+	// The two lightest items are necessarily downsample-able to one item, and are therefore a
+	// valid initial candidate set
+	s.growCandidateSet(s.weights[s.k-1]+s.totalWtR, 2)
+}
+
+func (s *VarOptSamplesSketch[T]) updateLight(item T, weight float64) {
+	mSlot := s.h // index of the gap, which becomes the M region
+	s.data[mSlot] = item
+	s.weights[mSlot] = weight
+	s.m++
+
+	s.growCandidateSet(s.totalWtR+weight, s.r+1)
+}
+
+func (s *VarOptSamplesSketch[T]) updateHeavyGeneral(item T, weight float64) {
+	// put into H, although may come back out momentarily
+	s.push(item, weight)
+	s.growCandidateSet(s.totalWtR, s.r)
+}
+
+func (s *VarOptSamplesSketch[T]) updateHeavyREq1(item T, weight float64) {
+	s.push(item, weight) // new item into H
+	s.popMinToMRegion()  // pop lightest back into M
+
+	// Any set of two items is downsample-able to one item,
+	// so the two lightest items are a valid starting point for the following
+	mSlot := s.k - 1 // array is k+1, 1 in R, so slot before is M
+	s.growCandidateSet(s.weights[mSlot]+s.totalWtR, 2)
+}
+
+func (s *VarOptSamplesSketch[T]) growCandidateSet(wtCands float64, numCands int) {
+	for s.h > 0 {
+		nextWt := s.peekMin()
+		nextTotWt := wtCands + nextWt
+
+		// test for strict lightness of next prospect (denominator multiplied through)
+		if nextWt*float64(numCands) < nextTotWt {
+			wtCands = nextTotWt
+			numCands++
+			s.popMinToMRegion() // adjusts h and m
+		} else {
+			break
+		}
+	}
+	s.downsampleCandidateSet(wtCands, numCands)
+}
+
+func (s *VarOptSamplesSketch[T]) downsampleCandidateSet(wtCands float64, numCands int) {
+	// need this before overwriting anything
+	deleteSlot := s.chooseDeleteSlot(wtCands, numCands)
+	leftmostCandSlot := s.h
+
+	// overwrite weights for items from M moving into R, to make bugs more obvious
+	for j := leftmostCandSlot; j < leftmostCandSlot+s.m; j++ {
+		s.weights[j] = -1.0
+	}
+
+	// The next two lines work even when deleteSlot == leftmostCandSlot
+	s.data[deleteSlot] = s.data[leftmostCandSlot]
+	s.data[leftmostCandSlot] = *new(T)
+
+	s.m = 0
+	s.r = numCands - 1
+	s.totalWtR = wtCands
+}
+
+func (s *VarOptSamplesSketch[T]) chooseDeleteSlot(wtCand float64, numCand int) int {
+	if s.m == 0 {
+		// this happens if we insert a really heavy item
+		return s.pickRandomSlotInR()
+	}
+	if s.m == 1 {
+		// check if we keep the item in M or pick one from R
+		// p(keep) = (numCand - 1) * wt_M / wt_cand
+		wtMCand := s.weights[s.h] // slot of item in M is h
+		if wtCand*nextDoubleExcludeZero() < float64(numCand-1)*wtMCand {
+			return s.pickRandomSlotInR() // keep item in M
+		}
+		return s.h // index of item in M
+	}
+	// general case
+	deleteSlot := s.chooseWeightedDeleteSlot(wtCand, numCand)
+	firstRSlot := s.h + s.m
+	if deleteSlot == firstRSlot {
+		return s.pickRandomSlotInR()
+	}
+	return deleteSlot
+}
+
+func (s *VarOptSamplesSketch[T]) chooseWeightedDeleteSlot(wtCand float64, numCand int) int {
+	offset := s.h
+	finalM := offset + s.m - 1
+	numToKeep := float64(numCand - 1)
+
+	leftSubtotal := 0.0
+	rightSubtotal := -1.0 * wtCand * nextDoubleExcludeZero()
+
+	for i := offset; i <= finalM; i++ {
+		leftSubtotal += numToKeep * s.weights[i]
+		rightSubtotal += wtCand
+
+		if leftSubtotal < rightSubtotal {
+			return i
+		}
+	}
+	// this slot tells caller that we need to delete out of R
+	return finalM + 1
+}
+
+func (s *VarOptSamplesSketch[T]) pickRandomSlotInR() int {
+	offset := s.h + s.m
+	if s.r == 1 {
+		return offset
+	}
+	return offset + rand.Intn(s.r)
+}
+
+func (s *VarOptSamplesSketch[T]) peekMin() float64 {
+	return s.weights[0]
+}
+
+func (s *VarOptSamplesSketch[T]) push(item T, weight float64) {
+	s.data[s.h] = item
+	s.weights[s.h] = weight
+	s.h++
+	s.restoreTowardsRoot(s.h - 1)
+}
+
+func (s *VarOptSamplesSketch[T]) popMinToMRegion() {
+	if s.h == 1 {
+		// just update bookkeeping
+		s.m++
+		s.h--
+		return
+	}
+	tgt := s.h - 1 // last slot of heap
+	s.swap(0, tgt)
+	s.m++
+	s.h--
+	s.restoreTowardsLeaves(0)
+}
+
+func (s *VarOptSamplesSketch[T]) convertToHeap() {
+	if s.h < 2 {
+		return // nothing to do
+	}
+	lastSlot := s.h - 1
+	lastNonLeaf := ((lastSlot + 1) / 2) - 1
+	for j := lastNonLeaf; j >= 0; j-- {
+		s.restoreTowardsLeaves(j)
+	}
+}
+
+func (s *VarOptSamplesSketch[T]) restoreTowardsLeaves(slotIn int) {
+	lastSlot := s.h - 1
+	slot := slotIn
+	child := 2*slotIn + 1 // might be invalid, need to check
+
+	for child <= lastSlot {
+		child2 := child + 1 // might also be invalid
+		if child2 <= lastSlot && s.weights[child2] < s.weights[child] {
+			// switch to other child if it's both valid and smaller
+			child = child2
+		}
+		if s.weights[slot] <= s.weights[child] {
+			// invariant holds so we're done
+			break
+		}
+		s.swap(slot, child)
+		slot = child
+		child = 2*slot + 1
+	}
+}
+
+func (s *VarOptSamplesSketch[T]) restoreTowardsRoot(slotIn int) {
+	slot := slotIn
+	p := ((slot + 1) / 2) - 1 // valid if slot >= 1
+	for slot > 0 && s.weights[slot] < s.weights[p] {
+		s.swap(slot, p)
+		slot = p
+		p = ((slot + 1) / 2) - 1
+	}
+}
+
+func (s *VarOptSamplesSketch[T]) swap(src int, dst int) {
+	s.data[src], s.data[dst] = s.data[dst], s.data[src]
+	s.weights[src], s.weights[dst] = s.weights[dst], s.weights[src]
+}
@@ -0,0 +1,251 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sampling
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/apache/datasketches-go/common"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	_EPS = 1e-9
+)
+
+func TestVarOpt_InvalidK(t *testing.T) {
+	_, err := NewVarOptSamplesSketch[int64](0)
+	assert.Error(t, err)
+	_, err = NewVarOptSamplesSketch[int64](_VAROPT_MAX_K + 1)
+	assert.Error(t, err)
+}
+
+func TestVarOpt_InvalidWeight(t *testing.T) {
+	sk, err := NewVarOptSamplesSketch[int64](10)
+	assert.NoError(t, err)
+	assert.Error(t, sk.Update(1, -1.0))
+	assert.Error(t, sk.Update(1, math.NaN()))
+	assert.Error(t, sk.Update(1, math.Inf(1)))
+	assert.NoError(t, sk.Update(1, 0.0))
+	assert.True(t, sk.IsEmpty())
+	assert.Equal(t, int64(0), sk.GetN())
+}
+
+func TestVarOpt_WarmupIsExact(t *testing.T) {
+	k := 100
+	sk, err := NewVarOptSamplesSketch[int64](k)
+	assert.NoError(t, err)
+	total := 0.0
+	for i := 1; i <= k; i++ {
+		assert.NoError(t, sk.Update(int64(i), float64(i)))
+		total += float64(i)
+	}
+	assert.Equal(t, int64(k), sk.GetN())
+	assert.Equal(t, k, sk.GetNumSamples())
+	samples := sk.GetSamples()
+	assert.Equal(t, k, len(samples))
+	for _, sample := range samples {
+		assert.Equal(t, float64(sample.Item), sample.Weight)
+	}
+	assert.Equal(t, total, sk.GetTotalWeight())
+}
+
+func TestVarOpt_EstimationModeTotalWeight(t *testing.T) {
+	k := 64
+	sk, err := NewVarOptSamplesSketch[int64](k)
+	assert.NoError(t, err)
+	total := 0.0
+	n := 10000
+	for i := 1; i <= n; i++ {
+		w := float64(i%37) + 0.5
+		assert.NoError(t, sk.Update(int64(i), w))
+		total += w
+	}
+	assert.Equal(t, int64(n), sk.GetN())
+	assert.Equal(t, k, sk.GetNumSamples())
+	sum := 0.0
+	for _, sample := range sk.GetSamples() {
+		sum += sample.Weight
+	}
+	assert.InDelta(t, total, sum, total*_EPS)
+	assert.InDelta(t, total, sk.GetTotalWeight(), total*_EPS)
+}
+
+func TestVarOpt_HeavyItemsKeepTheirWeight(t *testing.T) {
+	k := 20
+	sk, err := NewVarOptSamplesSketch[int64](k)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, sk.Update(int64(i), 1.0))
+	}
+	assert.NoError(t, sk.Update(-1, 1e6))
+	found := false
+	for _, sample := range sk.GetSamples() {
+		if sample.Item == -1 {
+			found = true
+			assert.Equal(t, 1e6, sample.Weight)
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestVarOpt_Unbiased(t *testing.T) {
+	k := 32
+	n := 1000
+	numTrials := 2000
+
+	trueSubsetWeight := 0.0
+	for i := 0; i < n; i++ {
+		if i%3 == 0 {
+			trueSubsetWeight += varOptTestWeight(i)
+		}
+	}
+
+	sumInvP := 0.0
+	sumSubset := 0.0
+	for trial := 0; trial < numTrials; trial++ {
+		// unit weights, so the adjusted weight of each sample is 1/p_i
+		uniform, err := NewVarOptSamplesSketch[int](k)
+		assert.NoError(t, err)
+		weighted, err := NewVarOptSamplesSketch[int](k)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, uniform.Update(i, 1.0))
+			assert.NoError(t, weighted.Update(i, varOptTestWeight(i)))
+		}
+		for _, sample := range uniform.GetSamples() {
+			sumInvP += sample.Weight
+		}
+		for _, sample := range weighted.GetSamples() {
+			if sample.Item%3 == 0 {
+				sumSubset += sample.Weight
+			}
+		}
+	}
+	assert.InDelta(t, float64(n), sumInvP/float64(numTrials), float64(n)*1e-6)
+	assert.InDelta(t, trueSubsetWeight, sumSubset/float64(numTrials), trueSubsetWeight*0.05)
+}
+
+func varOptTestWeight(i int) float64 {
+	return float64(1 + (i*7919)%100)
+}
+
+func TestVarOpt_Merge(t *testing.T) {
+	k := 50
+	sk1, err := NewVarOptSamplesSketch[int64](k)
+	assert.NoError(t, err)
+	sk2, err := NewVarOptSamplesSketch[int64](k)
+	assert.NoError(t, err)
+	total := 0.0
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, sk1.Update(int64(i), 1.0))
+		assert.NoError(t, sk2.Update(int64(1000+i), 2.0))
+		total += 3.0
+	}
+	assert.NoError(t, sk1.MergeApproximate(sk2))
+	assert.Error(t, sk1.MergeApproximate(nil))
+	assert.Equal(t, int64(2000), sk1.GetN())
+	assert.Equal(t, k, sk1.GetNumSamples())
+	assert.InDelta(t, total, sk1.GetTotalWeight(), total*_EPS)
+}
+
+func TestVarOpt_MergeApproximateInvalid(t *testing.T) {
+	sk1, err := NewVarOptSamplesSketch[int64](10)
+	assert.NoError(t, err)
+	sk2, err := NewVarOptSamplesSketch[int64](10)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, sk1.Update(int64(i), 1.0))
+		assert.NoError(t, sk2.Update(int64(10+i), 1.0))
+	}
+	// a corrupted weight is found before anything is merged
+	sk2.weights[4] = math.NaN()
+	assert.Error(t, sk1.MergeApproximate(sk2))
+	assert.Equal(t, int64(5), sk1.GetN())
+	assert.Equal(t, 5, sk1.GetNumSamples())
+	assert.Equal(t, 5.0, sk1.GetTotalWeight())
+}
+
+func TestVarOpt_NonComparableItems(t *testing.T) {
+	sk, err := NewVarOptSamplesSketch[[]string](4)
+	assert.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, sk.Update([]string{"item", strconv.Itoa(i)}, 1.0))
+	}
+	assert.Equal(t, 4, sk.GetNumSamples())
+	assert.InDelta(t, 10.0, sk.GetTotalWeight(), 10*_EPS)
+}
+
+func TestVarOpt_SerializeDeserialize(t *testing.T) {
+	k := 32
+	for _, n := range []int{0, 1, 10, k, k + 1, 1000} {
+		sk, err := NewVarOptSamplesSketch[string](k)
+		assert.NoError(t, err)
+		for i := 0; i < n; i++ {
+			assert.NoError(t, sk.Update(strconv.Itoa(i), float64(1+i%5)))
+		}
+		serde := common.ItemSketchStringSerDe{}
+		bytes, err := sk.ToSlice(serde)
+		assert.NoError(t, err)
+		sk2, err := NewVarOptFromSlice[string](bytes, serde)
+		assert.NoError(t, err)
+		assert.Equal(t, sk.GetK(), sk2.GetK())
+		assert.Equal(t, sk.GetN(), sk2.GetN())
+		assert.Equal(t, sk.GetSamples(), sk2.GetSamples())
+		bytes2, err := sk2.ToSlice(serde)
+		assert.NoError(t, err)
+		assert.Equal(t, bytes, bytes2)
+
+		// a deserialized sketch can keep being updated
+		assert.NoError(t, sk2.Update("x", 1.0))
+		assert.Equal(t, sk.GetN()+1, sk2.GetN())
+	}
+}
+
+func TestVarOpt_DeserializeCorrupt(t *testing.T) {
+	sk, err := NewVarOptSamplesSketch[int64](8)
+	assert.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, sk.Update(int64(i), 1.0))
+	}
+	serde := common.ItemSketchLongSerDe{}
+	bytes, err := sk.ToSlice(serde)
+	assert.NoError(t, err)
+
+	_, err = NewVarOptFromSlice[int64](bytes[:4], serde)
+	assert.Error(t, err)
+	_, err = NewVarOptFromSlice[int64](bytes, nil)
+	assert.Error(t, err)
+
+	badFamily := append([]byte{}, bytes...)
+	badFamily[_FAMILY_BYTE] = 0
+	_, err = NewVarOptFromSlice[int64](badFamily, serde)
+	assert.Error(t, err)
+
+	badSerVer := append([]byte{}, bytes...)
+	badSerVer[_SER_VER_BYTE] = 1
+	_, err = NewVarOptFromSlice[int64](badSerVer, serde)
+	assert.Error(t, err)
+
+	badCounts := append([]byte{}, bytes...)
+	badCounts[_H_COUNT_INT] = 100
+	_, err = NewVarOptFromSlice[int64](badCounts, serde)
+	assert.Error(t, err)
+}
@@ -0,0 +1,218 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package sketches deserializes sketches of any registered family, dispatching on the family ID
+// found in the third byte of the preamble of every serialized sketch, or on the type tag prepended by Serialize.
+//
+// NewRegistry registers the families whose format is enough to deserialize them: HLL, KLL doubles,
+// the Go CountMin sketch and the Go Bloom filter. The Frequency, Reservoir and VarOpt families are not registered,
+// as their format does not record the type of the items, so the caller must register a factory
+// with the right serde for them. The same holds for a KLL sketch of another item type than float64.
+package sketches
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/apache/datasketches-go/bloomfilter"
+	"github.com/apache/datasketches-go/frequencies"
+	"github.com/apache/datasketches-go/hll"
+	"github.com/apache/datasketches-go/internal"
+	"github.com/apache/datasketches-go/kll"
+)
+
+const (
+	_FAMILY_BYTE = 2
+)
+
+// Sketch is the minimal interface of a deserialized sketch.
+type Sketch interface {
+	// Estimate returns the main estimate of the sketch, see the adapters for its meaning per family.
+	Estimate() float64
+	// ToSlice returns the serialized sketch.
+	ToSlice() ([]byte, error)
+}
+
+// SketchFactory deserializes a sketch of one family.
+type SketchFactory func(data []byte) (Sketch, error)
+
+// Registry maps family IDs to the factories deserializing them. It is safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[byte]SketchFactory
+}
+
+// NewRegistry returns a registry populated with the HLL, KLL, Go CountMin and Go Bloom families.
+//
+// The KLL family is deserialized as a kll.DoublesSketch. The item type is not part of the KLL format,
+// so a blob whose length does not match the one of a doubles sketch is rejected. A sketch of 8 bytes
+// items of another type, or of strings that all serialize to 8 bytes, cannot be told apart:
+// register a factory for the KLL family to deserialize other item types.
+func NewRegistry() *Registry {
+	r := &Registry{factories: make(map[byte]SketchFactory)}
+	r.Register(byte(internal.FamilyEnum.HLL.Id), func(data []byte) (Sketch, error) {
+		sk, err := hll.NewHllSketchFromSlice(data, true)
+		if err != nil {
+			return nil, err
+		}
+		return HllSketch{sk}, nil
+	})
+	r.Register(byte(internal.FamilyEnum.Kll.Id), func(data []byte) (Sketch, error) {
+		sk, err := kll.NewDoublesSketchFromSlice(data)
+		if err != nil {
+			return nil, fmt.Errorf("not a KLL doubles sketch: %w", err)
+		}
+		sl, err := sk.ToSlice()
+		if err != nil {
+			return nil, err
+		}
+		if len(sl) != len(data) {
+			return nil, fmt.Errorf("not a KLL doubles sketch: %d bytes for %d retained items", len(data), sk.GetNumRetained())
+		}
+		return KllDoublesSketch{sk}, nil
+	})
+	r.Register(byte(internal.FamilyEnum.GoCountMin.Id), func(data []byte) (Sketch, error) {
+		sk, err := frequencies.NewCountMinSketchFromSlice(data)
+		if err != nil {
+			return nil, err
+		}
+		return CountMinSketch{sk}, nil
+	})
+	r.Register(byte(internal.FamilyEnum.GoBloom.Id), func(data []byte) (Sketch, error) {
+		bf, err := bloomfilter.NewBloomFilterFromSlice(data)
+		if err != nil {
+			return nil, err
+		}
+		return BloomFilter{bf}, nil
+	})
+	return r
+}
+
+// Register registers the factory of the given family ID, replacing any previous one.
+// It panics if factory is nil.
+func (r *Registry) Register(familyID byte, factory SketchFactory) {
+	if factory == nil {
+		panic("sketches: Register factory is nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[familyID] = factory
+}
+
+// Deserialize deserializes data with the factory registered for its family ID.
+func (r *Registry) Deserialize(data []byte) (Sketch, error) {
+	if len(data) <= _FAMILY_BYTE {
+		return nil, fmt.Errorf("input array too small: %d", len(data))
+	}
+	familyID := data[_FAMILY_BYTE]
+	r.mu.RLock()
+	factory, ok := r.factories[familyID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no factory registered for family ID: %d", familyID)
+	}
+	return factory(data)
+}
+
+var defaultRegistry = NewRegistry()
+
+// Register registers the factory of the given family ID in the default registry.
+func Register(familyID byte, factory SketchFactory) {
+	defaultRegistry.Register(familyID, factory)
+}
+
+// Deserialize deserializes data with the default registry.
+func Deserialize(data []byte) (Sketch, error) {
+	return defaultRegistry.Deserialize(data)
+}
+
+// HllSketch adapts an hll.HllSketch to Sketch.
+// Estimate returns the estimate of the number of distinct items, or NaN if it cannot be computed.
+type HllSketch struct {
+	hll.HllSketch
+}
+
+func (s HllSketch) Estimate() float64 {
+	est, err := s.GetEstimate()
+	if err != nil {
+		return math.NaN()
+	}
+	return est
+}
+
+func (s HllSketch) ToSlice() ([]byte, error) {
+	return s.ToCompactSlice()
+}
+
+func (s HllSketch) TypeTag() byte {
+	return byte(internal.FamilyEnum.HLL.Id)
+}
+
+// KllDoublesSketch adapts a kll.DoublesSketch to Sketch.
+// A quantile sketch has no single estimate, so Estimate returns the number of items presented to the sketch.
+type KllDoublesSketch struct {
+	*kll.DoublesSketch
+}
+
+func (s KllDoublesSketch) Estimate() float64 {
+	return float64(s.GetN())
+}
+
+func (s KllDoublesSketch) ToSlice() ([]byte, error) {
+	return s.DoublesSketch.ToSlice()
+}
+
+func (s KllDoublesSketch) TypeTag() byte {
+	return byte(internal.FamilyEnum.Kll.Id)
+}
+
+// CountMinSketch adapts a frequencies.CountMinSketch to Sketch.
+// Estimate returns the total weight presented to the sketch.
+type CountMinSketch struct {
+	*frequencies.CountMinSketch
+}
+
+func (s CountMinSketch) Estimate() float64 {
+	return float64(s.GetTotalWeight())
+}
+
+func (s CountMinSketch) ToSlice() ([]byte, error) {
+	return s.CountMinSketch.ToSlice(), nil
+}
+
+func (s CountMinSketch) TypeTag() byte {
+	return byte(internal.FamilyEnum.GoCountMin.Id)
+}
+
+// BloomFilter adapts a bloomfilter.BloomFilter to Sketch.
+// Estimate returns the number of bits set in the filter.
+type BloomFilter struct {
+	*bloomfilter.BloomFilter
+}
+
+func (s BloomFilter) Estimate() float64 {
+	return float64(s.GetBitsUsed())
+}
+
+func (s BloomFilter) ToSlice() ([]byte, error) {
+	return s.BloomFilter.ToSlice(), nil
+}
+
+func (s BloomFilter) TypeTag() byte {
+	return byte(internal.FamilyEnum.GoBloom.Id)
+}
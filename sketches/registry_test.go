@@ -0,0 +1,163 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sketches
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/apache/datasketches-go/bloomfilter"
+	"github.com/apache/datasketches-go/common"
+	"github.com/apache/datasketches-go/frequencies"
+	"github.com/apache/datasketches-go/hll"
+	"github.com/apache/datasketches-go/internal"
+	"github.com/apache/datasketches-go/kll"
+	"github.com/apache/datasketches-go/sampling"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeserialize(t *testing.T) {
+	hllSketch, err := hll.NewHllSketch(12, hll.TgtHllTypeHll4)
+	assert.NoError(t, err)
+	for i := 0; i < 10000; i++ {
+		assert.NoError(t, hllSketch.UpdateInt64(int64(i)))
+	}
+	hllBytes, err := hllSketch.ToCompactSlice()
+	assert.NoError(t, err)
+	sk, err := Deserialize(hllBytes)
+	assert.NoError(t, err)
+	assert.IsType(t, HllSketch{}, sk)
+	expected, err := hllSketch.GetEstimate()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, sk.Estimate())
+	bytes, err := sk.ToSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, hllBytes, bytes)
+
+	kllSketch, err := kll.NewDoublesSketch(200)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		kllSketch.Update(float64(i))
+	}
+	kllBytes, err := kllSketch.ToSlice()
+	assert.NoError(t, err)
+	sk, err = Deserialize(kllBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, sk.Estimate())
+	assert.Equal(t, kllSketch.GetQuantile(0.5), sk.(KllDoublesSketch).GetQuantile(0.5))
+
+	_, err = Deserialize([]byte{1, 2})
+	assert.Error(t, err)
+	_, err = Deserialize(hllBytes[:6])
+	assert.Error(t, err)
+}
+
+func TestDeserializeCountMinAndBloom(t *testing.T) {
+	cms, err := frequencies.NewCountMinSketch(0.01, 0.01)
+	assert.NoError(t, err)
+	cms.UpdateString("a", 3)
+	cms.UpdateString("b", 4)
+	sk, err := Deserialize(cms.ToSlice())
+	assert.NoError(t, err)
+	assert.Equal(t, 7.0, sk.Estimate())
+	assert.Equal(t, int64(3), sk.(CountMinSketch).EstimateString("a"))
+
+	filter, err := bloomfilter.New(0.01, 1000)
+	assert.NoError(t, err)
+	filter.UpdateString("a")
+	sk, err = Deserialize(filter.ToSlice())
+	assert.NoError(t, err)
+	assert.Equal(t, float64(filter.GetBitsUsed()), sk.Estimate())
+	assert.True(t, sk.(BloomFilter).QueryString("a"))
+
+	// a filter of the Java and C++ family is not registered
+	javaBytes := filter.ToSlice()
+	javaBytes[2] = byte(internal.FamilyEnum.Bloom.Id)
+	_, err = Deserialize(javaBytes)
+	assert.Error(t, err)
+}
+
+func TestDeserializeKllItemsRejected(t *testing.T) {
+	comparator := common.ItemSketchStringComparator(false)
+	serde := common.ItemSketchStringSerDe{}
+	kllSketch, err := kll.NewKllItemsSketch[string](200, 8, comparator, serde)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		kllSketch.Update(strconv.Itoa(i))
+	}
+	bytes, err := kllSketch.ToSlice()
+	assert.NoError(t, err)
+	_, err = Deserialize(bytes)
+	assert.Error(t, err)
+
+	// the caller can register the factory of the item type
+	r := NewRegistry()
+	r.Register(byte(internal.FamilyEnum.Kll.Id), func(data []byte) (Sketch, error) {
+		sk, err := kll.NewKllItemsSketchFromSlice[string](data, comparator, serde)
+		if err != nil {
+			return nil, err
+		}
+		return kllStringsSketch{sk}, nil
+	})
+	sk, err := r.Deserialize(bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, sk.Estimate())
+}
+
+type kllStringsSketch struct {
+	*kll.ItemsSketch[string]
+}
+
+func (s kllStringsSketch) Estimate() float64 {
+	return float64(s.GetN())
+}
+
+type reservoirSketch struct {
+	*sampling.ReservoirItemsSketch[int64]
+}
+
+func (s reservoirSketch) Estimate() float64 {
+	return float64(s.GetN())
+}
+
+func TestRegister(t *testing.T) {
+	reservoir, err := sampling.NewReservoirItemsSketch[int64](10, common.ItemSketchLongSerDe{})
+	assert.NoError(t, err)
+	for i := int64(0); i < 100; i++ {
+		reservoir.Update(i)
+	}
+	bytes, err := reservoir.ToSlice()
+	assert.NoError(t, err)
+
+	r := NewRegistry()
+	_, err = r.Deserialize(bytes)
+	assert.Error(t, err)
+
+	r.Register(byte(internal.FamilyEnum.Reservoir.Id), func(data []byte) (Sketch, error) {
+		sk, err := sampling.NewReservoirItemsSketchFromSlice[int64](data, common.ItemSketchLongSerDe{})
+		if err != nil {
+			return nil, err
+		}
+		return reservoirSketch{sk}, nil
+	})
+	sk, err := r.Deserialize(bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, 100.0, sk.Estimate())
+
+	assert.Panics(t, func() { r.Register(0, nil) })
+}
@@ -0,0 +1,83 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sketches
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Serializable is a sketch that can be serialized with a type tag, see Serialize.
+type Serializable interface {
+	// TypeTag returns the tag identifying the factory that deserializes the sketch,
+	// the family ID for the built-in adapters.
+	TypeTag() byte
+	// ToSlice returns the serialized sketch.
+	ToSlice() ([]byte, error)
+}
+
+// Serialize returns the serialized sketch prefixed with its one byte type tag.
+// The family ID in the preamble is enough for Deserialize, the tag is for stores that also hold
+// sketches whose format has no family ID, such as the HLL VLQ format.
+func Serialize(s Serializable) ([]byte, error) {
+	if s == nil {
+		return nil, errors.New("sketch is nil")
+	}
+	data, err := s.ToSlice()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 1+len(data))
+	out[0] = s.TypeTag()
+	copy(out[1:], data)
+	return out, nil
+}
+
+// DeserializeTagged deserializes data returned by Serialize with the factory registered for its tag.
+func (r *Registry) DeserializeTagged(data []byte) (Sketch, error) {
+	if len(data) == 0 {
+		return nil, errors.New("input array is empty")
+	}
+	r.mu.RLock()
+	factory, ok := r.factories[data[0]]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no factory registered for type tag: %d", data[0])
+	}
+	return factory(data[1:])
+}
+
+// DeserializeTagged deserializes data returned by Serialize with the default registry.
+func DeserializeTagged(data []byte) (Sketch, error) {
+	return defaultRegistry.DeserializeTagged(data)
+}
+
+// DeserializeAs deserializes data returned by Serialize with the default registry,
+// and returns an error if the sketch is not a T.
+func DeserializeAs[T Sketch](data []byte) (T, error) {
+	var zero T
+	sk, err := DeserializeTagged(data)
+	if err != nil {
+		return zero, err
+	}
+	t, ok := sk.(T)
+	if !ok {
+		return zero, fmt.Errorf("sketch is a %T, not a %T", sk, zero)
+	}
+	return t, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Licensed to the Apache Software Foundation (ASF) under one or more
+ * contributor license agreements.  See the NOTICE file distributed with
+ * this work for additional information regarding copyright ownership.
+ * The ASF licenses this file to You under the Apache License, Version 2.0
+ * (the "License"); you may not use this file except in compliance with
+ * the License.  You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package sketches
+
+import (
+	"testing"
+
+	"github.com/apache/datasketches-go/hll"
+	"github.com/apache/datasketches-go/kll"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSerializeTagged(t *testing.T) {
+	hllSketch, err := hll.NewHllSketch(12, hll.TgtHllTypeHll8)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		assert.NoError(t, hllSketch.UpdateInt64(int64(i)))
+	}
+	kllSketch, err := kll.NewDoublesSketch(200)
+	assert.NoError(t, err)
+	for i := 0; i < 1000; i++ {
+		kllSketch.Update(float64(i))
+	}
+
+	hllBytes, err := Serialize(HllSketch{hllSketch})
+	assert.NoError(t, err)
+	kllBytes, err := Serialize(KllDoublesSketch{kllSketch})
+	assert.NoError(t, err)
+
+	sk, err := DeserializeTagged(hllBytes)
+	assert.NoError(t, err)
+	expected, err := hllSketch.GetEstimate()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, sk.Estimate())
+
+	kllResult, err := DeserializeAs[KllDoublesSketch](kllBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, kllSketch.GetQuantile(0.5), kllResult.GetQuantile(0.5))
+	_, err = DeserializeAs[HllSketch](kllBytes)
+	assert.Error(t, err)
+
+	_, err = Serialize(nil)
+	assert.Error(t, err)
+	_, err = DeserializeTagged(nil)
+	assert.Error(t, err)
+	_, err = DeserializeTagged([]byte{255, 1, 2, 3})
+	assert.Error(t, err)
+	_, err = DeserializeAs[HllSketch](hllBytes[:5])
+	assert.Error(t, err)
+}